@@ -3,6 +3,7 @@ package main
 
 import (
 	"embed"
+	"errors"
 	"os"
 
 	"github.com/baobao/akm-go/internal/cli"
@@ -18,6 +19,10 @@ func init() {
 
 func main() {
 	if err := cli.Execute(); err != nil {
+		var exitErr *cli.ExitError
+		if errors.As(err, &exitErr) && exitErr.Code > 0 {
+			os.Exit(exitErr.Code)
+		}
 		os.Exit(1)
 	}
 }
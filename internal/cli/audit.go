@@ -0,0 +1,199 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/baobao/akm-go/internal/core"
+	"github.com/spf13/cobra"
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "审计日志查看与维护",
+	Long:  "审计日志的查看、校验与迁移工具",
+}
+
+var auditTailCmd = &cobra.Command{
+	Use:   "tail",
+	Short: "显示最近的审计记录",
+	Long: `按时间顺序显示最近的审计记录（最新的在最后）。
+
+示例:
+  akm audit tail           # 最近 20 条
+  akm audit tail -n 100`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		limit, _ := cmd.Flags().GetInt("lines")
+		if limit <= 0 {
+			return fmt.Errorf("-n 必须大于 0")
+		}
+
+		storage, err := core.GetStorage()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+		logs, err := storage.ReadAuditLogs(limit)
+		if err != nil {
+			return fmt.Errorf("读取审计日志失败: %w", err)
+		}
+		if len(logs) == 0 {
+			fmt.Println("审计日志为空")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "时间\t密钥\t操作\t项目")
+		fmt.Fprintln(w, "────\t────\t────\t────")
+		for _, log := range logs {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", log.Timestamp.Local().Format("2006-01-02 15:04:05"),
+				log.KeyName, log.Action, log.Project)
+		}
+		return w.Flush()
+	},
+}
+
+var auditVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "逐条校验审计日志的签名与链接",
+	Long: `校验每条审计记录的 HMAC 签名和 prev_hash 链接，逐条输出状态:
+
+  ✓ valid      签名有效
+  ? unsigned   未签名（旧版记录）
+  ✗ tampered   签名无效或无法解析
+  ⛓ 链断裂     与上一条记录的链接不符（中间记录被删除或调换）
+
+存在被篡改或链断裂的记录时以非零状态退出，可用于定时任务或 CI。
+旧 master key 签名的记录显示为 tampered，可先运行 akm audit compact 重新签名。
+
+示例:
+  akm audit verify
+  akm audit verify --problems   # 只列出有问题的记录`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		problemsOnly, _ := cmd.Flags().GetBool("problems")
+
+		storage, err := core.GetStorage()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+		checks, err := storage.VerifyAuditEntries()
+		if err != nil {
+			return fmt.Errorf("读取审计日志失败: %w", err)
+		}
+		if len(checks) == 0 {
+			fmt.Println("审计日志为空")
+			return nil
+		}
+
+		var valid, unsigned, tampered, broken int
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "行\t状态\t时间\t密钥\t操作")
+		fmt.Fprintln(w, "──\t────\t────\t────\t────")
+		for _, c := range checks {
+			symbol := "✓"
+			switch c.Status {
+			case core.AuditEntryValid:
+				valid++
+			case core.AuditEntryUnsigned:
+				unsigned++
+				symbol = "?"
+			default:
+				tampered++
+				symbol = "✗"
+			}
+			status := symbol + " " + c.Status
+			if c.BrokenChain {
+				broken++
+				status += " ⛓ 链断裂"
+			}
+			if problemsOnly && c.Status == core.AuditEntryValid && !c.BrokenChain {
+				continue
+			}
+			when, key, action := "-", "-", "(无法解析)"
+			if c.Entry != nil {
+				when = c.Entry.Timestamp.Local().Format("2006-01-02 15:04:05")
+				key, action = c.Entry.KeyName, c.Entry.Action
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", c.Line, status, when, key, action)
+		}
+		w.Flush()
+
+		fmt.Printf("\n共 %d 条: 有效 %d，未签名 %d，被篡改 %d，链断裂 %d\n", len(checks), valid, unsigned, tampered, broken)
+		if tampered > 0 || broken > 0 {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("审计日志完整性校验失败: %d 条被篡改，%d 处链断裂", tampered, broken)
+		}
+		if unsigned > 0 {
+			printWarning("%d 条记录未签名，无法校验", unsigned)
+		}
+		printSuccess("审计日志完整")
+		return nil
+	},
+}
+
+var auditCompactCmd = &cobra.Command{
+	Use:   "compact",
+	Short: "用当前 master key 重新签名审计日志",
+	Long: `读取全部审计日志并逐条校验，用当前 master key 重新签名后写回单个文件。
+
+- 当前 master key 签名的条目保持不变
+- 旧 master key 签名的条目重新签名
+- 未签名、签名无效或无法解析的条目原样保留并标记，绝不丢弃或重新签名
+
+原文件会备份为 audit.jsonl.<时间戳>.bak。
+
+示例:
+  akm audit compact --dry-run   # 只输出报告
+  akm audit compact`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		storage, err := core.GetStorage()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+
+		report, err := storage.CompactAuditLog(dryRun)
+		if err != nil {
+			return fmt.Errorf("压缩审计日志失败: %w", err)
+		}
+
+		fmt.Println("📋 审计日志压缩报告")
+		fmt.Printf("  总条目:     %d\n", report.Total)
+		fmt.Printf("  已是当前签名: %d\n", report.Current)
+		fmt.Printf("  重新签名:   %d\n", report.Resigned)
+		fmt.Printf("  未签名:     %d\n", report.Unsigned)
+		fmt.Printf("  无法校验:   %d\n", report.Unverified)
+
+		if len(report.Flagged) > 0 {
+			lines := make([]string, len(report.Flagged))
+			for i, n := range report.Flagged {
+				lines[i] = strconv.Itoa(n)
+			}
+			printWarning("%d 条无法校验的条目已原样保留 (行号: %s)", len(report.Flagged), strings.Join(lines, ", "))
+		}
+
+		if dryRun {
+			fmt.Println("\n(dry-run，未写入任何文件)")
+			return nil
+		}
+		if report.BackupFile != "" {
+			printSuccess("审计日志已重写，原文件备份于: %s", report.BackupFile)
+		}
+		return nil
+	},
+}
+
+func init() {
+	auditTailCmd.Flags().IntP("lines", "n", 20, "显示的条数")
+	auditVerifyCmd.Flags().Bool("problems", false, "只列出未通过校验的记录")
+	auditCompactCmd.Flags().Bool("dry-run", false, "只输出报告，不修改文件")
+
+	auditCmd.AddCommand(auditTailCmd)
+	auditCmd.AddCommand(auditVerifyCmd)
+	auditCmd.AddCommand(auditCompactCmd)
+}
@@ -2,6 +2,8 @@ package cli
 
 import (
 	"fmt"
+	"io"
+	"os"
 
 	"github.com/baobao/akm-go/internal/core"
 	"github.com/spf13/cobra"
@@ -101,6 +103,95 @@ var budgetResetCmd = &cobra.Command{
 	},
 }
 
+var budgetExportConfigCmd = &cobra.Command{
+	Use:     "export-config",
+	Aliases: []string{"export"},
+	Short:   "导出预算限制配置",
+	Long: `只导出各 provider 的每日/每月上限（不含计数），便于纳入版本控制并在其他机器上复用。
+
+示例:
+  akm budget export-config                     # JSON 输出到 stdout
+  akm budget export-config -o budget.yaml      # 按扩展名选择 YAML
+  akm budget export-config -F yaml`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		output, _ := cmd.Flags().GetString("output")
+		format, _ := cmd.Flags().GetString("format")
+		if format == "" {
+			format = core.BudgetConfigFormat(output)
+		}
+
+		bt, err := core.GetBudgetTracker()
+		if err != nil {
+			return fmt.Errorf("failed to load budget: %w", err)
+		}
+
+		data, err := core.MarshalBudgetConfig(bt.ExportConfig(), format)
+		if err != nil {
+			return fmt.Errorf("导出失败: %w", err)
+		}
+
+		if output == "" {
+			_, err := os.Stdout.Write(data)
+			return err
+		}
+		if err := os.WriteFile(output, data, 0644); err != nil {
+			return fmt.Errorf("写入文件失败: %w", err)
+		}
+		printSuccess("已导出预算配置到 %s", output)
+		return nil
+	},
+}
+
+var budgetImportConfigCmd = &cobra.Command{
+	Use:     "import-config <FILE>",
+	Aliases: []string{"import"},
+	Short:   "导入预算限制配置",
+	Long: `从 export-config 生成的 JSON/YAML 文件导入各 provider 的上限，计数器保持不变。
+provider 必须是已知平台，上限必须 >= 0（0 = 无限）。
+
+默认与现有配置合并（按 provider 覆盖）；--replace 会删除文件中未列出的 provider 的限制。
+FILE 为 - 时从 stdin 读取（格式默认 json）。
+
+示例:
+  akm budget import-config budget.yaml
+  akm budget import-config budget.json --replace
+  ssh old-host akm budget export | akm budget import -`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		replace, _ := cmd.Flags().GetBool("replace")
+		if format == "" {
+			format = core.BudgetConfigFormat(args[0])
+		}
+
+		var data []byte
+		var err error
+		if args[0] == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(args[0])
+		}
+		if err != nil {
+			return fmt.Errorf("读取文件失败: %w", err)
+		}
+		config, err := core.UnmarshalBudgetConfig(data, format)
+		if err != nil {
+			return fmt.Errorf("解析失败: %w", err)
+		}
+
+		bt, err := core.GetBudgetTracker()
+		if err != nil {
+			return fmt.Errorf("failed to load budget: %w", err)
+		}
+		if err := bt.ImportConfig(config, replace); err != nil {
+			return fmt.Errorf("导入失败: %w", err)
+		}
+
+		printSuccess("已导入 %d 个 provider 的预算限制", len(config))
+		return nil
+	},
+}
+
 func init() {
 	budgetSetCmd.Flags().StringP("provider", "p", "", "Provider 名称 (必须)")
 	budgetSetCmd.Flags().Int64("daily", 0, "每日请求数上限 (0=无限)")
@@ -108,6 +199,14 @@ func init() {
 
 	budgetResetCmd.Flags().StringP("provider", "p", "", "Provider 名称 (必须)")
 
+	budgetExportConfigCmd.Flags().StringP("output", "o", "", "输出文件 (默认 stdout)")
+	budgetExportConfigCmd.Flags().StringP("format", "F", "", "格式: json / yaml (默认按扩展名，否则 json)")
+
+	budgetImportConfigCmd.Flags().StringP("format", "F", "", "格式: json / yaml (默认按扩展名)")
+	budgetImportConfigCmd.Flags().Bool("replace", false, "替换全部限制（删除文件中未列出的 provider）")
+
 	budgetCmd.AddCommand(budgetSetCmd)
 	budgetCmd.AddCommand(budgetResetCmd)
+	budgetCmd.AddCommand(budgetExportConfigCmd)
+	budgetCmd.AddCommand(budgetImportConfigCmd)
 }
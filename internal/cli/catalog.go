@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/baobao/akm-go/internal/core"
+	"github.com/spf13/cobra"
+)
+
+var catalogCmd = &cobra.Command{
+	Use:   "catalog",
+	Short: "导出密钥清单（不含密钥值）",
+	Long: `导出可分享的密钥清单：名称、提供商、标签、描述、启用/过期状态和最近一次验证结果。
+不解密任何密钥值，可放心提交到团队 Wiki。
+
+示例:
+  akm catalog                          # Markdown 表格
+  akm catalog --format json -p 'openai*'
+  akm catalog -t prod -o docs/keys.md`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		provider, _ := cmd.Flags().GetString("provider")
+		tag, _ := cmd.Flags().GetString("tag")
+		format, _ := cmd.Flags().GetString("format")
+		output, _ := cmd.Flags().GetString("output")
+
+		storage, err := core.GetStorage()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+
+		entries, err := storage.Catalog(core.KeyFilter{Provider: provider, Tag: tag})
+		if err != nil {
+			return fmt.Errorf("生成清单失败: %w", err)
+		}
+
+		var content string
+		switch format {
+		case "json":
+			data, err := json.MarshalIndent(map[string]interface{}{
+				"keys":  entries,
+				"count": len(entries),
+			}, "", "  ")
+			if err != nil {
+				return err
+			}
+			content = string(data) + "\n"
+		case "markdown", "md":
+			content = core.FormatCatalogMarkdown(entries)
+		default:
+			return fmt.Errorf("不支持的格式 '%s'（可选: markdown, json）", format)
+		}
+
+		if output == "" {
+			fmt.Print(content)
+			return nil
+		}
+		if err := os.WriteFile(output, []byte(content), 0644); err != nil {
+			return fmt.Errorf("写入文件失败: %w", err)
+		}
+		printSuccess("已将 %d 个密钥的清单写入 %s", len(entries), output)
+		return nil
+	},
+}
+
+func init() {
+	catalogCmd.Flags().StringP("provider", "p", "", "按提供商过滤 (支持通配符, 如 'openai*')")
+	catalogCmd.Flags().StringP("tag", "t", "", "按标签过滤")
+	catalogCmd.Flags().StringP("format", "F", "markdown", "输出格式: markdown, json")
+	catalogCmd.Flags().StringP("output", "o", "", "写入文件")
+}
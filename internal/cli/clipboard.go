@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// errNoClipboard means no clipboard is reachable, e.g. a headless Linux
+// session without X11 or Wayland.
+var errNoClipboard = errors.New("no clipboard available")
+
+// clipboardCommand returns the command that reads stdin into the system
+// clipboard on this platform.
+func clipboardCommand() ([]string, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{"pbcopy"}, nil
+	case "windows":
+		return []string{"clip"}, nil
+	}
+
+	var candidates [][]string
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		candidates = append(candidates, []string{"wl-copy"})
+	}
+	if os.Getenv("DISPLAY") != "" {
+		candidates = append(candidates,
+			[]string{"xclip", "-selection", "clipboard"},
+			[]string{"xsel", "--clipboard", "--input"})
+	}
+	for _, c := range candidates {
+		if _, err := exec.LookPath(c[0]); err == nil {
+			return c, nil
+		}
+	}
+	return nil, errNoClipboard
+}
+
+// copyToClipboard places value on the system clipboard without echoing it.
+func copyToClipboard(value string) error {
+	args, err := clipboardCommand()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(value)
+	return cmd.Run()
+}
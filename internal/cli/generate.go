@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/baobao/akm-go/internal/core"
+	"github.com/spf13/cobra"
+)
+
+var generateCmd = &cobra.Command{
+	Use:   "generate <KEY_NAME>",
+	Short: "生成随机密钥并保存",
+	Long: `生成密码学安全的随机值（webhook 签名密钥、内部令牌等）并直接加密保存，无需 openssl rand。
+
+--format: base64url（默认，无填充）、base64、hex、alnum（大小写字母和数字）。
+--length: hex / base64 / base64url 为随机字节数（同 openssl rand -hex 32），alnum 为字符数；默认 32。
+默认不显示生成的值，需要时加 --print（只显示这一次，之后用 akm get 读取）。
+--print 与 get 一样受 AKM_REVEAL_POLICY 约束：masked 时只显示掩码，never 时拒绝且不保存。
+
+示例:
+  akm generate WEBHOOK_SECRET
+  akm generate INTERNAL_TOKEN --length 48 --format hex --print
+  akm generate SESSION_KEY --format alnum -d "session signing"`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		keyName := args[0]
+		length, _ := cmd.Flags().GetInt("length")
+		format, _ := cmd.Flags().GetString("format")
+		provider, _ := cmd.Flags().GetString("provider")
+		description, _ := cmd.Flags().GetString("description")
+		show, _ := cmd.Flags().GetBool("print")
+
+		if !core.ValidateKeyName(keyName) {
+			return fmt.Errorf("无效的密钥名称 '%s'：只能包含字母、数字和下划线，且不能以数字开头", keyName)
+		}
+		// Refuse before saving, so a forbidden --print leaves nothing behind
+		if show {
+			if err := core.CheckReveal(); err != nil {
+				return err
+			}
+		}
+		value, err := core.GenerateSecret(length, format)
+		if err != nil {
+			return fmt.Errorf("生成失败: %w", err)
+		}
+
+		storage, err := core.GetStorage()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+		if existing := storage.GetKey(keyName); existing != nil {
+			return fmt.Errorf("密钥 '%s' 已存在，使用 'akm rotate' 或 'akm update' 更换值", keyName)
+		}
+
+		var opts []core.KeyOption
+		if description != "" {
+			opts = append(opts, core.WithDescription(description))
+		}
+		key, err := storage.AddKey(keyName, value, provider, opts...)
+		if err != nil {
+			return fmt.Errorf("添加密钥失败: %w", err)
+		}
+
+		var shown string
+		if show {
+			if shown, err = core.RevealValue(value, false); err != nil {
+				return err
+			}
+		}
+
+		if jsonOutput {
+			result := map[string]interface{}{
+				"name":     key.Name,
+				"provider": key.Provider,
+				"format":   format,
+				"length":   length,
+			}
+			if show {
+				result["value"] = shown
+			}
+			return printJSON(result)
+		}
+		printSuccess("已生成并保存密钥 '%s' (provider: %s, %s, 长度 %d)", key.Name, key.Provider, format, length)
+		if show {
+			printWarning("以下值只显示这一次：")
+			fmt.Println(shown)
+		}
+		return nil
+	},
+}
+
+func init() {
+	generateCmd.Flags().Int("length", core.DefaultSecretLength, "长度: hex/base64/base64url 为字节数，alnum 为字符数")
+	generateCmd.Flags().String("format", core.DefaultSecretFormat, "编码: base64url, base64, hex, alnum")
+	generateCmd.Flags().StringP("provider", "p", "local", "提供商名称")
+	generateCmd.Flags().StringP("description", "d", "", "密钥描述")
+	generateCmd.Flags().Bool("print", false, "显示生成的值（仅此一次）")
+}
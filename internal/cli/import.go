@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/baobao/akm-go/internal/core"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import <FILE>",
+	Short: "从 .env 或 JSON 文件批量导入密钥",
+	Long: `从文件批量导入密钥，确认一次后一次性保存。
+
+格式按扩展名识别（.json 为 JSON，其余按 .env），可用 --format 指定:
+  env   NAME=value 行，支持 export 前缀、双引号（与 export/inject 相同的转义）/单引号值、# 注释
+  json  [{"name": "...", "value": "...", "provider": "...", "description": "..."}]
+
+JSON 中未写 provider 的条目使用 --provider。已存在的密钥默认跳过，
+--overwrite 时替换其值（旧值保留在历史中）。名称不合法、重复或值为空的条目计为失败。
+
+示例:
+  akm import .env -p openai
+  akm import keys.json --overwrite
+  akm import secrets.txt --format env -y`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		provider, _ := cmd.Flags().GetString("provider")
+		overwrite, _ := cmd.Flags().GetBool("overwrite")
+		noConfirm, _ := cmd.Flags().GetBool("yes")
+
+		if !noConfirm && !term.IsTerminal(int(os.Stdin.Fd())) {
+			return fmt.Errorf("非交互模式无法确认，请加 --yes")
+		}
+
+		entries, err := core.ParseImportFile(args[0], format)
+		if err != nil {
+			return fmt.Errorf("读取导入文件失败: %w", err)
+		}
+		if len(entries) == 0 {
+			fmt.Println("文件中没有密钥")
+			return nil
+		}
+
+		storage, err := core.GetStorage()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+
+		fmt.Printf("将从 %s 导入 %d 个密钥:\n", args[0], len(entries))
+		for _, e := range entries {
+			p := e.Provider
+			if p == "" {
+				p = provider
+			}
+			fmt.Printf("  - %s (%s)\n", e.Name, p)
+		}
+		if !noConfirm && !confirm("确认导入?") {
+			fmt.Println("已取消")
+			return nil
+		}
+
+		report, err := storage.AddKeysBatch(entries, provider, overwrite)
+		if err != nil {
+			return fmt.Errorf("导入失败: %w", err)
+		}
+		return printBatchReport(report, 0)
+	},
+}
+
+func init() {
+	importCmd.Flags().StringP("format", "F", "", "文件格式: env, json（默认按扩展名识别）")
+	importCmd.Flags().StringP("provider", "p", "unknown", "未指定提供商的条目使用的提供商")
+	importCmd.Flags().Bool("overwrite", false, "覆盖已存在的密钥（旧值保留在历史中）")
+	importCmd.Flags().BoolP("yes", "y", false, "跳过确认")
+}
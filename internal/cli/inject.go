@@ -1,11 +1,16 @@
 package cli
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
+	"text/template"
 
 	"github.com/baobao/akm-go/internal/core"
 	"github.com/spf13/cobra"
@@ -20,16 +25,36 @@ var injectCmd = &cobra.Command{
   akm inject                    # 生成包含所有密钥的 .env
   akm inject -p openai          # 只包含 OpenAI 的密钥
   akm inject -k KEY1,KEY2       # 只包含指定的密钥
+  akm inject -t prod -p openai  # 只包含带 prod 标签的 OpenAI 密钥（条件同时满足）
   akm inject -o custom.env      # 输出到指定文件
   akm inject --project          # 根据 akm.yaml 精确注入
-  akm inject --all ~/projects   # 扫描目录，批量注入所有有 akm.yaml 的项目`,
+                                # akm.yaml 中可写 OPENAI_API_KEY: ${provider:openai}
+                                # 表示注入本地任意一个有效的 openai 密钥
+                                # 也可使用等价的 akm.json
+  akm inject --print-schema     # 输出配置文件的 JSON Schema
+  akm inject --sort --no-quote  # 排序且不加引号（需要引号的值仍会加引号）
+  akm inject --no-header        # 不写注释头
+  akm inject --all ~/projects   # 扫描目录，批量注入所有有 akm.yaml 的项目
+  akm inject --add-gitignore    # 目标文件未被 git 忽略时自动加入 .gitignore
+
+在 git 仓库中，若目标文件未被 .gitignore 忽略，inject 会询问是否加入 .gitignore；
+非交互时拒绝写入，除非指定 --add-gitignore 或 -f。`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		provider, _ := cmd.Flags().GetString("provider")
 		keyNames, _ := cmd.Flags().GetString("keys")
+		tag, _ := cmd.Flags().GetString("tag")
 		output, _ := cmd.Flags().GetString("output")
 		force, _ := cmd.Flags().GetBool("force")
+		addIgnore, _ := cmd.Flags().GetBool("add-gitignore")
 		useProject, _ := cmd.Flags().GetBool("project")
 		allDir, _ := cmd.Flags().GetString("all")
+		printSchema, _ := cmd.Flags().GetBool("print-schema")
+		format := readEnvFormatFlags(cmd)
+
+		if printSchema {
+			fmt.Println(core.ProjectConfigSchema)
+			return nil
+		}
 
 		storage, err := core.GetStorage()
 		if err != nil {
@@ -43,14 +68,14 @@ var injectCmd = &cobra.Command{
 				homeDir, _ := os.UserHomeDir()
 				allDir = filepath.Join(homeDir, allDir[2:])
 			}
-			return injectAll(storage, allDir, force)
+			return injectAll(storage, allDir, force, addIgnore, format)
 		}
 
 		cwd, _ := os.Getwd()
 
 		// --project mode: use akm.yaml
 		if useProject {
-			return injectFromConfig(storage, cwd, force)
+			return injectFromConfig(storage, cwd, force, addIgnore, format)
 		}
 
 		// Default mode: inject all or filtered keys
@@ -63,6 +88,9 @@ var injectCmd = &cobra.Command{
 				return fmt.Errorf("文件 '%s' 已存在，使用 -f 强制覆盖", output)
 			}
 		}
+		if err := guardGitignore(output, force, addIgnore); err != nil {
+			return err
+		}
 
 		var names []string
 		if keyNames != "" {
@@ -73,7 +101,7 @@ var injectCmd = &cobra.Command{
 		}
 
 		project := filepath.Base(cwd)
-		keys, err := storage.GetKeysForInjection(project, provider, names)
+		keys, err := storage.GetKeysForInjection(project, provider, tag, names)
 		if err != nil {
 			return fmt.Errorf("获取密钥失败: %w", err)
 		}
@@ -83,7 +111,7 @@ var injectCmd = &cobra.Command{
 			return nil
 		}
 
-		content := buildEnvContent(project, keys)
+		content := core.FormatDotenv(keys, format.dotenvOptions(project, nil))
 		if err := os.WriteFile(output, []byte(content), 0600); err != nil {
 			return fmt.Errorf("写入文件失败: %w", err)
 		}
@@ -93,14 +121,14 @@ var injectCmd = &cobra.Command{
 	},
 }
 
-func injectFromConfig(storage *core.KeyStorage, dir string, force bool) error {
+func injectFromConfig(storage *core.KeyStorage, dir string, force, addIgnore bool, format envFormatFlags) error {
 	config, err := core.LoadProjectConfig(dir)
 	if err != nil {
 		return err
 	}
 
 	project := filepath.Base(dir)
-	keys, err := storage.GetKeysForInjection(project, config.Provider, config.Keys)
+	keys, err := storage.ResolveProjectKeys(project, config)
 	if err != nil {
 		return fmt.Errorf("获取密钥失败: %w", err)
 	}
@@ -111,9 +139,9 @@ func injectFromConfig(storage *core.KeyStorage, dir string, force bool) error {
 	}
 
 	// Warn about missing keys
-	for _, name := range config.Keys {
-		if _, ok := keys[name]; !ok {
-			printWarning("密钥 '%s' 在 akm.yaml 中声明但未找到", name)
+	for _, entry := range config.Keys {
+		if _, ok := keys[entry.Env]; !ok {
+			printWarning("密钥 '%s' 在 akm.yaml 中声明但未找到", entry.Source)
 		}
 	}
 
@@ -123,8 +151,11 @@ func injectFromConfig(storage *core.KeyStorage, dir string, force bool) error {
 			return fmt.Errorf("文件 '%s' 已存在，使用 -f 强制覆盖", output)
 		}
 	}
+	if err := guardGitignore(output, force, addIgnore); err != nil {
+		return err
+	}
 
-	content := buildEnvContent(project, keys)
+	content := core.FormatDotenv(keys, format.dotenvOptions(project, config.Dotenv))
 	if err := os.WriteFile(output, []byte(content), 0600); err != nil {
 		return fmt.Errorf("写入文件失败: %w", err)
 	}
@@ -133,7 +164,7 @@ func injectFromConfig(storage *core.KeyStorage, dir string, force bool) error {
 	return nil
 }
 
-func injectAll(storage *core.KeyStorage, parentDir string, force bool) error {
+func injectAll(storage *core.KeyStorage, parentDir string, force, addIgnore bool, format envFormatFlags) error {
 	configs, err := core.FindProjectConfigs(parentDir)
 	if err != nil {
 		return fmt.Errorf("扫描目录失败: %w", err)
@@ -148,7 +179,7 @@ func injectAll(storage *core.KeyStorage, parentDir string, force bool) error {
 
 	var success, failed int
 	for dir := range configs {
-		if err := injectFromConfig(storage, dir, force); err != nil {
+		if err := injectFromConfig(storage, dir, force, addIgnore, format); err != nil {
 			printError("[%s] %v", filepath.Base(dir), err)
 			failed++
 		} else {
@@ -163,18 +194,82 @@ func injectAll(storage *core.KeyStorage, parentDir string, force bool) error {
 	return nil
 }
 
-func buildEnvContent(project string, keys map[string]string) string {
-	var lines []string
-	lines = append(lines, "# Generated by akm (API Key Manager)")
-	lines = append(lines, fmt.Sprintf("# Project: %s", project))
-	lines = append(lines, "")
+// guardGitignore stops inject from writing secrets to a file git would pick
+// up. Inside a repository where output is not ignored it appends the file
+// name to .gitignore (with addIgnore, or after asking interactively), only
+// warns with force, and otherwise refuses.
+func guardGitignore(output string, force, addIgnore bool) error {
+	root, exposed := core.UnignoredInRepo(output)
+	if !exposed {
+		return nil
+	}
+	entry := filepath.Base(output)
+	if !addIgnore && !force && isInteractive() {
+		addIgnore = confirm(fmt.Sprintf("⚠️  %s 位于 git 仓库 %s 中且未被忽略，密钥可能被误提交。将 %s 加入 .gitignore?", output, root, entry))
+	}
+	if addIgnore {
+		if err := core.AppendGitignore(root, entry); err != nil {
+			return fmt.Errorf("更新 .gitignore 失败: %w", err)
+		}
+		printSuccess("已将 %s 加入 %s", entry, filepath.Join(root, ".gitignore"))
+		return nil
+	}
+	if force {
+		printWarning("%s 未被 .gitignore 忽略，注意不要提交其中的密钥！", output)
+		return nil
+	}
+	return fmt.Errorf("'%s' 位于 git 仓库中且未被 .gitignore 忽略，可能被误提交；使用 --add-gitignore 加入 .gitignore，或 -f 强制写入", output)
+}
+
+// envFormatFlags holds the .env formatting flags of inject.
+type envFormatFlags struct {
+	header    string
+	headerSet bool
+	noHeader  bool
+	noQuote   bool
+	sort      bool
+	timestamp bool
+}
+
+func readEnvFormatFlags(cmd *cobra.Command) envFormatFlags {
+	var f envFormatFlags
+	f.header, _ = cmd.Flags().GetString("header")
+	f.headerSet = cmd.Flags().Changed("header")
+	f.noHeader, _ = cmd.Flags().GetBool("no-header")
+	f.noQuote, _ = cmd.Flags().GetBool("no-quote")
+	f.sort, _ = cmd.Flags().GetBool("sort")
+	f.timestamp, _ = cmd.Flags().GetBool("timestamp")
+	return f
+}
+
+// dotenvOptions merges project config (if any) with flags; flags win.
+func (f envFormatFlags) dotenvOptions(project string, cfg *core.DotenvConfig) core.DotenvOptions {
+	header := []string{"Generated by akm (API Key Manager)", "Project: " + project}
+	opts := core.DotenvOptions{Header: header}
 
-	for name, value := range keys {
-		escapedValue := core.EscapeDotenvValue(value)
-		lines = append(lines, fmt.Sprintf("%s=\"%s\"", name, escapedValue))
+	if cfg != nil {
+		if cfg.Header != nil {
+			opts.Header = nil
+			if *cfg.Header != "" {
+				opts.Header = strings.Split(*cfg.Header, "\n")
+			}
+		}
+		opts.NoQuote = cfg.NoQuote
+		opts.Sort = cfg.Sort
+		opts.Timestamp = cfg.Timestamp
 	}
 
-	return strings.Join(lines, "\n") + "\n"
+	if f.headerSet {
+		opts.Header = strings.Split(strings.ReplaceAll(f.header, `\n`, "\n"), "\n")
+	}
+	if f.noHeader {
+		opts.Header = nil
+		opts.Timestamp = false
+	}
+	opts.NoQuote = opts.NoQuote || f.noQuote
+	opts.Sort = opts.Sort || f.sort
+	opts.Timestamp = (opts.Timestamp || f.timestamp) && !f.noHeader
+	return opts
 }
 
 var runCmd = &cobra.Command{
@@ -185,13 +280,40 @@ var runCmd = &cobra.Command{
 示例:
   akm run -- python app.py
   akm run -p openai -- node server.js
-  akm run -k OPENAI_API_KEY,ANTHROPIC_API_KEY -- ./script.sh`,
+  akm run -t ci -- ./test.sh
+  akm run -k OPENAI_API_KEY,ANTHROPIC_API_KEY -- ./script.sh
+  akm run --key OPENAI_API_KEY --key ANTHROPIC_API_KEY -- ./script.sh
+  akm run --env-file .env -- npm start       # 叠加已提交的非敏感配置
+
+注入哪些密钥: 不加任何过滤时注入全部有效密钥（跳过已停用和已过期的）；
+-p/-t 按提供商/标签筛选，-k/--key 只注入指定的密钥（可组合）。
+子进程继承当前环境并直接使用终端的 stdin/stdout/stderr，其退出码即 akm 的退出码。
+
+--env-file 可重复指定，按 akm 写出 .env 的同一转义规则解析，合并优先级（后者覆盖前者）:
+  当前进程环境 < --env-file（按指定顺序） < akm 密钥
+加 --env-file-override 时 env 文件覆盖同名的 akm 密钥。合并只在内存中进行，不写任何文件。`,
 	Args:                  cobra.MinimumNArgs(1),
 	DisableFlagParsing:    false,
 	DisableFlagsInUseLine: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		provider, _ := cmd.Flags().GetString("provider")
 		keyNames, _ := cmd.Flags().GetString("keys")
+		keyList, _ := cmd.Flags().GetStringArray("key")
+		tag, _ := cmd.Flags().GetString("tag")
+		envFiles, _ := cmd.Flags().GetStringArray("env-file")
+		envFileOverride, _ := cmd.Flags().GetBool("env-file-override")
+
+		// Load env files first so a typo fails before any key is decrypted
+		fileEnv := make(map[string]string)
+		for _, path := range envFiles {
+			values, err := core.LoadDotenvFile(path)
+			if err != nil {
+				return fmt.Errorf("读取 env 文件失败: %w", err)
+			}
+			for name, value := range values {
+				fileEnv[name] = value
+			}
+		}
 
 		storage, err := core.GetStorage()
 		if err != nil {
@@ -206,19 +328,28 @@ var runCmd = &cobra.Command{
 				names[i] = strings.TrimSpace(name)
 			}
 		}
+		for _, name := range keyList {
+			names = append(names, strings.TrimSpace(name))
+		}
 
 		cwd, _ := os.Getwd()
 		project := filepath.Base(cwd)
 
-		keys, err := storage.GetKeysForInjection(project, provider, names)
+		keys, err := storage.GetKeysForInjection(project, provider, tag, names)
 		if err != nil {
 			return fmt.Errorf("获取密钥失败: %w", err)
 		}
 
-		// Build environment
+		// Build environment; for duplicate names exec keeps the last entry
+		layers := []map[string]string{fileEnv, keys}
+		if envFileOverride {
+			layers = []map[string]string{keys, fileEnv}
+		}
 		env := os.Environ()
-		for name, value := range keys {
-			env = append(env, fmt.Sprintf("%s=%s", name, value))
+		for _, layer := range layers {
+			for name, value := range layer {
+				env = append(env, fmt.Sprintf("%s=%s", name, value))
+			}
 		}
 
 		// Run command
@@ -231,7 +362,17 @@ var runCmd = &cobra.Command{
 		execCmd.Stdout = os.Stdout
 		execCmd.Stderr = os.Stderr
 
-		return execCmd.Run()
+		if err := execCmd.Run(); err != nil {
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				// The command already reported its own failure; just pass the code on
+				cmd.SilenceErrors = true
+				cmd.SilenceUsage = true
+				return &ExitError{Code: exitErr.ExitCode()}
+			}
+			return fmt.Errorf("运行命令失败: %w", err)
+		}
+		return nil
 	},
 }
 
@@ -243,11 +384,70 @@ var exportCmd = &cobra.Command{
 示例:
   eval "$(akm export)"              # 导出到当前 shell
   akm export -p openai              # 只导出 OpenAI 密钥
-  akm export --format json          # JSON 格式输出`,
+  akm export --tag prod             # 只导出带 prod 标签的密钥
+  akm export --format json          # JSON 格式输出
+  akm export --format posix > keys.sh  # 可安全 set -a; . keys.sh 的 POSIX 格式
+  akm export -p openai --format k8s-secret --secret-name llm-keys | kubectl apply -f -
+
+格式说明:
+  shell   export KEY='value'，供 bash/zsh 的 eval 使用（默认）
+  posix   export KEY='value'，单引号按 POSIX 转义 ('\'')，适合 set -a; . file 或 sh/dash
+          （别名 dotenv-export）
+  env     KEY="value"，供 dotenv 类加载器 (python-dotenv、docker --env-file 等) 读取，
+          不要用 shell source
+  json    {"KEY": "value"}
+  dockerfile  ENV KEY="value"，可粘贴进 Dockerfile（值中的 " \ $ 已转义，不支持多行值）
+  k8s-secret  v1 Secret YAML（data 为 base64），可直接 kubectl apply -f；
+              名称由 --secret-name 指定（默认 akm-secrets）
+  eval "$(akm export --merge-existing-env)"  # 只导出与当前环境不同的密钥
+
+模板 (--template):
+  用 Go text/template 渲染任意格式（Terraform 变量、GitHub Actions、systemd EnvironmentFile 等）。
+  模板数据是按名称排序的列表，每项含 .Name .Value .Provider .Tags；
+  可用函数: dotenv（双引号 dotenv 转义）、shell（POSIX 单引号）、json、join、upper、lower。
+  模板能读取明文值，需交互确认或 --yes。
+
+  akm export --template tfvars.tmpl --yes > secrets.auto.tfvars
+  # tfvars.tmpl: {{range .}}{{lower .Name}} = {{json .Value}}
+  #              {{end}}`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		provider, _ := cmd.Flags().GetString("provider")
 		keyNames, _ := cmd.Flags().GetString("keys")
+		tag, _ := cmd.Flags().GetString("tag")
 		format, _ := cmd.Flags().GetString("format")
+		mergeEnv, _ := cmd.Flags().GetBool("merge-existing-env")
+		templateFile, _ := cmd.Flags().GetString("template")
+		yes, _ := cmd.Flags().GetBool("yes")
+		skipErrors, _ := cmd.Flags().GetBool("skip-errors")
+		secretName, _ := cmd.Flags().GetString("secret-name")
+
+		// Reject a bad Secret name before anything is decrypted
+		if format == "k8s-secret" && templateFile == "" {
+			if err := core.ValidateK8sName(secretName); err != nil {
+				return err
+			}
+		}
+
+		// Parse the template and confirm before anything is decrypted
+		var tmpl *template.Template
+		if templateFile != "" {
+			text, err := os.ReadFile(templateFile)
+			if err != nil {
+				return fmt.Errorf("读取模板失败: %w", err)
+			}
+			if tmpl, err = core.ParseKeysTemplate(filepath.Base(templateFile), string(text)); err != nil {
+				return err
+			}
+			if !yes {
+				if !isInteractive() {
+					return fmt.Errorf("模板可读取明文密钥值，非交互模式请使用 --yes 确认")
+				}
+				if !confirm(fmt.Sprintf("模板 %s 将获得明文密钥值，确认渲染?", templateFile)) {
+					fmt.Println("已取消")
+					return nil
+				}
+			}
+		}
 
 		storage, err := core.GetStorage()
 		if err != nil {
@@ -263,63 +463,136 @@ var exportCmd = &cobra.Command{
 			}
 		}
 
-		keys, err := storage.GetKeysForExport("cli-export", provider, names)
-		if err != nil {
-			return fmt.Errorf("获取密钥失败: %w", err)
+		var keys map[string]string
+		if skipErrors {
+			var failures map[string]error
+			keys, failures = storage.GetKeysForExportPartial("cli-export", provider, tag, names)
+			failed := make([]string, 0, len(failures))
+			for name := range failures {
+				failed = append(failed, name)
+			}
+			sort.Strings(failed)
+			for _, name := range failed {
+				fmt.Fprintf(os.Stderr, "⚠️  跳过无法解密的密钥 '%s': %v\n", name, failures[name])
+			}
+			if len(failed) > 0 {
+				fmt.Fprintf(os.Stderr, "⚠️  %d 个密钥解密失败已跳过，导出其余 %d 个\n", len(failed), len(keys))
+			}
+		} else {
+			keys, err = storage.GetKeysForExport("cli-export", provider, tag, names)
+			if err != nil {
+				return fmt.Errorf("获取密钥失败: %w", err)
+			}
 		}
 
-		switch format {
-		case "json":
-			fmt.Println("{")
-			i := 0
+		// Only emit keys that are missing from, or differ in, the current environment
+		if mergeEnv {
 			for name, value := range keys {
-				comma := ","
-				if i == len(keys)-1 {
-					comma = ""
+				if current, ok := os.LookupEnv(name); ok && current == value {
+					delete(keys, name)
 				}
-				// Escape JSON string
-				escaped := strings.ReplaceAll(value, "\\", "\\\\")
-				escaped = strings.ReplaceAll(escaped, "\"", "\\\"")
-				escaped = strings.ReplaceAll(escaped, "\n", "\\n")
-				escaped = strings.ReplaceAll(escaped, "\r", "\\r")
-				fmt.Printf("  %q: %q%s\n", name, escaped, comma)
-				i++
 			}
-			fmt.Println("}")
+		}
 
-		case "env":
-			for name, value := range keys {
-				escaped := core.EscapeDotenvValue(value)
-				fmt.Printf("%s=\"%s\"\n", name, escaped)
-			}
+		if tmpl != nil {
+			return core.RenderKeysTemplate(os.Stdout, tmpl, storage.TemplateKeys(keys))
+		}
+		return writeKeys(os.Stdout, keys, format, secretName)
+	},
+}
 
-		default: // shell
-			for name, value := range keys {
-				// Shell escape: single quotes with escaped single quotes
-				escaped := strings.ReplaceAll(value, "'", "'\"'\"'")
-				fmt.Printf("export %s='%s'\n", name, escaped)
+// writeKeys writes decrypted keys in shell, posix, env, json, dockerfile or
+// k8s-secret format, sorted by name. secretName is the k8s-secret
+// metadata.name ("" for core.DefaultSecretName).
+func writeKeys(w io.Writer, keys map[string]string, format, secretName string) error {
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(keys, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+
+	case "posix", "dotenv-export":
+		_, err := io.WriteString(w, core.FormatPOSIX(keys))
+		return err
+
+	case "dockerfile":
+		out, err := core.FormatDockerfile(keys)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, out)
+		return err
+
+	case "k8s-secret":
+		out, err := core.FormatK8sSecret(secretName, keys)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, out)
+		return err
+
+	case "env":
+		for _, name := range names {
+			escaped := core.EscapeDotenvValue(keys[name])
+			if _, err := fmt.Fprintf(w, "%s=\"%s\"\n", name, escaped); err != nil {
+				return err
 			}
 		}
 
-		return nil
-	},
+	default: // shell
+		for _, name := range names {
+			// Shell escape: single quotes with escaped single quotes
+			escaped := strings.ReplaceAll(keys[name], "'", "'\"'\"'")
+			if _, err := fmt.Fprintf(w, "export %s='%s'\n", name, escaped); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
 func init() {
 	// inject flags
-	injectCmd.Flags().StringP("provider", "p", "", "按提供商过滤")
+	injectCmd.Flags().StringP("provider", "p", "", "按提供商过滤 (支持通配符, 如 'openai*')")
 	injectCmd.Flags().StringP("keys", "k", "", "指定密钥名称（逗号分隔）")
+	injectCmd.Flags().StringP("tag", "t", "", "按标签过滤（与 provider 同时指定时需都满足）")
 	injectCmd.Flags().StringP("output", "o", "", "输出文件路径（默认 .env）")
-	injectCmd.Flags().BoolP("force", "f", false, "强制覆盖已存在的文件")
+	injectCmd.Flags().BoolP("force", "f", false, "强制覆盖已存在的文件；在 git 仓库中未被忽略时也照常写入（仅警告）")
+	injectCmd.Flags().Bool("add-gitignore", false, "目标文件未被 .gitignore 忽略时自动加入 .gitignore")
 	injectCmd.Flags().Bool("project", false, "根据当前目录的 akm.yaml 精确注入")
-	injectCmd.Flags().String("all", "", "扫描指定目录下所有含 akm.yaml 的子目录并批量注入")
+	injectCmd.Flags().String("all", "", "扫描指定目录下所有含 akm.yaml/akm.json 的子目录并批量注入")
+	injectCmd.Flags().Bool("print-schema", false, "输出 akm.yaml/akm.json 的 JSON Schema")
+	injectCmd.Flags().String("header", "", "自定义注释头（\\n 分隔多行）")
+	injectCmd.Flags().Bool("no-header", false, "不写注释头")
+	injectCmd.Flags().Bool("no-quote", false, "值不加引号（含空格等特殊字符的值仍加引号）")
+	injectCmd.Flags().Bool("sort", false, "按名称排序（现已默认排序，保留以兼容旧脚本）")
+	injectCmd.Flags().Bool("timestamp", false, "在注释头中写入生成时间")
 
 	// run flags
-	runCmd.Flags().StringP("provider", "p", "", "按提供商过滤")
+	runCmd.Flags().StringP("provider", "p", "", "按提供商过滤 (支持通配符, 如 'openai*')")
 	runCmd.Flags().StringP("keys", "k", "", "指定密钥名称（逗号分隔）")
+	runCmd.Flags().StringArray("key", nil, "指定密钥名称（可重复，与 --keys 合并）")
+	runCmd.Flags().StringP("tag", "t", "", "按标签过滤（与 provider 同时指定时需都满足）")
+	runCmd.Flags().StringArray("env-file", nil, "额外加载的 dotenv 文件（可重复，akm 密钥优先）")
+	runCmd.Flags().Bool("env-file-override", false, "env 文件覆盖同名的 akm 密钥")
 
 	// export flags
-	exportCmd.Flags().StringP("provider", "p", "", "按提供商过滤")
+	exportCmd.Flags().StringP("provider", "p", "", "按提供商过滤 (支持通配符, 如 'openai*')")
 	exportCmd.Flags().StringP("keys", "k", "", "指定密钥名称（逗号分隔）")
-	exportCmd.Flags().StringP("format", "F", "shell", "输出格式: shell, env, json")
+	exportCmd.Flags().StringP("tag", "t", "", "按标签过滤（与 provider 同时指定时需都满足）")
+	exportCmd.Flags().StringP("format", "F", "shell", "输出格式: shell, posix, env, json, dockerfile, k8s-secret")
+	exportCmd.Flags().String("secret-name", core.DefaultSecretName, "k8s-secret 格式的 metadata.name")
+	exportCmd.Flags().Bool("merge-existing-env", false, "只输出当前环境中缺失或值不同的密钥")
+	exportCmd.Flags().String("template", "", "用 Go text/template 模板文件渲染输出（覆盖 --format）")
+	exportCmd.Flags().Bool("skip-errors", false, "跳过解密失败的密钥（警告输出到 stderr）继续导出其余密钥")
+	exportCmd.Flags().BoolP("yes", "y", false, "确认模板可读取明文值（非交互时 --template 必需）")
 }
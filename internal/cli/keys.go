@@ -2,13 +2,19 @@ package cli
 
 import (
 	"bufio"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 	"syscall"
 	"text/tabwriter"
+	"time"
 
 	"github.com/baobao/akm-go/internal/core"
+	"github.com/baobao/akm-go/internal/models"
 	"github.com/spf13/cobra"
 	"golang.org/x/term"
 )
@@ -16,69 +22,292 @@ import (
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "列出所有密钥",
-	Long:  "列出所有存储的 API 密钥，可按提供商过滤",
+	Long: `列出所有存储的 API 密钥，可按提供商过滤。
+
+已过期的密钥不能再读取、注入、导出或被代理选用（没有过期时间的密钥不受影响），
+"过期时间"列中标记为 (已过期)。
+
+示例:
+  akm list --expired        # 只列出已过期的密钥
+  akm prune --expired       # 清理它们
+  akm list --sort-by updated  # 最近更新的在前（也可 provider、created；默认按名称）`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		provider, _ := cmd.Flags().GetString("provider")
+		expiredOnly, _ := cmd.Flags().GetBool("expired")
 		showValue, _ := cmd.Flags().GetBool("show-value")
+		jsonLines, _ := cmd.Flags().GetBool("json-lines")
+		selectMode, _ := cmd.Flags().GetBool("select")
+		sortBy, _ := cmd.Flags().GetString("sort-by")
 
 		storage, err := core.GetStorage()
 		if err != nil {
 			return fmt.Errorf("failed to initialize storage: %w", err)
 		}
 
+		if jsonLines {
+			return streamKeysJSONLines(storage, provider)
+		}
+		if jsonOutput && (showValue || selectMode) {
+			return fmt.Errorf("--json 不能与 --show-value 或 --select 同时使用")
+		}
+
+		if showValue {
+			if err := core.CheckReveal(); err != nil {
+				return err
+			}
+		}
+
+		if selectMode && !isInteractive() {
+			printWarning("非交互终端，忽略 --select")
+			selectMode = false
+		}
+
+		now := time.Now()
 		keys := storage.ListKeys(provider)
+		if expiredOnly {
+			expired := keys[:0]
+			for _, key := range keys {
+				if key.IsExpired(now) {
+					expired = append(expired, key)
+				}
+			}
+			keys = expired
+		}
+		// Stable order so row numbers mean the same thing between runs
+		if err := core.SortKeys(keys, sortBy); err != nil {
+			return fmt.Errorf("--sort-by 无效: %w", err)
+		}
+		if jsonOutput {
+			result := make([]keyLine, 0, len(keys))
+			for _, key := range keys {
+				result = append(result, newKeyLine(key))
+			}
+			return printJSON(map[string]interface{}{
+				"keys":  result,
+				"count": len(result),
+			})
+		}
 		if len(keys) == 0 {
 			fmt.Println("没有找到密钥")
 			return nil
 		}
-
 		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		prefix, rule := "", ""
+		if selectMode {
+			prefix, rule = "#\t", "─\t"
+		}
 		if showValue {
-			fmt.Fprintln(w, "名称\t提供商\t值\t状态")
-			fmt.Fprintln(w, "────\t──────\t──\t────")
+			fmt.Fprintln(w, prefix+"名称\t提供商\t值\t状态\t过期时间")
+			fmt.Fprintln(w, rule+"────\t──────\t──\t────\t────────")
 		} else {
-			fmt.Fprintln(w, "名称\t提供商\t来源\t状态")
-			fmt.Fprintln(w, "────\t──────\t────\t────")
+			fmt.Fprintln(w, prefix+"名称\t提供商\t来源\t状态\t过期时间")
+			fmt.Fprintln(w, rule+"────\t──────\t────\t────\t────────")
 		}
 
-		for _, key := range keys {
+		for i, key := range keys {
+			if selectMode {
+				fmt.Fprintf(w, "%d\t", i+1)
+			}
 			status := "✓"
 			if !key.IsActive {
 				status = "✗"
 			}
+			expires := "-"
+			if key.ExpiresAt.Time != nil {
+				expires = key.ExpiresAt.Time.Format("2006-01-02")
+				if key.IsExpired(now) {
+					expires += " (已过期)"
+				}
+			}
 
 			if showValue {
-				value, err := storage.GetKeyValue(key.Name, "cli-list")
-				if err != nil {
-					value = "<解密失败>"
+				masked := "<解密失败>"
+				if key.PassphraseProtected {
+					masked = "<需要口令>"
+				} else if key.IsExpired(now) {
+					masked = "<已过期>"
+				} else if value, err := storage.GetKeyValue(key.Name, "cli-list"); err == nil {
+					// Mask value for display
+					masked, _ = core.RevealValue(value, true)
 				}
-				// Mask value for display
-				masked := maskValue(value)
-				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", key.Name, key.Provider, masked, status)
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", key.Name, key.Provider, masked, status, expires)
 			} else {
 				source := "-"
 				if key.SourceProject != nil {
 					source = *key.SourceProject
 				}
-				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", key.Name, key.Provider, source, status)
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", key.Name, key.Provider, source, status, expires)
 			}
 		}
 		w.Flush()
 
 		fmt.Printf("\n共 %d 个密钥\n", len(keys))
+
+		if selectMode {
+			return selectKeyAction(storage, keys)
+		}
 		return nil
 	},
 }
 
+// isInteractive reports whether both stdin and stdout are terminals.
+func isInteractive() bool {
+	return term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// confirm asks a yes/no question on stdin; anything but y/yes means no.
+func confirm(prompt string) bool {
+	fmt.Fprintf(statusWriter(), "%s [y/N]: ", prompt)
+	reader := bufio.NewReader(os.Stdin)
+	response, _ := reader.ReadString('\n')
+	response = strings.TrimSpace(strings.ToLower(response))
+	return response == "y" || response == "yes"
+}
+
+// prompt prints label and returns the trimmed line read from stdin.
+func prompt(label string) string {
+	fmt.Fprint(statusWriter(), label)
+	reader := bufio.NewReader(os.Stdin)
+	line, _ := reader.ReadString('\n')
+	return strings.TrimSpace(line)
+}
+
+// selectKeyAction asks for a row number from the list just printed and runs
+// one action on that key. An empty answer cancels.
+func selectKeyAction(storage *core.KeyStorage, keys []*models.APIKey) error {
+	answer := prompt(fmt.Sprintf("\n选择序号 (1-%d，回车取消): ", len(keys)))
+	if answer == "" {
+		return nil
+	}
+	index, err := strconv.Atoi(answer)
+	if err != nil || index < 1 || index > len(keys) {
+		return fmt.Errorf("无效序号: %s", answer)
+	}
+	key := keys[index-1]
+
+	toggle := "停用"
+	if !key.IsActive {
+		toggle = "启用"
+	}
+	fmt.Printf("\n%s (%s)\n", key.Name, key.Provider)
+	fmt.Printf("  [g] 查看值  [v] 验证  [t] %s  [d] 删除\n", toggle)
+
+	switch strings.ToLower(prompt("操作 (回车取消): ")) {
+	case "":
+		return nil
+	case "g":
+		if err := core.CheckReveal(); err != nil {
+			return err
+		}
+		if !confirm(fmt.Sprintf("确认获取密钥 '%s' 的明文值?", key.Name)) {
+			fmt.Println("已取消")
+			return nil
+		}
+		passphrase, err := readKeyPassphrase(key)
+		if err != nil {
+			return err
+		}
+		value, err := storage.GetKeyValueWithPassphrase(key.Name, "cli-get", passphrase)
+		if err != nil {
+			return fmt.Errorf("获取密钥失败: %w", err)
+		}
+		if value, err = core.RevealValue(value, false); err != nil {
+			return err
+		}
+		fmt.Println(value)
+	case "v":
+		for _, r := range core.VerifyAll(storage, "", key.Name) {
+			fmt.Printf("  %s (%s): %s - %s\n", r.Name, r.Provider, r.Status, r.Message)
+		}
+	case "t":
+		if _, err := storage.UpdateKey(key.Name, map[string]interface{}{"is_active": !key.IsActive}); err != nil {
+			return fmt.Errorf("更新密钥失败: %w", err)
+		}
+		printSuccess("已%s密钥 '%s'", toggle, key.Name)
+	case "d":
+		if !confirm(deletePrompt(fmt.Sprintf("密钥 '%s'", key.Name))) {
+			fmt.Println("已取消")
+			return nil
+		}
+		if err := storage.DeleteKey(key.Name); err != nil {
+			return fmt.Errorf("删除密钥失败: %w", err)
+		}
+		printDeleted(key.Name)
+	default:
+		return fmt.Errorf("未知操作")
+	}
+	return nil
+}
+
+// keyLine is the per-key shape for `list --json` and `list --json-lines`
+// (metadata only, no values), matching the akm_list_keys MCP tool.
+type keyLine struct {
+	Name          string   `json:"name"`
+	Provider      string   `json:"provider"`
+	Description   *string  `json:"description,omitempty"`
+	SourceProject *string  `json:"source_project,omitempty"`
+	Tags          []string `json:"tags,omitempty"`
+	IsActive      bool     `json:"is_active"`
+}
+
+func newKeyLine(key *models.APIKey) keyLine {
+	return keyLine{
+		Name:          key.Name,
+		Provider:      key.Provider,
+		Description:   key.Description,
+		SourceProject: key.SourceProject,
+		Tags:          key.Tags,
+		IsActive:      key.IsActive,
+	}
+}
+
+// streamKeysJSONLines writes one JSON object per key to stdout.
+func streamKeysJSONLines(storage *core.KeyStorage, provider string) error {
+	w := bufio.NewWriter(os.Stdout)
+	enc := json.NewEncoder(w)
+
+	var encErr error
+	storage.EachKey(provider, func(key *models.APIKey) bool {
+		encErr = enc.Encode(newKeyLine(key))
+		return encErr == nil
+	})
+	if encErr != nil {
+		return encErr
+	}
+	return w.Flush()
+}
+
 var getCmd = &cobra.Command{
 	Use:   "get <KEY_NAME>",
 	Short: "获取密钥值",
-	Long:  "获取指定密钥的明文值（需要确认）",
-	Args:  cobra.ExactArgs(1),
+	Long: `获取指定密钥的明文值（需要确认）。
+
+AKM_REVEAL_POLICY 可限制显示: full（默认）、masked（始终遮盖）、never（禁止显示）。
+
+--all 一次输出全部（或按 -p/-t 过滤的）密钥，每个密钥记为一次 read 审计。
+输出到终端需加 --yes 或 --force，推荐用 --output 写入文件（权限 0600）。
+
+示例:
+  akm get --all --format json -o seed.json
+  akm get --all -p openai --format env -o .env.seed`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if all, _ := cmd.Flags().GetBool("all"); all {
+			if len(args) > 0 {
+				return fmt.Errorf("--all 不能与密钥名称同时使用")
+			}
+			return nil
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if all, _ := cmd.Flags().GetBool("all"); all {
+			return runGetAll(cmd)
+		}
+
 		keyName := args[0]
 		noConfirm, _ := cmd.Flags().GetBool("yes")
-		copyToClipboard, _ := cmd.Flags().GetBool("copy")
+		copyValue, _ := cmd.Flags().GetBool("copy")
+		version, _ := cmd.Flags().GetInt("version")
 
 		storage, err := core.GetStorage()
 		if err != nil {
@@ -90,42 +319,275 @@ var getCmd = &cobra.Command{
 			return fmt.Errorf("密钥 '%s' 不存在", keyName)
 		}
 
-		if !noConfirm {
-			fmt.Printf("确认获取密钥 '%s' 的明文值? [y/N]: ", keyName)
-			reader := bufio.NewReader(os.Stdin)
-			response, _ := reader.ReadString('\n')
-			response = strings.TrimSpace(strings.ToLower(response))
-			if response != "y" && response != "yes" {
-				fmt.Println("已取消")
-				return nil
+		if metadata, _ := cmd.Flags().GetBool("metadata"); metadata {
+			if jsonOutput {
+				return printJSON(keyMetadataJSON(key))
 			}
+			printKeyMetadata(key)
+			return nil
+		}
+		if jsonOutput && copyValue {
+			return fmt.Errorf("--json 不能与 --copy 同时使用")
+		}
+
+		if err := core.CheckReveal(); err != nil {
+			return err
 		}
 
-		value, err := storage.GetKeyValue(keyName, "cli-get")
+		if !noConfirm && !confirm(fmt.Sprintf("确认获取密钥 '%s' 的明文值?", keyName)) {
+			fmt.Fprintln(statusWriter(), "已取消")
+			return nil
+		}
+
+		passphrase, err := readKeyPassphrase(key)
+		if err != nil {
+			return err
+		}
+
+		value, err := storage.GetKeyValueVersion(keyName, version, "cli-get", passphrase)
 		if err != nil {
 			return fmt.Errorf("获取密钥失败: %w", err)
 		}
+		if value, err = core.RevealValue(value, false); err != nil {
+			return err
+		}
 
-		if copyToClipboard {
-			// TODO: implement clipboard copy
-			fmt.Println("复制到剪贴板功能暂未实现")
+		if copyValue {
+			if err := copyToClipboard(value); err == nil {
+				printSuccess("'%s' 已复制到剪贴板", key.Name)
+				return nil
+			} else if errors.Is(err, errNoClipboard) {
+				printWarning("没有可用的剪贴板（无图形会话或未安装 wl-copy/xclip/xsel），改为输出到终端")
+			} else {
+				printWarning("复制到剪贴板失败: %v，改为输出到终端", err)
+			}
 		}
 
+		if jsonOutput {
+			return printJSON(map[string]string{"name": key.Name, "value": value})
+		}
 		fmt.Println(value)
 		return nil
 	},
 }
 
+// keyMetadataJSON is `get --metadata --json`, the akm_get_key MCP tool's
+// shape plus the fields only the CLI shows.
+func keyMetadataJSON(key *models.APIKey) map[string]interface{} {
+	result := map[string]interface{}{
+		"name":       key.Name,
+		"provider":   key.Provider,
+		"is_active":  key.IsActive,
+		"created_at": key.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		"updated_at": key.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+	if key.Description != nil {
+		result["description"] = *key.Description
+	}
+	if key.SourceProject != nil {
+		result["source_project"] = *key.SourceProject
+	}
+	if key.CreatedBy != nil {
+		result["created_by"] = *key.CreatedBy
+	}
+	if key.UpdatedBy != nil {
+		result["updated_by"] = *key.UpdatedBy
+	}
+	if len(key.Tags) > 0 {
+		result["tags"] = key.Tags
+	}
+	if key.ExpiresAt.Time != nil {
+		result["expires_at"] = key.ExpiresAt.Time.Format(time.RFC3339)
+	}
+	if key.ModelName != nil && *key.ModelName != "" {
+		result["model_name"] = *key.ModelName
+	}
+	if key.ModelVersion != nil && *key.ModelVersion != "" {
+		result["model_version"] = *key.ModelVersion
+	}
+	if len(key.ModelCapabilities) > 0 {
+		result["model_capabilities"] = key.ModelCapabilities
+	}
+	if key.PassphraseProtected {
+		result["passphrase_protected"] = true
+	}
+	return result
+}
+
+// printKeyMetadata prints a key's metadata without decrypting its value.
+func printKeyMetadata(key *models.APIKey) {
+	fmt.Printf("名称:     %s\n", key.Name)
+	fmt.Printf("提供商:   %s\n", key.Provider)
+	if key.Description != nil && *key.Description != "" {
+		fmt.Printf("描述:     %s\n", *key.Description)
+	}
+	if key.SourceProject != nil && *key.SourceProject != "" {
+		fmt.Printf("来源项目: %s\n", *key.SourceProject)
+	}
+	if len(key.Tags) > 0 {
+		fmt.Printf("标签:     %s\n", strings.Join(key.Tags, ", "))
+	}
+	status := "启用"
+	if !key.IsActive {
+		status = "停用"
+	}
+	fmt.Printf("状态:     %s\n", status)
+	if key.PassphraseProtected {
+		fmt.Println("口令保护: 是")
+	}
+	fmt.Printf("创建时间: %s\n", key.CreatedAt.Format("2006-01-02 15:04"))
+	if key.CreatedBy != nil {
+		fmt.Printf("创建者:   %s\n", *key.CreatedBy)
+	}
+	fmt.Printf("更新时间: %s\n", key.UpdatedAt.Format("2006-01-02 15:04"))
+	if key.UpdatedBy != nil {
+		fmt.Printf("更新者:   %s\n", *key.UpdatedBy)
+	}
+	if key.ExpiresAt.Time != nil {
+		fmt.Printf("过期时间: %s\n", key.ExpiresAt.Time.Format("2006-01-02 15:04"))
+	}
+	if key.ModelName != nil && *key.ModelName != "" {
+		model := *key.ModelName
+		if key.ModelVersion != nil && *key.ModelVersion != "" {
+			model += " (" + *key.ModelVersion + ")"
+		}
+		fmt.Printf("模型:     %s\n", model)
+	}
+	if len(key.ModelCapabilities) > 0 {
+		fmt.Printf("模型能力: %s\n", strings.Join(key.ModelCapabilities, ", "))
+	}
+	if n := len(key.ValueHistory); n > 0 {
+		fmt.Printf("历史版本: %d\n", n)
+	}
+}
+
+// runGetAll implements `get --all`: a confirmed bulk read of every matching key.
+func runGetAll(cmd *cobra.Command) error {
+	noConfirm, _ := cmd.Flags().GetBool("yes")
+	force, _ := cmd.Flags().GetBool("force")
+	provider, _ := cmd.Flags().GetString("provider")
+	tag, _ := cmd.Flags().GetString("tag")
+	format, _ := cmd.Flags().GetString("format")
+	output, _ := cmd.Flags().GetString("output")
+	if jsonOutput {
+		format = "json"
+	}
+
+	if format != "env" && format != "json" && format != "posix" {
+		return fmt.Errorf("不支持的格式 '%s'（可选: env, posix, json）", format)
+	}
+
+	if err := core.CheckReveal(); err != nil {
+		return err
+	}
+
+	if output == "" && term.IsTerminal(int(os.Stdout.Fd())) && !noConfirm && !force {
+		return fmt.Errorf("拒绝将全部密钥输出到终端，请使用 --output 写入文件，或加 --yes/--force")
+	}
+	if output != "" && !force {
+		if _, err := os.Stat(output); err == nil {
+			return fmt.Errorf("文件 %s 已存在，使用 --force 覆盖", output)
+		}
+	}
+
+	storage, err := core.GetStorage()
+	if err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+
+	if !noConfirm && !confirm("确认获取全部匹配密钥的明文值?") {
+		fmt.Fprintln(statusWriter(), "已取消")
+		return nil
+	}
+
+	keys, err := storage.GetKeysForRead("cli-get", provider, tag)
+	if err != nil {
+		return fmt.Errorf("获取密钥失败: %w", err)
+	}
+	for name, value := range keys {
+		if keys[name], err = core.RevealValue(value, false); err != nil {
+			return err
+		}
+	}
+
+	if output == "" {
+		return writeKeys(os.Stdout, keys, format, "")
+	}
+
+	f, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("写入文件失败: %w", err)
+	}
+	defer f.Close()
+	// O_CREATE keeps an existing file's mode; secrets must not stay world-readable
+	if err := f.Chmod(0600); err != nil {
+		return fmt.Errorf("设置文件权限失败: %w", err)
+	}
+	if err := writeKeys(f, keys, format, ""); err != nil {
+		return fmt.Errorf("写入文件失败: %w", err)
+	}
+	printSuccess("已将 %d 个密钥写入 %s", len(keys), output)
+	return nil
+}
+
 var addCmd = &cobra.Command{
 	Use:   "add <KEY_NAME>",
 	Short: "添加新密钥",
-	Long:  "添加新的 API 密钥（交互式隐藏输入）",
-	Args:  cobra.ExactArgs(1),
+	Long: `添加新的 API 密钥（交互式隐藏输入）。
+
+--batch 从标准输入读取多行 NAME=value（支持 export 前缀、双引号/单引号值、# 注释），
+确认一次后一次性保存；已存在的密钥默认跳过，--overwrite 时替换值（旧值保留在历史中）。
+
+--expires 接受 RFC3339 时间 (2026-12-31T00:00:00+08:00)、日期 (2026-12-31) 或相对时长 (90d)。
+
+示例:
+  akm add NEW_KEY -p openai
+  akm add NEW_KEY -p openai --expires 90d --model gpt-4o --capability chat --capability vision
+  akm add --batch -p openai          # 粘贴多行，空行或 Ctrl-D 结束（输入不回显）
+  akm add --batch -p openai -y <<'EOF'
+  OPENAI_API_KEY=sk-...
+  OPENAI_ORG_KEY="sk-..."
+  EOF`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if batch, _ := cmd.Flags().GetBool("batch"); batch {
+			if len(args) > 0 {
+				return fmt.Errorf("--batch 从标准输入读取密钥，不能再指定密钥名称")
+			}
+			return nil
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if batch, _ := cmd.Flags().GetBool("batch"); batch {
+			return runAddBatch(cmd)
+		}
+
 		keyName := args[0]
 		provider, _ := cmd.Flags().GetString("provider")
 		description, _ := cmd.Flags().GetString("description")
 		valueFlag, _ := cmd.Flags().GetString("value")
+		strict, _ := cmd.Flags().GetBool("strict")
+		withPassphrase, _ := cmd.Flags().GetBool("passphrase")
+
+		var opts []core.KeyOption
+		expiresAt, hasExpiry, err := expiryFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		if hasExpiry {
+			opts = append(opts, core.WithExpiresAt(expiresAt))
+		}
+		if cmd.Flags().Changed("model") {
+			v, _ := cmd.Flags().GetString("model")
+			opts = append(opts, core.WithModel(v))
+		}
+		if cmd.Flags().Changed("model-version") {
+			v, _ := cmd.Flags().GetString("model-version")
+			opts = append(opts, core.WithModelVersion(v))
+		}
+		if cmd.Flags().Changed("capability") {
+			opts = append(opts, core.WithCapabilities(capabilitiesFromFlag(cmd)))
+		}
 
 		storage, err := core.GetStorage()
 		if err != nil {
@@ -137,28 +599,21 @@ var addCmd = &cobra.Command{
 			return fmt.Errorf("密钥 '%s' 已存在，使用 'akm update' 更新", keyName)
 		}
 
-		var value string
-		if valueFlag != "" {
-			value = valueFlag
-		} else {
-			// Interactive hidden input
-			fmt.Printf("请输入 %s 的值: ", keyName)
-			byteValue, err := term.ReadPassword(int(syscall.Stdin))
-			if err != nil {
-				return fmt.Errorf("读取输入失败: %w", err)
-			}
-			fmt.Println() // New line after hidden input
-			value = string(byteValue)
-		}
-
-		if value == "" {
-			return fmt.Errorf("密钥值不能为空")
+		value, err := readKeyValue(keyName, valueFlag, strict)
+		if err != nil {
+			return err
 		}
 
-		var opts []core.KeyOption
 		if description != "" {
 			opts = append(opts, core.WithDescription(description))
 		}
+		if withPassphrase {
+			passphrase, err := readNewPassphrase()
+			if err != nil {
+				return err
+			}
+			opts = append(opts, core.WithPassphrase(passphrase))
+		}
 
 		key, err := storage.AddKey(keyName, value, provider, opts...)
 		if err != nil {
@@ -166,6 +621,395 @@ var addCmd = &cobra.Command{
 		}
 
 		printSuccess("已添加密钥 '%s' (provider: %s)", key.Name, key.Provider)
+		if key.ExpiresAt.Time != nil {
+			fmt.Printf("   过期时间: %s\n", key.ExpiresAt.Time.Format("2006-01-02 15:04"))
+		}
+		return nil
+	},
+}
+
+// expiryFromFlags resolves --expires or --expires-in; ok is false when
+// neither was given.
+func expiryFromFlags(cmd *cobra.Command) (t time.Time, ok bool, err error) {
+	expires, _ := cmd.Flags().GetString("expires")
+	expiresIn, _ := cmd.Flags().GetString("expires-in")
+	switch {
+	case expires != "" && expiresIn != "":
+		return time.Time{}, false, fmt.Errorf("--expires 与 --expires-in 不能同时使用")
+	case expires != "":
+		if t, err = core.ParseExpiry(expires); err != nil {
+			return time.Time{}, false, fmt.Errorf("--expires 无效: %w", err)
+		}
+	case expiresIn != "":
+		if t, err = core.ExpiryFromNow(expiresIn); err != nil {
+			return time.Time{}, false, fmt.Errorf("--expires-in 无效: %w", err)
+		}
+	default:
+		return time.Time{}, false, nil
+	}
+	return t, true, nil
+}
+
+// capabilitiesFromFlag returns the --capability values, trimmed and
+// de-duplicated; empty values are dropped so --capability "" clears the list.
+func capabilitiesFromFlag(cmd *cobra.Command) []string {
+	values, _ := cmd.Flags().GetStringArray("capability")
+	capabilities := []string{}
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" && !slices.Contains(capabilities, v) {
+			capabilities = append(capabilities, v)
+		}
+	}
+	return capabilities
+}
+
+// promptForValue is what a bare `update --value` yields: ask for the value
+// with hidden input instead of taking it from the command line.
+const promptForValue = "<prompt>"
+
+var updateCmd = &cobra.Command{
+	Use:   "update <KEY_NAME>",
+	Short: "更新密钥元数据或值",
+	Long: `更新密钥的提供商、描述、启用状态、过期时间或模型信息。
+
+--value 直接替换密钥值且不保留旧值（旧值无法恢复）；需要保留历史以便回滚请用 rotate。
+单独写 --value 时交互式隐藏输入；也可用 --value=<值> 直接指定（不推荐）。
+
+--expires-in 支持 d(天)、w(周)、mo(月, 按 30 天计) 以及 h/m/s 等标准单位；
+--expires 另外接受 RFC3339 时间或日期 (2026-12-31)。
+--capability 可重复，替换原有列表；--capability "" 清空。
+
+示例:
+  akm update OPENAI_API_KEY --value          # 交互式输入新值
+  akm update OPENAI_API_KEY --expires-in 90d
+  akm update OPENAI_API_KEY --expires 2026-12-31T00:00:00Z
+  akm update OPENAI_API_KEY --model gpt-4o --model-version 2024-08-06 --capability chat
+  akm update OPENAI_API_KEY -p openai-azure -d "Azure 部署"
+  akm update OLD_KEY --active=false`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		setValue := cmd.Flags().Changed("value")
+		updates := map[string]interface{}{}
+		if cmd.Flags().Changed("provider") {
+			v, _ := cmd.Flags().GetString("provider")
+			updates["provider"] = v
+		}
+		if cmd.Flags().Changed("description") {
+			v, _ := cmd.Flags().GetString("description")
+			updates["description"] = v
+		}
+		if cmd.Flags().Changed("active") {
+			v, _ := cmd.Flags().GetBool("active")
+			updates["is_active"] = v
+		}
+		expiresAt, hasExpiry, err := expiryFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		if hasExpiry {
+			updates["expires_at"] = expiresAt
+		}
+		if cmd.Flags().Changed("model") {
+			v, _ := cmd.Flags().GetString("model")
+			updates["model_name"] = v
+		}
+		if cmd.Flags().Changed("model-version") {
+			v, _ := cmd.Flags().GetString("model-version")
+			updates["model_version"] = v
+		}
+		if cmd.Flags().Changed("capability") {
+			updates["model_capabilities"] = capabilitiesFromFlag(cmd)
+		}
+		if len(updates) == 0 && !setValue {
+			return fmt.Errorf("没有要更新的字段，参见 'akm update --help'")
+		}
+
+		storage, err := core.GetStorage()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+
+		key := storage.GetKey(args[0])
+		if key == nil {
+			return fmt.Errorf("密钥 '%s' 不存在", args[0])
+		}
+
+		if setValue {
+			valueFlag, _ := cmd.Flags().GetString("value")
+			if valueFlag == promptForValue {
+				valueFlag = ""
+			}
+			strict, _ := cmd.Flags().GetBool("strict")
+			value, err := readKeyValue(key.Name, valueFlag, strict)
+			if err != nil {
+				return err
+			}
+			if key, err = storage.UpdateKeyValue(key.Name, value); err != nil {
+				return fmt.Errorf("更新密钥值失败: %w", err)
+			}
+		}
+
+		if len(updates) > 0 {
+			if key, err = storage.UpdateKey(key.Name, updates); err != nil {
+				return fmt.Errorf("更新密钥失败: %w", err)
+			}
+		}
+
+		printSuccess("已更新密钥 '%s'", key.Name)
+		if setValue {
+			fmt.Println("   密钥值已替换（旧值未保留）")
+		}
+		if key.ExpiresAt.Time != nil {
+			fmt.Printf("   过期时间: %s\n", key.ExpiresAt.Time.Format("2006-01-02 15:04"))
+		}
+		return nil
+	},
+}
+
+// readKeyPassphrase prompts for the passphrase of a protected key; it returns
+// "" without prompting for other keys.
+func readKeyPassphrase(key *models.APIKey) (string, error) {
+	if !key.PassphraseProtected {
+		return "", nil
+	}
+	fmt.Printf("请输入 %s 的口令: ", key.Name)
+	passphrase, err := term.ReadPassword(int(syscall.Stdin))
+	fmt.Println()
+	if err != nil {
+		return "", fmt.Errorf("读取口令失败: %w", err)
+	}
+	return string(passphrase), nil
+}
+
+// readNewPassphrase prompts twice for a new per-key passphrase.
+func readNewPassphrase() (string, error) {
+	fmt.Print("设置口令: ")
+	first, err := term.ReadPassword(int(syscall.Stdin))
+	fmt.Println()
+	if err != nil {
+		return "", fmt.Errorf("读取口令失败: %w", err)
+	}
+	if len(first) == 0 {
+		return "", fmt.Errorf("口令不能为空")
+	}
+	fmt.Print("确认口令: ")
+	second, err := term.ReadPassword(int(syscall.Stdin))
+	fmt.Println()
+	if err != nil {
+		return "", fmt.Errorf("读取口令失败: %w", err)
+	}
+	if string(first) != string(second) {
+		return "", fmt.Errorf("两次输入的口令不一致")
+	}
+	return string(first), nil
+}
+
+// readBatchLines reads NAME=value lines from stdin until EOF. On a terminal
+// each line is read without echo and an empty line also ends input.
+func readBatchLines() ([]string, error) {
+	var lines []string
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		scanner := bufio.NewScanner(os.Stdin)
+		scanner.Buffer(make([]byte, 64<<10), 1<<20)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		return lines, scanner.Err()
+	}
+
+	fmt.Println("逐行粘贴 NAME=value（输入不回显），空行或 Ctrl-D 结束:")
+	for {
+		line, err := term.ReadPassword(int(syscall.Stdin))
+		if err != nil || strings.TrimSpace(string(line)) == "" {
+			break
+		}
+		lines = append(lines, string(line))
+		fmt.Printf("  已读取 %d 行\r", len(lines))
+	}
+	fmt.Println()
+	return lines, nil
+}
+
+// runAddBatch implements `add --batch`.
+func runAddBatch(cmd *cobra.Command) error {
+	provider, _ := cmd.Flags().GetString("provider")
+	strict, _ := cmd.Flags().GetBool("strict")
+	overwrite, _ := cmd.Flags().GetBool("overwrite")
+	noConfirm, _ := cmd.Flags().GetBool("yes")
+
+	// A piped stdin is consumed by the batch itself, leaving nothing to confirm with
+	if !noConfirm && !term.IsTerminal(int(os.Stdin.Fd())) {
+		return fmt.Errorf("从管道读取时无法交互确认，请加 --yes")
+	}
+
+	storage, err := core.GetStorage()
+	if err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+
+	lines, err := readBatchLines()
+	if err != nil {
+		return fmt.Errorf("读取输入失败: %w", err)
+	}
+
+	var entries []core.BatchKey
+	failed := 0
+	for i, line := range lines {
+		name, value, ok, err := core.ParseEnvLine(line)
+		if err != nil {
+			printError("第 %d 行: %v", i+1, err)
+			failed++
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if err := core.CheckValueStrength(value); err != nil && value != "" {
+			if strict {
+				printError("%s: 密钥值校验失败: %v", name, err)
+				failed++
+				continue
+			}
+			printWarning("%s: 密钥值可能不完整: %v", name, err)
+		}
+		entries = append(entries, core.BatchKey{Name: name, Value: value})
+	}
+	if len(entries) == 0 {
+		if failed > 0 {
+			return fmt.Errorf("没有可添加的密钥（%d 行有误）", failed)
+		}
+		fmt.Println("没有读取到密钥")
+		return nil
+	}
+
+	fmt.Printf("将添加 %d 个密钥 (provider: %s):\n", len(entries), provider)
+	for _, e := range entries {
+		fmt.Printf("  - %s\n", e.Name)
+	}
+	if !noConfirm && !confirm("确认添加?") {
+		fmt.Println("已取消")
+		return nil
+	}
+
+	report, err := storage.AddKeysBatch(entries, provider, overwrite)
+	if err != nil {
+		return fmt.Errorf("批量添加失败: %w", err)
+	}
+	return printBatchReport(report, failed)
+}
+
+// printBatchReport prints the outcome of AddKeysBatch. failed counts entries
+// rejected before the batch ran; any failure makes the result an error.
+func printBatchReport(report *core.BatchAddReport, failed int) error {
+	for _, name := range report.Added {
+		printSuccess("已添加 %s", name)
+	}
+	for _, name := range report.Overwritten {
+		printSuccess("已覆盖 %s（旧值保留在历史中）", name)
+	}
+	for _, name := range report.Skipped {
+		printWarning("已存在，跳过 %s（使用 --overwrite 覆盖）", name)
+	}
+	for _, f := range report.Failed {
+		printError("%s: %v", f.Name, f.Err)
+	}
+	failed += len(report.Failed)
+
+	fmt.Printf("\n添加 %d，覆盖 %d，跳过 %d，失败 %d\n",
+		len(report.Added), len(report.Overwritten), len(report.Skipped), failed)
+	if failed > 0 {
+		return fmt.Errorf("%d 个密钥添加失败", failed)
+	}
+	return nil
+}
+
+// readKeyValue returns the value from --value or hidden interactive input,
+// then applies the strength check (warning, or error when strict).
+func readKeyValue(keyName, valueFlag string, strict bool) (string, error) {
+	var value string
+	if valueFlag != "" {
+		value = valueFlag
+	} else {
+		// Interactive hidden input
+		fmt.Printf("请输入 %s 的值: ", keyName)
+		byteValue, err := term.ReadPassword(int(syscall.Stdin))
+		if err != nil {
+			return "", fmt.Errorf("读取输入失败: %w", err)
+		}
+		fmt.Println() // New line after hidden input
+		value = string(byteValue)
+	}
+
+	if value == "" {
+		return "", fmt.Errorf("密钥值不能为空")
+	}
+
+	if err := core.CheckValueStrength(value); err != nil {
+		if strict {
+			return "", fmt.Errorf("密钥值校验失败: %w", err)
+		}
+		printWarning("密钥值可能不完整: %v（使用 --strict 拒绝此类值）", err)
+	}
+	return value, nil
+}
+
+var rotateCmd = &cobra.Command{
+	Use:   "rotate <KEY_NAME>",
+	Short: "轮换密钥值",
+	Long: `用新值替换密钥，旧值加密保存在历史中（最多保留若干个版本）。
+
+示例:
+  akm rotate OPENAI_API_KEY          # 交互式输入新值
+  akm get OPENAI_API_KEY --version 1 # 查看上一个值
+  akm rollback OPENAI_API_KEY        # 恢复上一个值`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		keyName := args[0]
+		valueFlag, _ := cmd.Flags().GetString("value")
+		strict, _ := cmd.Flags().GetBool("strict")
+
+		storage, err := core.GetStorage()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+
+		if storage.GetKey(keyName) == nil {
+			return fmt.Errorf("密钥 '%s' 不存在", keyName)
+		}
+
+		value, err := readKeyValue(keyName, valueFlag, strict)
+		if err != nil {
+			return err
+		}
+
+		key, err := storage.RotateKeyValue(keyName, value)
+		if err != nil {
+			return fmt.Errorf("轮换密钥失败: %w", err)
+		}
+
+		printSuccess("已轮换密钥 '%s' (保留 %d 个历史版本)", key.Name, len(key.ValueHistory))
+		return nil
+	},
+}
+
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback <KEY_NAME>",
+	Short: "恢复密钥的上一个值",
+	Long:  "将密钥恢复为上一个值，当前值进入历史（可再次 rollback 撤销）",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		keyName := args[0]
+
+		storage, err := core.GetStorage()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+
+		if _, err := storage.RollbackKeyValue(keyName); err != nil {
+			return fmt.Errorf("恢复失败: %w", err)
+		}
+
+		printSuccess("已将 '%s' 恢复为上一个值", keyName)
 		return nil
 	},
 }
@@ -173,8 +1017,9 @@ var addCmd = &cobra.Command{
 var deleteCmd = &cobra.Command{
 	Use:   "delete <KEY_NAME>",
 	Short: "删除密钥",
-	Long:  "删除指定的 API 密钥",
-	Args:  cobra.ExactArgs(1),
+	Long: `删除指定的 API 密钥。密钥移入回收站，保留期内（AKM_TRASH_RETENTION，默认 30d）
+可用 akm restore 恢复；akm trash list 查看，akm trash empty 永久清空。`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		keyName := args[0]
 		force, _ := cmd.Flags().GetBool("force")
@@ -188,22 +1033,80 @@ var deleteCmd = &cobra.Command{
 			return fmt.Errorf("密钥 '%s' 不存在", keyName)
 		}
 
-		if !force {
-			fmt.Printf("确认删除密钥 '%s'? 此操作不可恢复! [y/N]: ", keyName)
-			reader := bufio.NewReader(os.Stdin)
-			response, _ := reader.ReadString('\n')
-			response = strings.TrimSpace(strings.ToLower(response))
-			if response != "y" && response != "yes" {
-				fmt.Println("已取消")
-				return nil
-			}
+		if !force && !confirm(deletePrompt(fmt.Sprintf("密钥 '%s'", keyName))) {
+			fmt.Println("已取消")
+			return nil
 		}
 
 		if err := storage.DeleteKey(keyName); err != nil {
 			return fmt.Errorf("删除密钥失败: %w", err)
 		}
 
-		printSuccess("已删除密钥 '%s'", keyName)
+		printDeleted(keyName)
+		return nil
+	},
+}
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "清理停用或过期的密钥",
+	Long: `删除停用 (--inactive) 和/或已过期 (--expired) 的密钥。
+未指定条件时两者都清理。清理的密钥与 delete 一样移入回收站，
+保留期内可用 akm restore 恢复。
+
+示例:
+  akm prune --dry-run           # 预览将被删除的密钥
+  akm prune --expired -f        # 删除已过期密钥，跳过确认`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		inactive, _ := cmd.Flags().GetBool("inactive")
+		expired, _ := cmd.Flags().GetBool("expired")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		force, _ := cmd.Flags().GetBool("force")
+
+		if !inactive && !expired {
+			inactive, expired = true, true
+		}
+		filter := core.PruneFilter{Inactive: inactive, Expired: expired}
+
+		storage, err := core.GetStorage()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+
+		candidates := storage.PruneCandidates(filter)
+		if len(candidates) == 0 {
+			fmt.Println("没有需要清理的密钥")
+			return nil
+		}
+
+		fmt.Printf("将清理 %d 个密钥:\n", len(candidates))
+		for _, key := range candidates {
+			reason := "停用"
+			if key.IsExpired(time.Now()) {
+				reason = "已过期 " + key.ExpiresAt.Time.Format("2006-01-02")
+			}
+			fmt.Printf("  - %s (%s, %s)\n", key.Name, key.Provider, reason)
+		}
+
+		if dryRun {
+			return nil
+		}
+
+		if !force && !confirm(deletePrompt("以上密钥")) {
+			fmt.Println("已取消")
+			return nil
+		}
+
+		removed, err := storage.Prune(filter)
+		if err != nil {
+			return fmt.Errorf("清理失败: %w", err)
+		}
+
+		if core.TrashRetention() == 0 {
+			printSuccess("已清理 %d 个密钥", len(removed))
+			return nil
+		}
+		printSuccess("已清理 %d 个密钥（可用 akm restore <KEY_NAME> 恢复）", len(removed))
 		return nil
 	},
 }
@@ -211,7 +1114,7 @@ var deleteCmd = &cobra.Command{
 var searchCmd = &cobra.Command{
 	Use:   "search <QUERY>",
 	Short: "搜索密钥",
-	Long:  "按名称、提供商、描述等搜索密钥",
+	Long:  "按名称、提供商、描述、来源项目和标签搜索密钥（不区分大小写，子串匹配）",
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		query := args[0]
@@ -222,6 +1125,22 @@ var searchCmd = &cobra.Command{
 		}
 
 		keys := storage.SearchKeys(query)
+		if jsonOutput {
+			type result struct {
+				Name        string  `json:"name"`
+				Provider    string  `json:"provider"`
+				Description *string `json:"description,omitempty"`
+			}
+			results := make([]result, 0, len(keys))
+			for _, key := range keys {
+				results = append(results, result{Name: key.Name, Provider: key.Provider, Description: key.Description})
+			}
+			return printJSON(map[string]interface{}{
+				"query":   query,
+				"results": results,
+				"count":   len(results),
+			})
+		}
 		if len(keys) == 0 {
 			fmt.Printf("没有找到匹配 '%s' 的密钥\n", query)
 			return nil
@@ -248,28 +1167,65 @@ var searchCmd = &cobra.Command{
 	},
 }
 
-// maskValue masks the middle part of a value for display.
-func maskValue(value string) string {
-	if len(value) <= 8 {
-		return strings.Repeat("*", len(value))
-	}
-	return value[:4] + strings.Repeat("*", len(value)-8) + value[len(value)-4:]
-}
-
 func init() {
 	// list flags
-	listCmd.Flags().StringP("provider", "p", "", "按提供商过滤")
+	listCmd.Flags().StringP("provider", "p", "", "按提供商过滤 (支持通配符, 如 'openai*')")
 	listCmd.Flags().Bool("show-value", false, "显示密钥值（部分遮盖）")
+	listCmd.Flags().Bool("json-lines", false, "以 NDJSON 逐行输出（不含密钥值）")
+	listCmd.Flags().String("sort-by", "name", "排序: name（不区分大小写）, provider, created, updated（后两者最新在前）")
+	listCmd.Flags().Bool("expired", false, "只列出已过期的密钥")
+	listCmd.Flags().Bool("select", false, "编号显示并交互选择密钥执行操作（仅限终端）")
 
 	// get flags
 	getCmd.Flags().BoolP("yes", "y", false, "跳过确认")
-	getCmd.Flags().BoolP("copy", "c", false, "复制到剪贴板")
+	getCmd.Flags().BoolP("copy", "c", false, "复制到剪贴板而不输出值（无剪贴板时退回输出并警告）")
+	getCmd.Flags().Int("version", 0, "历史版本（0=当前，1=上一个值）")
+	getCmd.Flags().Bool("metadata", false, "只显示元数据（创建者、更新者等），不显示密钥值")
+	getCmd.Flags().Bool("all", false, "输出全部匹配的密钥")
+	getCmd.Flags().StringP("provider", "p", "", "配合 --all 按提供商过滤 (支持通配符, 如 'openai*')")
+	getCmd.Flags().StringP("tag", "t", "", "配合 --all 按标签过滤")
+	getCmd.Flags().StringP("format", "F", "env", "配合 --all 的输出格式: env, posix, json")
+	getCmd.Flags().StringP("output", "o", "", "配合 --all 写入文件（权限 0600）")
+	getCmd.Flags().BoolP("force", "f", false, "配合 --all 允许输出到终端或覆盖已存在的文件")
 
 	// add flags
 	addCmd.Flags().StringP("provider", "p", "unknown", "提供商名称")
 	addCmd.Flags().StringP("description", "d", "", "密钥描述")
 	addCmd.Flags().StringP("value", "v", "", "密钥值（不推荐，建议使用交互式输入）")
+	addCmd.Flags().Bool("strict", false, "密钥值过短或熵过低时拒绝添加")
+	addCmd.Flags().String("expires-in", "", "多久后过期 (如 30d, 2w, 3mo)")
+	addCmd.Flags().String("expires", "", "过期时间: RFC3339、日期 (2026-12-31) 或相对时长 (90d)")
+	addCmd.Flags().String("model", "", "模型名称 (如 gpt-4o)")
+	addCmd.Flags().String("model-version", "", "模型版本")
+	addCmd.Flags().StringArray("capability", nil, "模型能力，可重复 (如 chat, vision, embeddings)")
+	addCmd.Flags().Bool("passphrase", false, "额外用独立口令加密（读取时需要口令）")
+	addCmd.Flags().Bool("batch", false, "从标准输入批量读取 NAME=value 行")
+	addCmd.Flags().Bool("overwrite", false, "配合 --batch 覆盖已存在的密钥")
+	addCmd.Flags().BoolP("yes", "y", false, "配合 --batch 跳过确认")
+
+	// update flags
+	updateCmd.Flags().StringP("provider", "p", "", "提供商名称")
+	updateCmd.Flags().StringP("description", "d", "", "密钥描述")
+	updateCmd.Flags().Bool("active", true, "启用或停用密钥")
+	updateCmd.Flags().String("expires-in", "", "多久后过期 (如 30d, 2w, 3mo)")
+	updateCmd.Flags().String("expires", "", "过期时间: RFC3339、日期 (2026-12-31) 或相对时长 (90d)")
+	updateCmd.Flags().String("model", "", "模型名称")
+	updateCmd.Flags().String("model-version", "", "模型版本")
+	updateCmd.Flags().StringArray("capability", nil, "模型能力，可重复，替换原有列表")
+	updateCmd.Flags().StringP("value", "v", "", "替换密钥值，不保留旧值（单独使用时交互式输入）")
+	updateCmd.Flags().Lookup("value").NoOptDefVal = promptForValue
+	updateCmd.Flags().Bool("strict", false, "密钥值过短或熵过低时拒绝")
+
+	// rotate flags
+	rotateCmd.Flags().StringP("value", "v", "", "新密钥值（不推荐，建议使用交互式输入）")
+	rotateCmd.Flags().Bool("strict", false, "密钥值过短或熵过低时拒绝")
 
 	// delete flags
 	deleteCmd.Flags().BoolP("force", "f", false, "跳过确认")
+
+	// prune flags
+	pruneCmd.Flags().Bool("inactive", false, "清理停用的密钥")
+	pruneCmd.Flags().Bool("expired", false, "清理已过期的密钥")
+	pruneCmd.Flags().Bool("dry-run", false, "只显示将被清理的密钥")
+	pruneCmd.Flags().BoolP("force", "f", false, "跳过确认")
 }
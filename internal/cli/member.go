@@ -0,0 +1,170 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/baobao/akm-go/internal/core"
+	"github.com/spf13/cobra"
+)
+
+var memberCmd = &cobra.Command{
+	Use:   "member",
+	Short: "团队成员（多人共享密钥库）",
+	Long: `团队模式: 把 master key 分别用每位成员的 X25519 公钥加密（key wrapping）保存在 members.json，
+成员用自己的私钥解开 master key，无需共享同一个 master 秘密。默认仍是单 master key 模式。
+
+密钥格式与 age 兼容: 公钥 age1...，私钥 AGE-SECRET-KEY-1...（age-keygen 生成的密钥也可用）。
+
+成员端: 同步共享的数据目录（~/.apikey-manager/data），把私钥放在 ~/.apikey-manager/identity.txt
+或用 AKM_IDENTITY_FILE 指定；Keychain 中没有 master key 时自动用私钥解锁，
+设置 AKM_IDENTITY_FILE 时优先于 Keychain。
+
+示例:
+  akm member keygen                        # 成员生成自己的密钥对，把公钥发给管理员
+  akm member add age1... --name alice      # 已能解密的人为新成员包装 master key
+  akm member ls
+  akm member rm alice                      # 删除包装；彻底撤销还需 akm master-key rotate`,
+}
+
+var memberKeygenCmd = &cobra.Command{
+	Use:   "keygen",
+	Short: "生成成员密钥对",
+	Long: `生成 X25519 密钥对，私钥写入文件（0600），公钥打印到 stdout。
+
+示例:
+  akm member keygen
+  akm member keygen -o ~/.config/akm/identity.txt`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		output, _ := cmd.Flags().GetString("output")
+		force, _ := cmd.Flags().GetBool("force")
+
+		if output == "" {
+			path, err := core.DefaultIdentityFile()
+			if err != nil {
+				return err
+			}
+			output = path
+		}
+		if !force {
+			if _, err := os.Stat(output); err == nil {
+				return fmt.Errorf("文件 '%s' 已存在，使用 -f 强制覆盖（旧私钥将无法再解锁）", output)
+			}
+		}
+
+		identity, recipient, err := core.GenerateIdentity()
+		if err != nil {
+			return fmt.Errorf("生成密钥对失败: %w", err)
+		}
+		content := fmt.Sprintf("# created: %s\n# public key: %s\n%s\n", time.Now().Format(time.RFC3339), recipient, identity)
+		if err := os.MkdirAll(filepath.Dir(output), 0700); err != nil {
+			return fmt.Errorf("创建目录失败: %w", err)
+		}
+		if err := os.WriteFile(output, []byte(content), 0600); err != nil {
+			return fmt.Errorf("写入私钥失败: %w", err)
+		}
+
+		printSuccess("私钥已写入 %s（请勿分享）", output)
+		fmt.Println("公钥（发给管理员执行 akm member add）:")
+		fmt.Println(recipient)
+		return nil
+	},
+}
+
+var memberAddCmd = &cobra.Command{
+	Use:   "add <PUBLIC_KEY>",
+	Short: "为新成员包装 master key",
+	Long: `用成员的公钥 (age1...) 包装当前 master key 并写入 members.json。
+需要当前能解密密钥库（Keychain 或已是成员）。
+
+示例:
+  akm member add age1... --name alice`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, _ := cmd.Flags().GetString("name")
+		if name == "" {
+			return fmt.Errorf("必须指定 --name")
+		}
+
+		storage, err := core.GetStorage()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+
+		member, err := storage.AddMember(name, args[0])
+		if err != nil {
+			return fmt.Errorf("添加成员失败: %w", err)
+		}
+
+		printSuccess("已为成员 %s 包装 master key", member.Name)
+		return nil
+	},
+}
+
+var memberListCmd = &cobra.Command{
+	Use:     "ls",
+	Aliases: []string{"list"},
+	Short:   "列出成员",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		storage, err := core.GetStorage()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+
+		members, err := storage.ListMembers()
+		if err != nil {
+			return fmt.Errorf("读取成员失败: %w", err)
+		}
+		if len(members) == 0 {
+			fmt.Println("暂无成员（单 master key 模式）。使用 'akm member add' 添加。")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "名称\t公钥\t添加时间")
+		fmt.Fprintln(w, "────\t────\t────────")
+		for _, m := range members {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", m.Name, m.Recipient, m.AddedAt.Local().Format("2006-01-02 15:04"))
+		}
+		w.Flush()
+		return nil
+	},
+}
+
+var memberRemoveCmd = &cobra.Command{
+	Use:     "rm <NAME|PUBLIC_KEY>",
+	Aliases: []string{"remove"},
+	Short:   "删除成员",
+	Long: `删除成员的 master key 包装。该成员可能已保存过 master key，
+要彻底撤销请随后运行 'akm master-key rotate'（新 key 只会为剩余成员重新包装）。`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		storage, err := core.GetStorage()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+
+		member, err := storage.RemoveMember(args[0])
+		if err != nil {
+			return fmt.Errorf("删除成员失败: %w", err)
+		}
+
+		printSuccess("已删除成员 %s", member.Name)
+		printWarning("彻底撤销其访问权限请运行 'akm master-key rotate'")
+		return nil
+	},
+}
+
+func init() {
+	memberKeygenCmd.Flags().StringP("output", "o", "", "私钥文件路径（默认 ~/.apikey-manager/identity.txt）")
+	memberKeygenCmd.Flags().BoolP("force", "f", false, "覆盖已存在的私钥文件")
+	memberAddCmd.Flags().String("name", "", "成员名称 (必须)")
+
+	memberCmd.AddCommand(memberKeygenCmd)
+	memberCmd.AddCommand(memberAddCmd)
+	memberCmd.AddCommand(memberListCmd)
+	memberCmd.AddCommand(memberRemoveCmd)
+}
@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/baobao/akm-go/internal/core"
+	"github.com/baobao/akm-go/internal/models"
+	"github.com/spf13/cobra"
+)
+
+var platformsCmd = &cobra.Command{
+	Use:   "platforms",
+	Short: "查看内置的平台注册表",
+	Long: `内置平台注册表 (platforms.json) 描述已知 provider 的 API 地址、接口格式、
+能力和文档链接。代理的路由、模型自动识别和密钥验证都以它为准。`,
+}
+
+var platformsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "列出已知平台",
+	Long: `列出注册表中的平台及其能力（流式 / 函数调用 / 视觉 / 音频）。
+
+示例:
+  akm platforms list
+  akm platforms list --category domestic
+  akm platforms list --models           # 同时列出支持的模型
+  akm platforms list --json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		category, _ := cmd.Flags().GetString("category")
+		showModels, _ := cmd.Flags().GetBool("models")
+
+		platforms := make([]models.Platform, 0)
+		for _, p := range core.Platforms() {
+			if category == "" || strings.EqualFold(p.Category, category) {
+				platforms = append(platforms, p)
+			}
+		}
+		if jsonOutput {
+			return printJSON(map[string]interface{}{
+				"platforms": platforms,
+				"count":     len(platforms),
+			})
+		}
+		if len(platforms) == 0 {
+			fmt.Println("没有找到平台")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\t名称\t类别\tAPI 地址\t格式\t能力\t文档")
+		fmt.Fprintln(w, "──\t────\t────\t────────\t────\t────\t────")
+		for _, p := range platforms {
+			docs := "-"
+			if p.DocsURL != nil {
+				docs = *p.DocsURL
+			}
+			name := p.Name
+			if !p.IsActive {
+				name += " (停用)"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", p.ID, name, p.Category, p.APIBase, p.APIFormat,
+				platformCapabilities(p), docs)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		if showModels {
+			for _, p := range platforms {
+				if len(p.SupportedModels) == 0 {
+					continue
+				}
+				fmt.Printf("\n%s: %s\n", p.ID, strings.Join(p.SupportedModels, ", "))
+			}
+		}
+		fmt.Printf("\n共 %d 个平台\n", len(platforms))
+		return nil
+	},
+}
+
+// platformCapabilities summarizes a platform's feature flags.
+func platformCapabilities(p models.Platform) string {
+	var caps []string
+	for _, c := range []struct {
+		ok   bool
+		name string
+	}{
+		{p.SupportsStreaming, "流式"},
+		{p.SupportsFunctionCalls, "函数"},
+		{p.SupportsVision, "视觉"},
+		{p.SupportsAudio, "音频"},
+	} {
+		if c.ok {
+			caps = append(caps, c.name)
+		}
+	}
+	if len(caps) == 0 {
+		return "-"
+	}
+	return strings.Join(caps, ",")
+}
+
+func init() {
+	platformsListCmd.Flags().String("category", "", "按类别过滤: international, domestic, aggregator, opensource")
+	platformsListCmd.Flags().Bool("models", false, "同时列出各平台支持的模型")
+
+	platformsCmd.AddCommand(platformsListCmd)
+}
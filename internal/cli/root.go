@@ -2,15 +2,22 @@
 package cli
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 
+	"github.com/baobao/akm-go/internal/core"
 	"github.com/spf13/cobra"
 )
 
 var (
 	// Version is set at build time
 	Version = "dev"
+
+	// jsonOutput is the global --json flag: machine-readable results on
+	// stdout, status messages on stderr
+	jsonOutput bool
 )
 
 var rootCmd = &cobra.Command{
@@ -33,6 +40,24 @@ var rootCmd = &cobra.Command{
   akm server                  # 启动 HTTP API 服务器
   akm mcp serve               # 启动 MCP 服务器`,
 	Version: Version,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		profile, _ := cmd.Flags().GetString("profile")
+		if profile == "" {
+			profile = os.Getenv("AKM_PROFILE")
+		}
+		jsonOutput, _ = cmd.Flags().GetBool("json")
+		return core.SetProfile(profile)
+	},
+}
+
+// ExitError carries a subprocess exit code (see `akm run`) that the caller
+// should exit with instead of the generic 1.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
 }
 
 // Execute runs the root command.
@@ -43,23 +68,41 @@ func Execute() error {
 func init() {
 	rootCmd.SetOut(os.Stdout)
 	rootCmd.SetErr(os.Stderr)
+	rootCmd.PersistentFlags().String("profile", "", "使用指定的存储配置（如 work），也可用 AKM_PROFILE；默认 default")
+	rootCmd.PersistentFlags().Bool("json", false, "以 JSON 输出结果（list、search、get、generate、verify-keys、platforms list），提示信息写入 stderr")
 
 	// Add subcommands
 	rootCmd.AddCommand(listCmd)
 	rootCmd.AddCommand(getCmd)
 	rootCmd.AddCommand(addCmd)
+	rootCmd.AddCommand(generateCmd)
+	rootCmd.AddCommand(importCmd)
+	rootCmd.AddCommand(updateCmd)
 	rootCmd.AddCommand(deleteCmd)
+	rootCmd.AddCommand(restoreCmd)
+	rootCmd.AddCommand(trashCmd)
+	rootCmd.AddCommand(rotateCmd)
+	rootCmd.AddCommand(rollbackCmd)
+	rootCmd.AddCommand(pruneCmd)
 	rootCmd.AddCommand(searchCmd)
+	rootCmd.AddCommand(tagCmd)
 	rootCmd.AddCommand(injectCmd)
 	rootCmd.AddCommand(runCmd)
 	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(catalogCmd)
+	rootCmd.AddCommand(platformsCmd)
+	rootCmd.AddCommand(shareCmd)
 	rootCmd.AddCommand(verifyCmd)
 	rootCmd.AddCommand(healthCmd)
 	rootCmd.AddCommand(backupCmd)
+	rootCmd.AddCommand(auditCmd)
+	rootCmd.AddCommand(statsCmd)
 	rootCmd.AddCommand(serverCmd)
 	rootCmd.AddCommand(mcpCmd)
 	rootCmd.AddCommand(masterKeyCmd)
+	rootCmd.AddCommand(memberCmd)
 	rootCmd.AddCommand(budgetCmd)
+	rootCmd.AddCommand(selfUpdateCmd)
 }
 
 // printError prints an error message to stderr.
@@ -67,9 +110,29 @@ func printError(format string, args ...interface{}) {
 	fmt.Fprintf(os.Stderr, "❌ "+format+"\n", args...)
 }
 
-// printSuccess prints a success message to stdout.
+// printSuccess prints a success message to stdout (stderr under --json).
 func printSuccess(format string, args ...interface{}) {
-	fmt.Printf("✅ "+format+"\n", args...)
+	fmt.Fprintf(statusWriter(), "✅ "+format+"\n", args...)
+}
+
+// statusWriter is where human-oriented messages and prompts go: stdout
+// normally, stderr under --json so stdout carries only the JSON document.
+func statusWriter() io.Writer {
+	if jsonOutput {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// printJSON writes v to stdout as indented JSON, the same layout the MCP
+// tools use.
+func printJSON(v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Println(string(data))
+	return err
 }
 
 // printWarning prints a warning message to stderr.
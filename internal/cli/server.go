@@ -2,6 +2,8 @@ package cli
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 
 	"github.com/baobao/akm-go/internal/http"
 	"github.com/baobao/akm-go/internal/mcp"
@@ -14,19 +16,69 @@ var serverCmd = &cobra.Command{
 	Long: `启动 HTTP API 服务器，提供 RESTful API 和 Web UI。
 
 示例:
-  akm server                    # 默认端口 8000
+  akm server                    # 默认 127.0.0.1:8000，仅本机可访问
   akm server --port 8080        # 指定端口
-  akm server --no-web           # 不启动 Web UI`,
+  akm server --bind 0.0.0.0     # 监听所有网卡（对外暴露，建议同时启用 TLS）
+  akm server --no-web           # 不启动 Web UI
+  akm server --tls-cert cert.pem --tls-key key.pem
+  akm server --tls-self-signed  # 生成短期自签名证书（仅开发用）
+  akm server --strict-provider  # 拒绝拼错/未知的模型名，而不是转发给上游
+
+环境变量:
+  AKM_TLS_CERT / AKM_TLS_KEY    # 等同于 --tls-cert / --tls-key
+  AKM_STRICT_PROVIDER=1         # 等同于 --strict-provider`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		bind, _ := cmd.Flags().GetString("bind")
 		port, _ := cmd.Flags().GetInt("port")
 		noWeb, _ := cmd.Flags().GetBool("no-web")
+		tlsCert, _ := cmd.Flags().GetString("tls-cert")
+		tlsKey, _ := cmd.Flags().GetString("tls-key")
+		selfSigned, _ := cmd.Flags().GetBool("tls-self-signed")
+		strictProvider, _ := cmd.Flags().GetBool("strict-provider")
+
+		if tlsCert == "" {
+			tlsCert = os.Getenv("AKM_TLS_CERT")
+		}
+		if tlsKey == "" {
+			tlsKey = os.Getenv("AKM_TLS_KEY")
+		}
+
+		if selfSigned {
+			if tlsCert != "" || tlsKey != "" {
+				return fmt.Errorf("--tls-self-signed 不能与 --tls-cert/--tls-key 同时使用")
+			}
+			homeDir, err := os.UserHomeDir()
+			if err != nil {
+				return err
+			}
+			tlsCert, tlsKey, err = http.EnsureSelfSignedCert(filepath.Join(homeDir, ".apikey-manager", "data", "tls"))
+			if err != nil {
+				return fmt.Errorf("生成自签名证书失败: %w", err)
+			}
+			printWarning("使用自签名证书，客户端需信任 %s", tlsCert)
+		}
+
+		if !http.IsLoopbackBind(bind) && tlsCert == "" {
+			printWarning("监听非回环地址 %s 且未启用 TLS，密钥和令牌将以明文在网络上传输", bind)
+		}
 
 		fmt.Printf("🚀 启动 API 服务器...\n")
+		fmt.Printf("   地址: %s\n", bind)
 		fmt.Printf("   端口: %d\n", port)
 		fmt.Printf("   Web UI: %v\n", !noWeb)
+		fmt.Printf("   TLS: %v\n", tlsCert != "")
 		fmt.Println()
 
-		return http.StartServer(port, !noWeb)
+		return http.StartServer(http.ServerOptions{
+			Bind:      bind,
+			Port:      port,
+			EnableWeb: !noWeb,
+			TLSCert:   tlsCert,
+			TLSKey:    tlsKey,
+			Version:   Version,
+
+			StrictProvider: strictProvider,
+		})
 	},
 }
 
@@ -49,9 +101,49 @@ var mcpServeCmd = &cobra.Command{
 	},
 }
 
+var mcpDoctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "检查 MCP 服务器配置",
+	Long: `不依赖客户端，在进程内完整走一遍 MCP 流程：注册工具、访问存储、
+initialize、tools/list 以及调用 akm_health，用于排查 "Agent 看不到密钥" 等问题。
+
+示例:
+  akm mcp doctor`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fmt.Println("🩺 MCP 自检")
+		if exe, err := os.Executable(); err == nil {
+			fmt.Printf("   可执行文件: %s (MCP 客户端配置中的 command 应指向它)\n", exe)
+		}
+		fmt.Println()
+
+		failed := 0
+		for _, c := range mcp.Doctor(cmd.Context()) {
+			icon := "✅"
+			if !c.OK {
+				icon = "❌"
+				failed++
+			}
+			fmt.Printf("%s %s: %s\n", icon, c.Name, c.Detail)
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("%d 项检查失败", failed)
+		}
+		fmt.Println()
+		printSuccess("MCP 配置正常")
+		return nil
+	},
+}
+
 func init() {
+	serverCmd.Flags().String("bind", http.DefaultBindAddress, "监听地址（0.0.0.0 表示所有网卡）")
 	serverCmd.Flags().IntP("port", "p", 8000, "服务器端口")
 	serverCmd.Flags().Bool("no-web", false, "不启动 Web UI")
+	serverCmd.Flags().String("tls-cert", "", "TLS 证书路径（启用 HTTPS）")
+	serverCmd.Flags().String("tls-key", "", "TLS 私钥路径")
+	serverCmd.Flags().Bool("tls-self-signed", false, "生成并使用短期自签名证书（开发用）")
+	serverCmd.Flags().Bool("strict-provider", false, "代理拒绝平台注册表中不存在的模型（返回 400 并列出可用模型）")
 
 	mcpCmd.AddCommand(mcpServeCmd)
+	mcpCmd.AddCommand(mcpDoctorCmd)
 }
@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var shareCmd = &cobra.Command{
+	Use:   "share <NAME>",
+	Short: "生成一次性查看链接",
+	Long: `请求正在运行的 akm server 生成一次性、短时有效的链接，把密钥交给同事而无需明文传递。
+链接只能打开一次（GET 返回 {"name","value"}），过期或使用后立即失效；
+令牌只保存在服务器内存中，服务器重启后所有链接失效。创建与查看都会记入审计日志。
+
+注意: 聊天工具的链接预览可能会"打开"链接，分享时请避免自动预览。
+
+环境变量:
+  AKM_SERVER_URL   # 服务器地址，等同于 --server（默认 http://localhost:8000）
+  AKM_API_KEY      # 服务器启用认证时使用的 API Key 或访问令牌（需 write 作用域）
+
+示例:
+  akm share OPENAI_API_KEY
+  akm share OPENAI_API_KEY --ttl 30m --server https://akm.internal:8443`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		server, _ := cmd.Flags().GetString("server")
+		ttl, _ := cmd.Flags().GetDuration("ttl")
+		if server == "" {
+			server = os.Getenv("AKM_SERVER_URL")
+		}
+		if server == "" {
+			server = "http://localhost:8000"
+		}
+
+		body, _ := json.Marshal(map[string]string{"name": args[0], "ttl": ttl.String()})
+		req, err := http.NewRequest("POST", strings.TrimRight(server, "/")+"/api/share", bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("无效的服务器地址: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if apiKey := os.Getenv("AKM_API_KEY"); apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+apiKey)
+		}
+
+		client := &http.Client{Timeout: 10 * time.Second}
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("无法连接 akm server (%s)，请先运行 'akm server': %w", server, err)
+		}
+		defer resp.Body.Close()
+
+		var result struct {
+			Name      string    `json:"name"`
+			URL       string    `json:"url"`
+			ExpiresAt time.Time `json:"expires_at"`
+			Error     string    `json:"error"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return fmt.Errorf("服务器响应无效 (HTTP %d): %w", resp.StatusCode, err)
+		}
+		if resp.StatusCode != http.StatusCreated {
+			return fmt.Errorf("生成分享链接失败 (HTTP %d): %s", resp.StatusCode, result.Error)
+		}
+
+		printSuccess("已为 %s 生成一次性链接（%s 前有效）:", result.Name, result.ExpiresAt.Local().Format("2006-01-02 15:04:05"))
+		fmt.Println(result.URL)
+		return nil
+	},
+}
+
+func init() {
+	shareCmd.Flags().String("server", "", "akm server 地址 (默认 $AKM_SERVER_URL 或 http://localhost:8000)")
+	shareCmd.Flags().Duration("ttl", 10*time.Minute, "链接有效期 (最长 24h)")
+}
@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/baobao/akm-go/internal/core"
+	"github.com/spf13/cobra"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "按审计日志统计密钥使用情况",
+	Long: `汇总审计日志中每个密钥的读取 (read)、注入 (inject)、导出 (export) 次数、
+最后使用时间和使用过的项目，按使用次数从多到少排列。
+
+超过 --stale-days 天（默认 90）未被使用的密钥标记为 💤，可考虑删除；
+在此期间内新添加的密钥不会被标记。
+
+示例:
+  akm stats
+  akm stats --stale-days 30
+  akm stats --stale-only        # 只列出可考虑删除的密钥`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		staleDays, _ := cmd.Flags().GetInt("stale-days")
+		staleOnly, _ := cmd.Flags().GetBool("stale-only")
+		if staleDays <= 0 {
+			return fmt.Errorf("--stale-days 必须大于 0")
+		}
+
+		storage, err := core.GetStorage()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+		stats, err := storage.UsageStats()
+		if err != nil {
+			return fmt.Errorf("读取审计日志失败: %w", err)
+		}
+		if len(stats) == 0 {
+			fmt.Println("暂无密钥")
+			return nil
+		}
+
+		names := make([]string, 0, len(stats))
+		for name := range stats {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			a, b := stats[names[i]], stats[names[j]]
+			if a.Total() != b.Total() {
+				return a.Total() > b.Total()
+			}
+			return names[i] < names[j]
+		})
+
+		cutoff := time.Now().AddDate(0, 0, -staleDays)
+		stale := 0
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "密钥\t读取\t注入\t导出\t最后使用\t项目\t")
+		fmt.Fprintln(w, "────\t────\t────\t────\t────────\t────\t")
+		for _, name := range names {
+			usage := stats[name]
+			isStale := usage.Stale(cutoff)
+			if isStale {
+				stale++
+			} else if staleOnly {
+				continue
+			}
+
+			lastUsed := "从未"
+			if !usage.LastUsed.IsZero() {
+				lastUsed = usage.LastUsed.Local().Format("2006-01-02 15:04")
+			}
+			projects := "-"
+			if len(usage.Projects) > 0 {
+				projects = strings.Join(usage.Projects, ",")
+			}
+			mark := ""
+			if isStale {
+				mark = "💤"
+			}
+			fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\t%s\t%s\n", name, usage.Reads, usage.Injects, usage.Exports,
+				lastUsed, projects, mark)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		if stale > 0 {
+			fmt.Println()
+			printWarning("%d 个密钥超过 %d 天未使用，可考虑删除（akm delete <name>）", stale, staleDays)
+		}
+		return nil
+	},
+}
+
+func init() {
+	statsCmd.Flags().Int("stale-days", 90, "超过多少天未使用视为闲置")
+	statsCmd.Flags().Bool("stale-only", false, "只列出闲置密钥")
+}
@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/baobao/akm-go/internal/core"
+	"github.com/baobao/akm-go/internal/models"
+	"github.com/spf13/cobra"
+)
+
+var tagCmd = &cobra.Command{
+	Use:   "tag",
+	Short: "批量管理密钥标签",
+	Long: `按提供商、名称或已有标签批量添加/移除标签，一次保存完成。
+
+示例:
+  akm tag add prod --provider openai          # 给所有 openai 密钥加 prod 标签
+  akm tag add team-a ml --provider 'openai*'  # 支持通配符和多个标签
+  akm tag remove staging --tag prod           # 从带 prod 标签的密钥移除 staging`,
+}
+
+var tagAddCmd = &cobra.Command{
+	Use:   "add <TAG>...",
+	Short: "为匹配的密钥添加标签",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runTagUpdate(cmd, args, true)
+	},
+}
+
+var tagRemoveCmd = &cobra.Command{
+	Use:   "remove <TAG>...",
+	Short: "从匹配的密钥移除标签",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runTagUpdate(cmd, args, false)
+	},
+}
+
+// runTagUpdate applies a bulk tag add or remove using the command's filter flags.
+func runTagUpdate(cmd *cobra.Command, tags []string, add bool) error {
+	provider, _ := cmd.Flags().GetString("provider")
+	name, _ := cmd.Flags().GetString("name")
+	tag, _ := cmd.Flags().GetString("tag")
+	all, _ := cmd.Flags().GetBool("all")
+
+	if provider == "" && name == "" && tag == "" && !all {
+		return fmt.Errorf("请指定 --provider、--name 或 --tag，或使用 --all 作用于全部密钥")
+	}
+
+	storage, err := core.GetStorage()
+	if err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+
+	filter := core.KeyFilter{Provider: provider, Name: name, Tag: tag}
+	var updated []*models.APIKey
+	if add {
+		updated, err = storage.AddTagsWhere(filter, tags)
+	} else {
+		updated, err = storage.RemoveTagsWhere(filter, tags)
+	}
+	if err != nil {
+		return fmt.Errorf("更新标签失败: %w", err)
+	}
+
+	if len(updated) == 0 {
+		fmt.Println("没有密钥需要更新")
+		return nil
+	}
+
+	sort.Slice(updated, func(i, j int) bool { return updated[i].Name < updated[j].Name })
+	for _, key := range updated {
+		fmt.Printf("  - %s (%s): %v\n", key.Name, key.Provider, key.Tags)
+	}
+	printSuccess("已更新 %d 个密钥", len(updated))
+	return nil
+}
+
+func init() {
+	for _, c := range []*cobra.Command{tagAddCmd, tagRemoveCmd} {
+		c.Flags().StringP("provider", "p", "", "按提供商过滤 (支持通配符, 如 'openai*')")
+		c.Flags().StringP("name", "n", "", "指定密钥名称")
+		c.Flags().StringP("tag", "t", "", "按已有标签过滤")
+		c.Flags().Bool("all", false, "作用于全部密钥")
+	}
+
+	tagCmd.AddCommand(tagAddCmd)
+	tagCmd.AddCommand(tagRemoveCmd)
+}
@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/baobao/akm-go/internal/core"
+	"github.com/spf13/cobra"
+)
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "管理 HTTP API 访问令牌",
+	Long: `管理访问 HTTP API 的令牌。存在任意令牌时服务器即要求认证
+（AKM_API_KEY 仍然有效）。令牌加密保存在数据目录，只存哈希，撤销对下一个请求立即生效。
+
+作用域:
+  read   GET /api/...（不含明文值）
+  write  其他 /api/... 请求（添加、删除、导出、验证）以及 show_value=true 读取明文值
+  proxy  /v1/... 提供商代理
+
+示例:
+  akm server token create --name ci --scope proxy
+  akm server token ls
+  akm server token revoke 1a2b3c4d`,
+}
+
+var tokenCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "创建令牌（仅显示一次）",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, _ := cmd.Flags().GetString("name")
+		scopes, _ := cmd.Flags().GetStringSlice("scope")
+
+		storage, err := core.GetStorage()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+
+		raw, token, err := storage.CreateToken(name, scopes)
+		if err != nil {
+			return fmt.Errorf("创建令牌失败: %w", err)
+		}
+
+		printSuccess("已创建令牌 %s (作用域: %s)", token.ID, strings.Join(token.Scopes, ","))
+		printWarning("令牌只显示这一次，请妥善保存:")
+		fmt.Println(raw)
+		return nil
+	},
+}
+
+var tokenListCmd = &cobra.Command{
+	Use:     "ls",
+	Aliases: []string{"list"},
+	Short:   "列出令牌（不显示令牌本身）",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		storage, err := core.GetStorage()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+
+		tokens, err := storage.ListTokens()
+		if err != nil {
+			return fmt.Errorf("读取令牌失败: %w", err)
+		}
+		if len(tokens) == 0 {
+			fmt.Println("没有令牌")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\t名称\t作用域\t创建时间\t最后使用")
+		fmt.Fprintln(w, "──\t────\t──────\t────────\t────────")
+		for _, t := range tokens {
+			lastUsed := "从未"
+			if t.LastUsedAt != nil {
+				lastUsed = t.LastUsedAt.Format("2006-01-02 15:04")
+			}
+			name := t.Name
+			if name == "" {
+				name = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", t.ID, name, strings.Join(t.Scopes, ","),
+				t.CreatedAt.Format("2006-01-02 15:04"), lastUsed)
+		}
+		w.Flush()
+		return nil
+	},
+}
+
+var tokenRevokeCmd = &cobra.Command{
+	Use:   "revoke <ID>",
+	Short: "撤销令牌",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		storage, err := core.GetStorage()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+
+		if err := storage.RevokeToken(args[0]); err != nil {
+			return fmt.Errorf("撤销令牌失败: %w", err)
+		}
+		printSuccess("已撤销令牌 %s", args[0])
+		return nil
+	},
+}
+
+func init() {
+	tokenCreateCmd.Flags().String("name", "", "令牌备注名称")
+	tokenCreateCmd.Flags().StringSlice("scope", nil, "作用域 (read,write,proxy；默认全部)")
+
+	tokenCmd.AddCommand(tokenCreateCmd)
+	tokenCmd.AddCommand(tokenListCmd)
+	tokenCmd.AddCommand(tokenRevokeCmd)
+	serverCmd.AddCommand(tokenCmd)
+}
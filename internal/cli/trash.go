@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/baobao/akm-go/internal/core"
+	"github.com/spf13/cobra"
+)
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore <KEY_NAME>",
+	Short: "从回收站恢复已删除的密钥",
+	Long: `恢复用 delete 删除的密钥（值、标签、历史版本原样恢复）。
+删除后保留期内可恢复，保留期由 AKM_TRASH_RETENTION 设置（默认 30d）。
+
+示例:
+  akm trash list               # 查看可恢复的密钥
+  akm restore OPENAI_API_KEY`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		storage, err := core.GetStorage()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+		key, err := storage.RestoreKey(args[0])
+		if err != nil {
+			return fmt.Errorf("恢复密钥失败: %w", err)
+		}
+		printSuccess("已恢复密钥 '%s'", key.Name)
+		return nil
+	},
+}
+
+var trashCmd = &cobra.Command{
+	Use:   "trash",
+	Short: "管理已删除密钥的回收站",
+	Long: `delete 会把密钥移入回收站，保留期内可用 akm restore 恢复，过期后自动清除。
+保留期由 AKM_TRASH_RETENTION 设置（如 7d、2w，默认 30d；0 表示删除即永久删除）。`,
+}
+
+var trashListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "列出回收站中可恢复的密钥",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		storage, err := core.GetStorage()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+		keys := storage.TrashedKeys()
+		if len(keys) == 0 {
+			fmt.Println("回收站为空")
+			return nil
+		}
+
+		retention := core.TrashRetention()
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "名称\t提供商\t删除时间\t可恢复至")
+		fmt.Fprintln(w, "────\t──────\t────────\t────────")
+		for _, key := range keys {
+			deleted := key.DeletedAt.Time
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", key.Name, key.Provider,
+				deleted.Local().Format("2006-01-02 15:04"), deleted.Add(retention).Local().Format("2006-01-02 15:04"))
+		}
+		w.Flush()
+		fmt.Printf("\n共 %d 个，使用 akm restore <KEY_NAME> 恢复\n", len(keys))
+		return nil
+	},
+}
+
+var trashEmptyCmd = &cobra.Command{
+	Use:   "empty",
+	Short: "清空回收站（永久删除）",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		force, _ := cmd.Flags().GetBool("force")
+
+		storage, err := core.GetStorage()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+		count := len(storage.TrashedKeys())
+		if count == 0 {
+			fmt.Println("回收站为空")
+			return nil
+		}
+		if !force && !confirm(fmt.Sprintf("确认永久删除回收站中的 %d 个密钥? 此操作不可恢复!", count)) {
+			fmt.Println("已取消")
+			return nil
+		}
+
+		names, err := storage.EmptyTrash()
+		if err != nil {
+			return fmt.Errorf("清空回收站失败: %w", err)
+		}
+		printSuccess("已永久删除 %d 个密钥", len(names))
+		return nil
+	},
+}
+
+// deletePrompt is the confirmation shown before deleting keys, which only
+// warns of permanence when the trash is disabled.
+func deletePrompt(what string) string {
+	if core.TrashRetention() == 0 {
+		return fmt.Sprintf("确认删除%s? 此操作不可恢复!", what)
+	}
+	return fmt.Sprintf("确认删除%s? (%s 内可用 akm restore 恢复)", what, formatRetention(core.TrashRetention()))
+}
+
+// printDeleted reports a deleted key and, with the trash enabled, how to get it back.
+func printDeleted(name string) {
+	if core.TrashRetention() == 0 {
+		printSuccess("已删除密钥 '%s'", name)
+		return
+	}
+	printSuccess("已删除密钥 '%s'（可用 akm restore %s 恢复）", name, name)
+}
+
+// formatRetention renders whole days as "30 天" and anything else as a duration.
+func formatRetention(d time.Duration) string {
+	if d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%d 天", d/(24*time.Hour))
+	}
+	return d.String()
+}
+
+func init() {
+	trashEmptyCmd.Flags().BoolP("force", "f", false, "跳过确认")
+
+	trashCmd.AddCommand(trashListCmd)
+	trashCmd.AddCommand(trashEmptyCmd)
+}
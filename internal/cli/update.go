@@ -0,0 +1,272 @@
+package cli
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/mod/semver"
+)
+
+const (
+	// releaseRepo is the GitHub repository that publishes akm releases.
+	releaseRepo = "bao243092078-crypto/akm-go"
+	// checksumsAsset is the release asset listing sha256 sums (sha256sum format).
+	checksumsAsset = "checksums.txt"
+)
+
+// managedInstallPrefixes are install locations owned by package managers.
+// Overwriting binaries there would desync the package manager's state. The
+// first matching prefix wins, so list more specific prefixes first.
+var managedInstallPrefixes = []struct {
+	prefix string
+	hint   string
+}{
+	{"/opt/homebrew/", "brew upgrade akm"},
+	{"/usr/local/Cellar/", "brew upgrade akm"},
+	{"/home/linuxbrew/", "brew upgrade akm"},
+	{"/nix/store/", "nix profile upgrade"},
+	{"/usr/bin/", "系统包管理器 (apt/dnf/pacman)"},
+	{"/snap/", "snap refresh akm"},
+}
+
+type githubRelease struct {
+	TagName string `json:"tag_name"`
+	Assets  []struct {
+		Name               string `json:"name"`
+		BrowserDownloadURL string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+var selfUpdateCmd = &cobra.Command{
+	Use:   "self-update",
+	Short: "更新 akm 到最新版本",
+	Long: `检查 GitHub 最新 release，下载当前平台的二进制，校验 sha256 后原子替换当前可执行文件。
+
+release 需包含 akm-<os>-<arch> 二进制以及 checksums.txt。
+通过 Homebrew、Nix 等包管理器安装时拒绝执行，请使用对应的升级命令。
+当前版本不低于最新 release 时不会安装（避免降级）；开发版本 (dev) 无法比较版本，
+需要 --force 才会安装。--check 发现新版本时以退出码 1 结束。
+
+示例:
+  akm self-update               # 更新到最新版本
+  akm self-update --check       # 只检查，不下载`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		checkOnly, _ := cmd.Flags().GetBool("check")
+		force, _ := cmd.Flags().GetBool("force")
+
+		exe, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("无法定位当前可执行文件: %w", err)
+		}
+		if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+			exe = resolved
+		}
+
+		for _, m := range managedInstallPrefixes {
+			if strings.HasPrefix(exe, m.prefix) {
+				return fmt.Errorf("akm 由包管理器安装 (%s)，请使用: %s", exe, m.hint)
+			}
+		}
+
+		client := &http.Client{Timeout: 60 * time.Second}
+		release, err := fetchLatestRelease(client)
+		if err != nil {
+			return fmt.Errorf("检查更新失败: %w", err)
+		}
+
+		latest := canonicalVersion(release.TagName)
+		if !semver.IsValid(latest) {
+			return fmt.Errorf("最新 release 的版本号无法识别: %s", release.TagName)
+		}
+		current := canonicalVersion(Version)
+		fmt.Printf("当前版本: %s\n最新版本: %s\n", Version, release.TagName)
+
+		switch {
+		case !semver.IsValid(current):
+			// dev or otherwise unversioned build: nothing to compare against
+			if checkOnly {
+				printWarning("当前为开发版本，无法判断是否需要更新")
+				return nil
+			}
+			if !force {
+				return fmt.Errorf("当前为开发版本 (%s)，无法与 %s 比较；确认要安装 release 版本请使用 --force", Version, release.TagName)
+			}
+		case semver.Compare(current, latest) > 0 && !force:
+			printSuccess("当前版本比最新 release 更新，不会降级（如需降级请使用 --force）")
+			return nil
+		case semver.Compare(current, latest) == 0 && !force:
+			printSuccess("已是最新版本")
+			return nil
+		}
+		if checkOnly {
+			fmt.Printf("有可用更新: %s → %s，运行 akm self-update 安装\n", Version, release.TagName)
+			cmd.SilenceErrors = true
+			cmd.SilenceUsage = true
+			return &ExitError{Code: 1}
+		}
+
+		assetName := fmt.Sprintf("akm-%s-%s", runtime.GOOS, runtime.GOARCH)
+		if runtime.GOOS == "windows" {
+			assetName += ".exe"
+		}
+		var binURL, sumsURL string
+		for _, a := range release.Assets {
+			switch a.Name {
+			case assetName:
+				binURL = a.BrowserDownloadURL
+			case checksumsAsset:
+				sumsURL = a.BrowserDownloadURL
+			}
+		}
+		if binURL == "" {
+			return fmt.Errorf("release %s 没有适用于 %s/%s 的二进制 (%s)", release.TagName, runtime.GOOS, runtime.GOARCH, assetName)
+		}
+		if sumsURL == "" {
+			return fmt.Errorf("release %s 缺少 %s，拒绝安装未校验的二进制", release.TagName, checksumsAsset)
+		}
+
+		expected, err := fetchChecksum(client, sumsURL, assetName)
+		if err != nil {
+			return fmt.Errorf("获取校验和失败: %w", err)
+		}
+
+		fmt.Printf("下载 %s...\n", assetName)
+		if err := replaceExecutable(client, binURL, expected, exe); err != nil {
+			return fmt.Errorf("更新失败: %w", err)
+		}
+
+		printSuccess("已更新到 %s", release.TagName)
+		return nil
+	},
+}
+
+// canonicalVersion returns v with the "v" prefix semver expects.
+func canonicalVersion(v string) string {
+	if strings.HasPrefix(v, "v") {
+		return v
+	}
+	return "v" + v
+}
+
+// fetchLatestRelease queries the GitHub API for the latest release.
+func fetchLatestRelease(client *http.Client) (*githubRelease, error) {
+	req, err := http.NewRequest("GET", "https://api.github.com/repos/"+releaseRepo+"/releases/latest", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GitHub API returned HTTP %d", resp.StatusCode)
+	}
+
+	var release githubRelease
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, fmt.Errorf("invalid release JSON: %w", err)
+	}
+	return &release, nil
+}
+
+// fetchChecksum downloads a sha256sum-format file and returns the sum for assetName.
+func fetchChecksum(client *http.Client, url, assetName string) (string, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == assetName {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("no checksum for %s", assetName)
+}
+
+// replaceExecutable downloads the new binary next to exe, verifies its sha256,
+// and atomically swaps it in. The previous binary is restored if the swap fails.
+func replaceExecutable(client *http.Client, url, expectedSum, exe string) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download returned HTTP %d", resp.StatusCode)
+	}
+
+	info, err := os.Stat(exe)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(exe)
+	tmp, err := os.CreateTemp(dir, ".akm-update-*")
+	if err != nil {
+		return fmt.Errorf("cannot write to %s (try running with sufficient permissions): %w", dir, err)
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName) // no-op after successful rename
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, h), resp.Body); err != nil {
+		tmp.Close()
+		return fmt.Errorf("download failed: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if got := hex.EncodeToString(h.Sum(nil)); got != expectedSum {
+		return fmt.Errorf("checksum mismatch: expected %s, got %s", expectedSum, got)
+	}
+
+	if err := os.Chmod(tmpName, info.Mode().Perm()); err != nil {
+		return err
+	}
+
+	backup := exe + ".old"
+	if err := os.Rename(exe, backup); err != nil {
+		return fmt.Errorf("failed to back up current binary: %w", err)
+	}
+	if err := os.Rename(tmpName, exe); err != nil {
+		if rbErr := os.Rename(backup, exe); rbErr != nil {
+			return fmt.Errorf("failed to install new binary (%v) and rollback failed: %w; previous binary is at %s", err, rbErr, backup)
+		}
+		return fmt.Errorf("failed to install new binary, rolled back: %w", err)
+	}
+	os.Remove(backup)
+	return nil
+}
+
+func init() {
+	selfUpdateCmd.Flags().Bool("check", false, "只检查是否有新版本")
+	selfUpdateCmd.Flags().Bool("force", false, "即使版本相同也重新安装")
+}
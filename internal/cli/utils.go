@@ -5,17 +5,26 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/baobao/akm-go/internal/core"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
 var verifyCmd = &cobra.Command{
 	Use:   "verify-keys",
 	Short: "验证密钥有效性",
-	Long:  "通过调用各提供商 API 验证密钥是否有效",
+	Long: `通过调用各提供商 API 验证密钥是否有效。
+
+状态: valid 有效; valid_limited 受限可用（认证通过但无权访问验证端点，
+如无模型列表权限的受限密钥）; invalid 无效; error 请求出错; unsupported 不支持。
+
+密钥由有效变为无效时可触发通知（每次状态变化只通知一次）:
+  AKM_VERIFY_WEBHOOK=https://...    # POST {name, provider, status, message}
+  AKM_VERIFY_HOOK_CMD='notify.sh'   # 通过 stdin 传入同样的 JSON`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		provider, _ := cmd.Flags().GetString("provider")
 		name, _ := cmd.Flags().GetString("name")
@@ -30,24 +39,41 @@ var verifyCmd = &cobra.Command{
 			keys = nil
 			if k := storage.GetKey(name); k != nil {
 				keys = append(keys, k)
+				name = k.Name
 			} else {
 				return fmt.Errorf("密钥 '%s' 不存在", name)
 			}
 		}
 		if len(keys) == 0 {
+			if jsonOutput {
+				return printJSON(map[string]interface{}{"results": []*core.VerifyResult{}, "count": 0})
+			}
 			fmt.Println("没有密钥需要验证")
 			return nil
 		}
 
-		fmt.Printf("验证 %d 个密钥...\n\n", len(keys))
+		fmt.Fprintf(statusWriter(), "验证 %d 个密钥...\n\n", len(keys))
 
 		results := core.VerifyAll(storage, provider, name)
+		if jsonOutput {
+			summary := make(map[string]int)
+			for _, r := range results {
+				summary[r.Status]++
+			}
+			return printJSON(map[string]interface{}{
+				"results": results,
+				"count":   len(results),
+				"summary": summary,
+			})
+		}
 
 		for _, r := range results {
 			var icon string
 			switch r.Status {
 			case "valid":
 				icon = "\033[32m✓\033[0m" // green
+			case core.StatusValidLimited:
+				icon = "\033[36m~\033[0m" // cyan
 			case "invalid":
 				icon = "\033[31m✗\033[0m" // red
 			case "error":
@@ -59,11 +85,13 @@ var verifyCmd = &cobra.Command{
 		}
 
 		// Summary
-		var valid, invalid, errCount, unsupported int
+		var valid, limited, invalid, errCount, unsupported int
 		for _, r := range results {
 			switch r.Status {
 			case "valid":
 				valid++
+			case core.StatusValidLimited:
+				limited++
 			case "invalid":
 				invalid++
 			case "error":
@@ -72,22 +100,30 @@ var verifyCmd = &cobra.Command{
 				unsupported++
 			}
 		}
-		fmt.Printf("\n结果: %d 有效, %d 无效, %d 错误, %d 不支持\n", valid, invalid, errCount, unsupported)
+		fmt.Printf("\n结果: %d 有效, %d 受限可用, %d 无效, %d 错误, %d 不支持\n", valid, limited, invalid, errCount, unsupported)
 
 		return nil
 	},
 }
 
 func init() {
-	verifyCmd.Flags().StringP("provider", "p", "", "按提供商过滤")
+	verifyCmd.Flags().StringP("provider", "p", "", "按提供商过滤 (支持通配符, 如 'openai*')")
 	verifyCmd.Flags().StringP("name", "n", "", "指定密钥名称")
 }
 
 var healthCmd = &cobra.Command{
 	Use:   "health",
 	Short: "系统健康检查",
-	Long:  "检查加密系统、存储、审计日志等状态",
+	Long: `检查加密系统、存储、审计日志等状态。
+
+示例:
+  akm health                  # 本地检查
+  akm health --network        # 额外探测各平台 API 是否可达（不发送密钥）
+  akm health --deep           # 只探测有启用密钥的 provider，报告可达性和延迟`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		network, _ := cmd.Flags().GetBool("network")
+		deep, _ := cmd.Flags().GetBool("deep")
+
 		fmt.Println("🔍 API Key Manager 健康检查")
 
 		// Check crypto
@@ -106,7 +142,7 @@ var healthCmd = &cobra.Command{
 				if err != nil || decrypted != testMsg {
 					fmt.Printf("❌ 解密失败\n")
 				} else {
-					fmt.Println("✅ 正常")
+					fmt.Printf("✅ 正常 (master key: %s)\n", crypto.MasterKeySource())
 				}
 			}
 		}
@@ -124,14 +160,15 @@ var healthCmd = &cobra.Command{
 		// Check audit logs
 		fmt.Print("审计日志: ")
 		if storage != nil {
-			total, verified, unsigned, tampered, err := storage.VerifyAuditLogs()
+			total, verified, unsigned, tampered, brokenChain, err := storage.VerifyAuditLogs()
 			if err != nil {
 				fmt.Printf("❌ %v\n", err)
 			} else if total == 0 {
 				fmt.Println("✅ 空（无日志）")
 			} else {
-				if tampered > 0 {
-					fmt.Printf("⚠️  %d 条，%d 已验证，%d 未签名，%d 被篡改\n", total, verified, unsigned, tampered)
+				if tampered > 0 || brokenChain > 0 {
+					fmt.Printf("⚠️  %d 条，%d 已验证，%d 未签名，%d 被篡改，%d 处链断裂（条目被删除或重排）\n",
+						total, verified, unsigned, tampered, brokenChain)
 				} else {
 					fmt.Printf("✅ %d 条，%d 已验证\n", total, verified)
 				}
@@ -140,24 +177,49 @@ var healthCmd = &cobra.Command{
 
 		// Check data directory
 		fmt.Print("数据目录: ")
-		homeDir, _ := os.UserHomeDir()
-		dataDir := filepath.Join(homeDir, ".apikey-manager", "data")
+		dataDir, _ := core.DataDir()
 		if info, err := os.Stat(dataDir); err != nil {
 			fmt.Printf("❌ %v\n", err)
 		} else {
-			fmt.Printf("✅ %s (mode: %s)\n", dataDir, info.Mode())
+			fmt.Printf("✅ %s (mode: %s, profile: %s)\n", dataDir, info.Mode(), core.ActiveProfile())
+		}
+
+		if network {
+			fmt.Println("网络连通性:")
+			printReachability(core.ProbePlatforms(5 * time.Second))
+		}
+		if deep && storage != nil {
+			fmt.Println("Provider 连通性（有启用密钥）:")
+			results := core.ProbeProviders(storage, 5*time.Second)
+			if len(results) == 0 {
+				fmt.Println("  （没有启用的密钥）")
+			}
+			printReachability(results)
 		}
 
 		return nil
 	},
 }
 
+func init() {
+	healthCmd.Flags().Bool("network", false, "探测所有已知平台 API 的网络连通性")
+	healthCmd.Flags().Bool("deep", false, "探测有启用密钥的 provider 是否可达及延迟（需要网络）")
+}
+
 var backupCmd = &cobra.Command{
 	Use:   "backup",
 	Short: "备份密钥和审计日志",
-	Long:  "创建密钥和审计日志的备份",
+	Long: `创建密钥和审计日志的备份。
+
+keys.json 总是完整备份；--since 只保留指定时间范围内的审计日志
+（每条日志单独签名，过滤后的日志仍可通过校验）。
+
+示例:
+  akm backup                    # 完整备份
+  akm backup --since 720h       # 只备份最近 30 天的审计日志`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		outputDir, _ := cmd.Flags().GetString("output")
+		since, _ := cmd.Flags().GetDuration("since")
 
 		storage, err := core.GetStorage()
 		if err != nil {
@@ -165,12 +227,16 @@ var backupCmd = &cobra.Command{
 		}
 
 		if outputDir == "" {
-			homeDir, _ := os.UserHomeDir()
 			timestamp := time.Now().Format("20060102-150405")
-			outputDir = filepath.Join(homeDir, ".apikey-manager", "backups", timestamp)
+			outputDir = filepath.Join(storage.AutoBackupDir(), timestamp)
+		}
+
+		var cutoff time.Time
+		if since > 0 {
+			cutoff = time.Now().Add(-since)
 		}
 
-		if err := storage.Backup(outputDir); err != nil {
+		if err := storage.Backup(outputDir, cutoff); err != nil {
 			return fmt.Errorf("备份失败: %w", err)
 		}
 
@@ -182,7 +248,7 @@ var backupCmd = &cobra.Command{
 var masterKeyCmd = &cobra.Command{
 	Use:   "master-key",
 	Short: "管理 master key",
-	Long:  "导出或导入 master key（用于备份恢复或迁移机器）",
+	Long:  "导出或导入 master key（用于备份恢复或迁移机器），或改用口令保护",
 }
 
 var masterKeyExportCmd = &cobra.Command{
@@ -215,11 +281,13 @@ var masterKeyImportCmd = &cobra.Command{
 示例:
   echo 'KEY' | akm master-key import
   akm master-key import < key.txt
-  akm master-key import              # 交互式输入`,
+  akm master-key import              # 交互式输入
+  akm master-key import --previous   # 导入旧 key，仅用于解密未迁移的记录`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		force, _ := cmd.Flags().GetBool("force")
+		previous, _ := cmd.Flags().GetBool("previous")
 
-		if !force {
+		if !force && !previous {
 			fmt.Print("⚠️  此操作将覆盖当前 master key！确认继续? [y/N]: ")
 			var response string
 			fmt.Scanln(&response)
@@ -245,19 +313,275 @@ var masterKeyImportCmd = &cobra.Command{
 			return fmt.Errorf("加密系统初始化失败: %w", err)
 		}
 
+		if previous {
+			if err := crypto.ImportPreviousMasterKey(keyInput); err != nil {
+				return fmt.Errorf("导入失败: %w", err)
+			}
+			printSuccess("旧 master key 已导入到 %s（仅用于解密）", masterKeyStore(crypto))
+			return nil
+		}
+
 		if err := crypto.ImportMasterKey(keyInput); err != nil {
 			return fmt.Errorf("导入失败: %w", err)
 		}
 
-		printSuccess("master key 已导入到 Keychain")
+		printSuccess("master key 已导入到 %s", masterKeyStore(crypto))
+		return nil
+	},
+}
+
+var masterKeyRekeyCmd = &cobra.Command{
+	Use:   "rekey <KEY_NAME>",
+	Short: "用当前 master key 重新加密单个密钥",
+	Long: `解密指定密钥（当前或旧 master key 均可），并用当前 master key 重新加密保存。
+配合 'akm master-key import --previous' 可逐条迁移，无需一次性全量重新加密。
+
+示例:
+  akm master-key rekey OPENAI_API_KEY`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		storage, err := core.GetStorage()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+
+		source, err := storage.ReencryptKey(args[0])
+		if err != nil {
+			return fmt.Errorf("重新加密失败: %w", err)
+		}
+
+		printSuccess("已重新加密 '%s' (解密来源: %s master key)", args[0], source)
 		return nil
 	},
 }
 
+var masterKeyRotateCmd = &cobra.Command{
+	Use:   "rotate",
+	Short: "生成新 master key 并重新加密全部密钥",
+	Long: `生成新的 master key，用它重新加密所有密钥（含历史版本）和 keys.json。
+旧 master key 保留为 previous，未迁移的数据（如审计日志签名）仍可读取。
+任一密钥失败时不做任何修改。团队模式下新 key 会为 members.json 中的现有成员重新包装，
+已删除的成员拿不到新 key。
+
+--dry-run 只在内存中用临时 key 解密并重新加密每条记录、校验往返结果，
+不写 keys.json、不修改 Keychain，并列出失败的密钥。
+
+示例:
+  akm master-key rotate --dry-run   # 预演
+  akm master-key rotate             # 执行轮换
+  akm audit compact                 # 轮换后用新 key 重新签名审计日志
+  akm master-key retire-previous    # 过渡期结束后删除旧 key`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		force, _ := cmd.Flags().GetBool("force")
+
+		storage, err := core.GetStorage()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+
+		if !dryRun && !force && !confirm("确认轮换 master key 并重新加密全部密钥?") {
+			fmt.Println("已取消")
+			return nil
+		}
+
+		report, err := storage.RotateMasterKey(dryRun)
+		if report != nil {
+			fmt.Printf("密钥总数: %d\n", report.Total)
+			fmt.Printf("可轮换:   %d\n", report.Rotated)
+			if len(report.Failures) > 0 {
+				names := make([]string, 0, len(report.Failures))
+				for name := range report.Failures {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				fmt.Printf("失败:     %d\n", len(names))
+				for _, name := range names {
+					fmt.Printf("  ✗ %s: %v\n", name, report.Failures[name])
+				}
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("轮换失败: %w", err)
+		}
+
+		if dryRun {
+			if len(report.Failures) > 0 {
+				printWarning("预演发现 %d 个失败，请先修复后再执行轮换", len(report.Failures))
+			} else {
+				printSuccess("预演通过，未做任何修改")
+			}
+			return nil
+		}
+
+		printSuccess("master key 已轮换，旧 key 已保存为 previous")
+		fmt.Println("建议: 运行 'akm master-key export' 备份新 key，并运行 'akm audit compact' 重新签名审计日志")
+		return nil
+	},
+}
+
+var masterKeyRetireCmd = &cobra.Command{
+	Use:   "retire-previous",
+	Short: "删除旧 master key，结束轮换过渡期",
+	Long: `轮换后旧 master key 作为 previous 保留在 Keychain 中，未迁移的数据仍可解密。
+确认所有密钥（含历史版本）都已由当前 key 加密后，用此命令删除旧 key。
+仍依赖旧 key 的密钥会被列出，此时不做任何修改（先运行 'akm master-key rekey'）。
+
+tokens.json 仍由旧 key 加密时同样拒绝（轮换时会自动重新加密）。
+
+旧 key 签名的审计记录在删除后将无法验证，因此存在这类记录时也会拒绝，
+请先运行 'akm audit compact' 重新签名。
+
+示例:
+  akm audit compact
+  akm master-key retire-previous`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		force, _ := cmd.Flags().GetBool("force")
+
+		storage, err := core.GetStorage()
+		if err != nil {
+			return fmt.Errorf("failed to initialize storage: %w", err)
+		}
+
+		if !force && !confirm("确认删除旧 master key? 删除后用它加密的数据将无法恢复") {
+			fmt.Println("已取消")
+			return nil
+		}
+
+		if err := storage.RetirePreviousMasterKey(); err != nil {
+			return fmt.Errorf("删除旧 master key 失败: %w", err)
+		}
+
+		printSuccess("旧 master key 已删除")
+		return nil
+	},
+}
+
+var masterKeySetPassphraseCmd = &cobra.Command{
+	Use:   "set-passphrase",
+	Short: "改用口令保护 master key（无 Keychain 的环境）",
+	Long: `把 master key（及轮换过渡期的旧 key）用口令加密后保存到
+~/.apikey-manager/data/master.enc，适用于没有 Keychain 的服务器和容器。
+加密密钥由 Argon2id 从口令派生。
+
+master.enc 存在时优先于 Keychain；之后每次启动从 AKM_PASSPHRASE 读取口令，
+未设置时在终端提示输入。已处于口令模式时再次运行即修改口令。
+默认保留 Keychain 中的副本，确认 master.enc 可用后可加 --remove-keychain 删除。
+
+示例:
+  akm master-key set-passphrase                   # 迁移现有 Keychain 中的 key
+  akm master-key set-passphrase --remove-keychain # 迁移并删除 Keychain 副本
+  akm master-key set-passphrase --new             # 新机器: 直接生成口令保护的 key`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		newKey, _ := cmd.Flags().GetBool("new")
+		removeKeychain, _ := cmd.Flags().GetBool("remove-keychain")
+
+		if newKey {
+			passphrase, err := readNewPassphrase()
+			if err != nil {
+				return err
+			}
+			if err := core.CreatePassphraseMasterKey(passphrase); err != nil {
+				return fmt.Errorf("创建 master key 失败: %w", err)
+			}
+			path, _ := core.MasterKeyFile()
+			printSuccess("已生成新的 master key 并以口令保护: %s", path)
+			printWarning("请运行 'akm master-key export' 备份，忘记口令将无法解密所有密钥")
+			return nil
+		}
+
+		crypto, err := core.GetCrypto()
+		if err != nil {
+			return fmt.Errorf("加密系统初始化失败: %w", err)
+		}
+		wasPassphrase := crypto.PassphraseMode()
+
+		passphrase, err := readNewPassphrase()
+		if err != nil {
+			return err
+		}
+		if err := crypto.SetPassphrase(passphrase); err != nil {
+			return fmt.Errorf("设置口令失败: %w", err)
+		}
+		path, _ := core.MasterKeyFile()
+		if wasPassphrase {
+			printSuccess("口令已修改: %s", path)
+		} else {
+			printSuccess("master key 已以口令保护保存到 %s", path)
+		}
+
+		if removeKeychain {
+			if err := crypto.RemoveKeychainMasterKey(); err != nil {
+				return fmt.Errorf("删除 Keychain 副本失败: %w", err)
+			}
+			printSuccess("已删除 Keychain 中的 master key")
+			printWarning("忘记口令将无法解密所有密钥，请确认已用 'akm master-key export' 备份")
+		} else if !wasPassphrase {
+			fmt.Println("Keychain 中的副本已保留；确认无误后可运行 'akm master-key set-passphrase --remove-keychain' 删除")
+		}
+		return nil
+	},
+}
+
+// masterKeyStore names where the master key is persisted, for messages.
+func masterKeyStore(crypto *core.KeyEncryption) string {
+	if crypto.PassphraseMode() {
+		return "master.enc"
+	}
+	return "Keychain"
+}
+
+// promptMasterPassphrase asks for the master.enc passphrase on the terminal.
+// Without one (pipes, akm mcp serve) it fails instead of consuming stdin.
+func promptMasterPassphrase() (string, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", fmt.Errorf("master key is passphrase-protected: set AKM_PASSPHRASE")
+	}
+	fmt.Fprint(os.Stderr, "请输入 master key 口令: ")
+	passphrase, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("读取口令失败: %w", err)
+	}
+	return string(passphrase), nil
+}
+
+// printReachability prints one line per probed platform or provider.
+func printReachability(results []core.ReachabilityResult) {
+	for _, r := range results {
+		switch {
+		case r.Skipped:
+			fmt.Printf("  ⏭️  %s: 跳过（平台注册表中没有其 API 地址）\n", r.Platform)
+		case r.Reachable:
+			fmt.Printf("  ✅ %s: 可达 (HTTP %d, %s)\n", r.Platform, r.StatusCode, r.Latency.Round(time.Millisecond))
+		default:
+			hint := ""
+			if r.RequiresVPN {
+				hint = "，该平台需要 VPN"
+			}
+			fmt.Printf("  ❌ %s: 不可达 (%s%s)\n", r.Platform, r.Error, hint)
+		}
+	}
+}
+
 func init() {
+	core.PassphrasePrompt = promptMasterPassphrase
+
 	backupCmd.Flags().StringP("output", "o", "", "备份输出目录")
+	backupCmd.Flags().Duration("since", 0, "只备份该时长内的审计日志（如 720h），默认全部")
 
 	masterKeyImportCmd.Flags().BoolP("force", "f", false, "跳过确认")
+	masterKeyImportCmd.Flags().Bool("previous", false, "作为旧 master key 导入（仅用于解密，不替换当前 key）")
 	masterKeyCmd.AddCommand(masterKeyExportCmd)
 	masterKeyCmd.AddCommand(masterKeyImportCmd)
+	masterKeyRotateCmd.Flags().Bool("dry-run", false, "只在内存中预演，不写入任何内容")
+	masterKeyRotateCmd.Flags().BoolP("force", "f", false, "跳过确认")
+	masterKeyCmd.AddCommand(masterKeyRekeyCmd)
+	masterKeyCmd.AddCommand(masterKeyRotateCmd)
+	masterKeyRetireCmd.Flags().BoolP("force", "f", false, "跳过确认")
+	masterKeyCmd.AddCommand(masterKeyRetireCmd)
+	masterKeySetPassphraseCmd.Flags().Bool("new", false, "不读取 Keychain，直接生成新的口令保护 master key（仅限尚无密钥时）")
+	masterKeySetPassphraseCmd.Flags().Bool("remove-keychain", false, "迁移后删除 Keychain 中的 master key 副本")
+	masterKeyCmd.AddCommand(masterKeySetPassphraseCmd)
 }
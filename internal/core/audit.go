@@ -0,0 +1,337 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/baobao/akm-go/internal/models"
+)
+
+// AuditCompactReport summarizes an audit log compaction.
+type AuditCompactReport struct {
+	Total      int    `json:"total"`
+	Current    int    `json:"current"`    // already signed with the current master key
+	Resigned   int    `json:"resigned"`   // signed with the previous key or relinked to a re-signed entry, re-signed now
+	Unsigned   int    `json:"unsigned"`   // kept verbatim, flagged
+	Unverified int    `json:"unverified"` // bad signature or unparseable, kept verbatim, flagged
+	Flagged    []int  `json:"flagged"`    // 1-based line numbers of flagged entries
+	BackupFile string `json:"backup_file,omitempty"`
+}
+
+// CompactAuditLog rewrites the audit log with every verifiable entry signed
+// under the current master key. Entries that cannot be verified (unsigned,
+// bad signature, unparseable) are never re-signed or dropped: they are copied
+// verbatim and listed in the report. With dryRun the file is left untouched.
+// The original file is kept as audit.jsonl.<timestamp>.bak.
+func (s *KeyStorage) CompactAuditLog(dryRun bool) (*AuditCompactReport, error) {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+
+	report := &AuditCompactReport{}
+	f, err := os.Open(s.auditFile)
+	if os.IsNotExist(err) {
+		return report, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	// Re-signing changes an entry's signature, so the next entry's prev_hash
+	// is relinked to it. Links that were already broken are left broken.
+	var origPrev, newPrev *string
+
+	var out bytes.Buffer
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		report.Total++
+
+		var log models.KeyUsageLog
+		if err := json.Unmarshal(line, &log); err != nil {
+			report.Unverified++
+			report.Flagged = append(report.Flagged, report.Total)
+			out.Write(line)
+			out.WriteByte('\n')
+			origPrev, newPrev = nil, nil
+			continue
+		}
+
+		if log.Signature == nil || *log.Signature == "" {
+			report.Unsigned++
+			report.Flagged = append(report.Flagged, report.Total)
+			out.Write(line)
+			out.WriteByte('\n')
+			origPrev, newPrev = nil, nil
+			continue
+		}
+
+		original := log.Signature
+		source, err := s.crypto.VerifySignatureWithSource(auditSigningPayload(&log), *log.Signature)
+		if err != nil {
+			return nil, err
+		}
+		if source != KeySourcePrimary && source != KeySourcePrevious {
+			report.Unverified++
+			report.Flagged = append(report.Flagged, report.Total)
+			out.Write(line)
+			out.WriteByte('\n')
+			origPrev, newPrev = original, original
+			continue
+		}
+
+		relink := log.PrevHash != nil && origPrev != nil && *log.PrevHash == *origPrev && *newPrev != *origPrev
+		if relink {
+			log.PrevHash = newPrev
+		}
+		if source == KeySourcePrevious || relink {
+			signature, err := s.crypto.SignMessage(auditSigningPayload(&log))
+			if err != nil {
+				return nil, err
+			}
+			log.Signature = &signature
+			report.Resigned++
+		} else {
+			report.Current++
+		}
+		origPrev, newPrev = original, log.Signature
+
+		// Re-marshal verified entries into the canonical field layout
+		logBytes, err := json.Marshal(&log)
+		if err != nil {
+			return nil, err
+		}
+		out.Write(logBytes)
+		out.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	f.Close()
+
+	if dryRun {
+		return report, nil
+	}
+
+	report.BackupFile = fmt.Sprintf("%s.%s.bak", s.auditFile, time.Now().Format("20060102-150405"))
+	original, err := os.ReadFile(s.auditFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to back up audit log: %w", err)
+	}
+	if err := os.WriteFile(report.BackupFile, original, s.filePerm); err != nil {
+		return nil, fmt.Errorf("failed to back up audit log: %w", err)
+	}
+
+	tempFile := s.auditFile + ".tmp"
+	if err := os.WriteFile(tempFile, out.Bytes(), s.filePerm); err != nil {
+		return nil, fmt.Errorf("failed to write audit log: %w", err)
+	}
+	if err := os.Rename(tempFile, s.auditFile); err != nil {
+		os.Remove(tempFile)
+		return nil, fmt.Errorf("failed to replace audit log: %w", err)
+	}
+	return report, nil
+}
+
+// Per-entry audit verification outcomes.
+const (
+	AuditEntryValid    = "valid"    // signature verifies
+	AuditEntryUnsigned = "unsigned" // no signature (legacy entry)
+	AuditEntryTampered = "tampered" // bad signature or unparseable line
+)
+
+// AuditEntryCheck is the verification result for one audit log line.
+type AuditEntryCheck struct {
+	Line        int                 `json:"line"`            // 1-based, blank lines not counted
+	Entry       *models.KeyUsageLog `json:"entry,omitempty"` // nil when the line does not parse
+	Status      string              `json:"status"`
+	BrokenChain bool                `json:"broken_chain,omitempty"` // prev_hash does not match the line before
+}
+
+// readAuditLines returns the non-blank lines of the audit file.
+func (s *KeyStorage) readAuditLines() ([]string, error) {
+	data, err := os.ReadFile(s.auditFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+// VerifyAuditEntries checks every audit entry's signature and chain link, in
+// file order. See VerifyAuditLogs for the chain rules.
+func (s *KeyStorage) VerifyAuditEntries() ([]AuditEntryCheck, error) {
+	lines, err := s.readAuditLines()
+	if err != nil {
+		return nil, err
+	}
+
+	checks := make([]AuditEntryCheck, 0, len(lines))
+	var prevSignature *string
+	for i, line := range lines {
+		check := AuditEntryCheck{Line: i + 1}
+
+		var log models.KeyUsageLog
+		if err := json.Unmarshal([]byte(line), &log); err != nil {
+			check.Status = AuditEntryTampered
+			checks = append(checks, check)
+			prevSignature = nil
+			continue
+		}
+		check.Entry = &log
+
+		if log.PrevHash != nil && i > 0 && (prevSignature == nil || *prevSignature != *log.PrevHash) {
+			check.BrokenChain = true
+		}
+		prevSignature = log.Signature
+
+		switch {
+		case log.Signature == nil || *log.Signature == "":
+			check.Status = AuditEntryUnsigned
+		default:
+			if valid, _ := s.crypto.VerifySignature(auditSigningPayload(&log), *log.Signature); valid {
+				check.Status = AuditEntryValid
+			} else {
+				check.Status = AuditEntryTampered
+			}
+		}
+		checks = append(checks, check)
+	}
+	return checks, nil
+}
+
+// auditEntriesSignedWithPrevious counts audit entries whose signature only
+// verifies under the previous master key.
+func (s *KeyStorage) auditEntriesSignedWithPrevious() (int, error) {
+	lines, err := s.readAuditLines()
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, line := range lines {
+		var log models.KeyUsageLog
+		if json.Unmarshal([]byte(line), &log) != nil || log.Signature == nil || *log.Signature == "" {
+			continue
+		}
+		source, err := s.crypto.VerifySignatureWithSource(auditSigningPayload(&log), *log.Signature)
+		if err != nil {
+			return 0, err
+		}
+		if source == KeySourcePrevious {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// ReadAuditLogs returns the last limit parseable audit entries, oldest first;
+// limit <= 0 returns all of them. Lines that do not parse are skipped (they
+// show up in VerifyAuditEntries).
+func (s *KeyStorage) ReadAuditLogs(limit int) ([]*models.KeyUsageLog, error) {
+	lines, err := s.readAuditLines()
+	if err != nil {
+		return nil, err
+	}
+
+	var logs []*models.KeyUsageLog
+	for i := len(lines) - 1; i >= 0 && (limit <= 0 || len(logs) < limit); i-- {
+		var log models.KeyUsageLog
+		if err := json.Unmarshal([]byte(lines[i]), &log); err != nil {
+			continue
+		}
+		logs = append(logs, &log)
+	}
+	slices.Reverse(logs)
+	return logs, nil
+}
+
+// KeyUsage aggregates the audit trail of one key's value being accessed.
+type KeyUsage struct {
+	Reads     int       `json:"reads"`
+	Injects   int       `json:"injects"`
+	Exports   int       `json:"exports"`
+	LastUsed  time.Time `json:"last_used"` // zero when never accessed
+	Projects  []string  `json:"projects"`  // distinct, sorted
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Total returns the number of recorded accesses.
+func (u *KeyUsage) Total() int {
+	return u.Reads + u.Injects + u.Exports
+}
+
+// Stale reports whether the key has not been accessed since cutoff. Keys
+// created after cutoff have not had the chance yet and are never stale.
+func (u *KeyUsage) Stale(cutoff time.Time) bool {
+	return u.CreatedAt.Before(cutoff) && u.LastUsed.Before(cutoff)
+}
+
+// UsageStats aggregates read, inject and export audit entries per stored key.
+// Every current key is present, including ones never accessed; entries for
+// keys that no longer exist are ignored.
+func (s *KeyStorage) UsageStats() (map[string]KeyUsage, error) {
+	logs, err := s.ReadAuditLogs(0)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make(map[string]*KeyUsage)
+	projects := make(map[string]map[string]bool)
+	s.EachKey("", func(key *models.APIKey) bool {
+		stats[key.Name] = &KeyUsage{CreatedAt: key.CreatedAt.Time}
+		projects[key.Name] = make(map[string]bool)
+		return true
+	})
+
+	for _, log := range logs {
+		usage, ok := stats[log.KeyName]
+		if !ok {
+			continue
+		}
+		switch log.Action {
+		case "read":
+			usage.Reads++
+		case "inject":
+			usage.Injects++
+		case "export":
+			usage.Exports++
+		default:
+			continue
+		}
+		if log.Timestamp.After(usage.LastUsed) {
+			usage.LastUsed = log.Timestamp.Time
+		}
+		if log.Project != "" {
+			projects[log.KeyName][log.Project] = true
+		}
+	}
+
+	result := make(map[string]KeyUsage, len(stats))
+	for name, usage := range stats {
+		usage.Projects = make([]string, 0, len(projects[name]))
+		for project := range projects[name] {
+			usage.Projects = append(usage.Projects, project)
+		}
+		slices.Sort(usage.Projects)
+		result[name] = *usage
+	}
+	return result, nil
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// AuditSink receives each signed audit entry (one JSON line) after it has been
+// appended to the local audit file. It defaults to the sink configured via
+// AKM_AUDIT_SINK; nil disables it.
+var AuditSink func(entry []byte) error = auditSinkFromEnv()
+
+// auditSinkTimeout bounds how long a single entry may wait on the collector.
+const auditSinkTimeout = 5 * time.Second
+
+// auditSinkFromEnv builds the sink from AKM_AUDIT_SINK:
+//
+//	syslog                     local syslog daemon
+//	syslog://host:514          remote syslog over UDP (syslog+tcp:// for TCP)
+//	http(s)://collector/path   POST each entry as application/json
+func auditSinkFromEnv() func(entry []byte) error {
+	target := strings.TrimSpace(os.Getenv("AKM_AUDIT_SINK"))
+	if target == "" {
+		return nil
+	}
+
+	switch {
+	case strings.HasPrefix(target, "http://"), strings.HasPrefix(target, "https://"):
+		client := &http.Client{Timeout: auditSinkTimeout}
+		return func(entry []byte) error {
+			resp, err := client.Post(target, "application/json", bytes.NewReader(entry))
+			if err != nil {
+				return fmt.Errorf("audit collector failed: %w", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode >= 300 {
+				return fmt.Errorf("audit collector returned HTTP %d", resp.StatusCode)
+			}
+			return nil
+		}
+	case target == "syslog":
+		return syslogSink("", "")
+	case strings.HasPrefix(target, "syslog://"):
+		return syslogSink("udp", strings.TrimPrefix(target, "syslog://"))
+	case strings.HasPrefix(target, "syslog+tcp://"):
+		return syslogSink("tcp", strings.TrimPrefix(target, "syslog+tcp://"))
+	}
+
+	fmt.Fprintf(os.Stderr, "⚠️  不支持的 AKM_AUDIT_SINK: %s（应为 syslog、syslog://host:port 或 http(s):// 地址），已忽略\n", target)
+	return nil
+}
+
+// shipAuditEntry sends entry to the configured sink, if any. Failures are
+// counted in AuditErrors and reported on stderr.
+func shipAuditEntry(entry []byte) error {
+	if AuditSink == nil {
+		return nil
+	}
+	if err := AuditSink(entry); err != nil {
+		cnt := AuditErrors.Add(1)
+		fmt.Fprintf(os.Stderr, "⚠️  审计日志外发失败 (累计 %d 次): %v\n", cnt, err)
+		return err
+	}
+	return nil
+}
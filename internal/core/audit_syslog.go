@@ -0,0 +1,36 @@
+//go:build !windows && !plan9
+
+package core
+
+import (
+	"fmt"
+	"log/syslog"
+	"sync"
+)
+
+// syslogSink writes entries to syslog at info level under the "akm" tag.
+// An empty network dials the local daemon. The connection is opened on first
+// use and re-dialed after a failed write.
+func syslogSink(network, addr string) func(entry []byte) error {
+	var (
+		mu sync.Mutex
+		w  *syslog.Writer
+	)
+	return func(entry []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if w == nil {
+			var err error
+			w, err = syslog.Dial(network, addr, syslog.LOG_INFO|syslog.LOG_AUTH, "akm")
+			if err != nil {
+				return fmt.Errorf("syslog unavailable: %w", err)
+			}
+		}
+		if err := w.Info(string(entry)); err != nil {
+			w.Close()
+			w = nil
+			return fmt.Errorf("syslog write failed: %w", err)
+		}
+		return nil
+	}
+}
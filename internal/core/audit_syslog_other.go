@@ -0,0 +1,13 @@
+//go:build windows || plan9
+
+package core
+
+import "errors"
+
+// syslogSink is unavailable on this platform; every entry reports an error so
+// the misconfiguration shows up in AuditErrors.
+func syslogSink(network, addr string) func(entry []byte) error {
+	return func(entry []byte) error {
+		return errors.New("syslog is not supported on this platform")
+	}
+}
@@ -0,0 +1,77 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// autoBackupPrefix names auto-backup directories; only these are ever pruned.
+	autoBackupPrefix = "auto-"
+	// DefaultAutoBackupKeep is how many auto-backups are kept without AKM_AUTO_BACKUP_KEEP.
+	DefaultAutoBackupKeep = 10
+)
+
+// autoBackupEnabled reports whether AKM_AUTO_BACKUP is set.
+func autoBackupEnabled() bool {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv("AKM_AUTO_BACKUP"))) {
+	case "1", "true", "yes", "on":
+		return true
+	}
+	return false
+}
+
+// autoBackupKeep returns the retention count from AKM_AUTO_BACKUP_KEEP.
+func autoBackupKeep() int {
+	if n, err := strconv.Atoi(os.Getenv("AKM_AUTO_BACKUP_KEEP")); err == nil && n > 0 {
+		return n
+	}
+	return DefaultAutoBackupKeep
+}
+
+// AutoBackupDir is where auto-backups land: the "backups" directory next to
+// the data directory (~/.apikey-manager/backups by default).
+func (s *KeyStorage) AutoBackupDir() string {
+	return filepath.Join(filepath.Dir(s.dataDir), "backups")
+}
+
+// autoBackup takes a full backup before a destructive operation when
+// AKM_AUTO_BACKUP is enabled, then prunes the oldest auto-backups beyond the
+// retention count. A failed backup aborts the operation.
+func (s *KeyStorage) autoBackup(operation string) error {
+	if !autoBackupEnabled() {
+		return nil
+	}
+
+	name := autoBackupPrefix + time.Now().Format("20060102-150405.000") + "-" + operation
+	dir := filepath.Join(s.AutoBackupDir(), name)
+	if err := s.Backup(dir, time.Time{}); err != nil {
+		return fmt.Errorf("auto-backup before %s failed, operation aborted: %w", operation, err)
+	}
+
+	entries, err := os.ReadDir(s.AutoBackupDir())
+	if err != nil {
+		return nil // the backup itself succeeded
+	}
+	var autos []string
+	for _, e := range entries {
+		if e.IsDir() && strings.HasPrefix(e.Name(), autoBackupPrefix) {
+			autos = append(autos, e.Name())
+		}
+	}
+	// Names start with the timestamp, so lexical order is chronological
+	sort.Strings(autos)
+	for len(autos) > autoBackupKeep() {
+		if err := os.RemoveAll(filepath.Join(s.AutoBackupDir(), autos[0])); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  清理旧自动备份失败: %v\n", err)
+			break
+		}
+		autos = autos[1:]
+	}
+	return nil
+}
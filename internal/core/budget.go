@@ -1,18 +1,23 @@
 package core
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 // BudgetConfig defines per-provider request limits.
 type BudgetConfig struct {
-	DailyLimit   int64 `json:"daily_limit"`   // 0 = unlimited
-	MonthlyLimit int64 `json:"monthly_limit"` // 0 = unlimited
+	DailyLimit   int64 `json:"daily_limit" yaml:"daily_limit"`     // 0 = unlimited
+	MonthlyLimit int64 `json:"monthly_limit" yaml:"monthly_limit"` // 0 = unlimited
 }
 
 // providerCounter tracks request counts for a single provider.
@@ -25,7 +30,7 @@ type providerCounter struct {
 
 // budgetData is the persistent file format.
 type budgetData struct {
-	Config   map[string]*BudgetConfig   `json:"config"`
+	Config   map[string]*BudgetConfig    `json:"config"`
 	Counters map[string]*providerCounter `json:"counters"`
 }
 
@@ -35,31 +40,40 @@ type BudgetTracker struct {
 	config   map[string]*BudgetConfig
 	counters map[string]*providerCounter
 	file     string
+
+	// Record only marks the counters dirty; a single saver goroutine,
+	// started on first use, writes them out at most once per
+	// budgetSaveInterval
+	saveCh    chan struct{}
+	saverOnce sync.Once
 }
 
+// budgetSaveInterval is the minimum gap between background counter saves,
+// and so the longest a recorded request stays in memory only.
+var budgetSaveInterval = time.Second
+
 var (
 	budgetInstance *BudgetTracker
 	budgetOnce     sync.Once
+	budgetErr      error // sticky: later calls see the first failure
 )
 
-// GetBudgetTracker returns the singleton BudgetTracker.
+// GetBudgetTracker returns the singleton BudgetTracker for the active profile.
 func GetBudgetTracker() (*BudgetTracker, error) {
-	var initErr error
 	budgetOnce.Do(func() {
-		homeDir, err := os.UserHomeDir()
+		dataDir, err := DataDir()
 		if err != nil {
-			initErr = err
+			budgetErr = err
 			return
 		}
-		dataDir := filepath.Join(homeDir, ".apikey-manager", "data")
 		if err := os.MkdirAll(dataDir, 0700); err != nil {
-			initErr = err
+			budgetErr = err
 			return
 		}
-		budgetInstance, initErr = newBudgetTracker(filepath.Join(dataDir, "budget.json"))
+		budgetInstance, budgetErr = newBudgetTracker(filepath.Join(dataDir, "budget.json"))
 	})
-	if initErr != nil {
-		return nil, initErr
+	if budgetErr != nil {
+		return nil, budgetErr
 	}
 	return budgetInstance, nil
 }
@@ -69,6 +83,7 @@ func newBudgetTracker(file string) (*BudgetTracker, error) {
 		config:   make(map[string]*BudgetConfig),
 		counters: make(map[string]*providerCounter),
 		file:     file,
+		saveCh:   make(chan struct{}, 1),
 	}
 	if err := bt.load(); err != nil && !os.IsNotExist(err) {
 		return nil, fmt.Errorf("failed to load budget data: %w", err)
@@ -83,7 +98,14 @@ func (bt *BudgetTracker) load() error {
 	}
 	var bd budgetData
 	if err := json.Unmarshal(data, &bd); err != nil {
-		return err
+		// A truncated or garbled file must not take the proxy down with it:
+		// keep a copy for inspection and start from empty state.
+		corrupt := bt.file + ".corrupt"
+		if renameErr := os.Rename(bt.file, corrupt); renameErr != nil {
+			return fmt.Errorf("budget file is corrupt (%v) and could not be moved aside: %w", err, renameErr)
+		}
+		fmt.Fprintf(os.Stderr, "⚠️  预算文件损坏 (%v)，已备份到 %s 并重置\n", err, corrupt)
+		return nil
 	}
 	if bd.Config != nil {
 		bt.config = bd.Config
@@ -94,6 +116,9 @@ func (bt *BudgetTracker) load() error {
 	return nil
 }
 
+// save writes the budget file atomically. Callers hold bt.mu: writers hold
+// it exclusively and the saver goroutine is the only reader that saves, so
+// writes never overlap. Each still gets its own temp file.
 func (bt *BudgetTracker) save() error {
 	bd := budgetData{
 		Config:   bt.config,
@@ -103,11 +128,55 @@ func (bt *BudgetTracker) save() error {
 	if err != nil {
 		return err
 	}
-	tempFile := bt.file + ".tmp"
-	if err := os.WriteFile(tempFile, data, 0600); err != nil {
+	tmp, err := os.CreateTemp(filepath.Dir(bt.file), filepath.Base(bt.file)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName) // no-op after successful rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
 		return err
 	}
-	return os.Rename(tempFile, bt.file)
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpName, bt.file)
+}
+
+// scheduleSave asks the saver goroutine for a write, coalescing with one
+// already pending.
+func (bt *BudgetTracker) scheduleSave() {
+	bt.saverOnce.Do(func() { go bt.saveLoop() })
+	select {
+	case bt.saveCh <- struct{}{}:
+	default:
+	}
+}
+
+// saveLoop writes the counters whenever they changed, then waits out
+// budgetSaveInterval; requests recorded meanwhile leave one pending signal
+// and are written together on the next pass.
+func (bt *BudgetTracker) saveLoop() {
+	for range bt.saveCh {
+		bt.saveCounters()
+		time.Sleep(budgetSaveInterval)
+	}
+}
+
+func (bt *BudgetTracker) saveCounters() {
+	// A panic here would take down the whole server, not just one request
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  保存预算失败: %v\n", r)
+		}
+	}()
+	bt.mu.RLock()
+	defer bt.mu.RUnlock()
+	if err := bt.save(); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  保存预算失败: %v\n", err)
+	}
 }
 
 // Check returns an error if the provider has exceeded its budget.
@@ -134,7 +203,9 @@ func (bt *BudgetTracker) Check(provider string) error {
 	return nil
 }
 
-// Record records one request for the provider. Saves asynchronously.
+// Record records one request for the provider. The save is coalesced with
+// other recent requests and happens in the background within
+// budgetSaveInterval.
 func (bt *BudgetTracker) Record(provider string) {
 	bt.mu.Lock()
 	counter := bt.ensureCounter(provider)
@@ -158,12 +229,7 @@ func (bt *BudgetTracker) Record(provider string) {
 
 	bt.mu.Unlock()
 
-	// Async save (best-effort)
-	go func() {
-		bt.mu.RLock()
-		defer bt.mu.RUnlock()
-		_ = bt.save()
-	}()
+	bt.scheduleSave()
 }
 
 // SetConfig sets budget limits for a provider.
@@ -178,6 +244,117 @@ func (bt *BudgetTracker) SetConfig(provider string, daily, monthly int64) error
 	return bt.save()
 }
 
+// ExportConfig returns a copy of the per-provider limits, without counters.
+func (bt *BudgetTracker) ExportConfig() map[string]*BudgetConfig {
+	bt.mu.RLock()
+	defer bt.mu.RUnlock()
+
+	config := make(map[string]*BudgetConfig, len(bt.config))
+	for p, cfg := range bt.config {
+		c := *cfg
+		config[p] = &c
+	}
+	return config
+}
+
+// ImportConfig applies limits from an exported config. Existing limits for
+// providers not in config are kept unless replace is set. Counters are never
+// touched.
+func (bt *BudgetTracker) ImportConfig(config map[string]*BudgetConfig, replace bool) error {
+	if err := ValidateBudgetConfig(config); err != nil {
+		return err
+	}
+
+	bt.mu.Lock()
+	defer bt.mu.Unlock()
+
+	previous := bt.config
+	next := make(map[string]*BudgetConfig, len(config))
+	if !replace {
+		for p, cfg := range previous {
+			next[p] = cfg
+		}
+	}
+	for p, cfg := range config {
+		c := *cfg
+		next[p] = &c
+	}
+	bt.config = next
+	if err := bt.save(); err != nil {
+		bt.config = previous
+		return err
+	}
+	return nil
+}
+
+// ValidateBudgetConfig checks that every provider is a known platform ID and
+// every limit is non-negative.
+func ValidateBudgetConfig(config map[string]*BudgetConfig) error {
+	known := make(map[string]bool)
+	for _, p := range Platforms() {
+		known[p.ID] = true
+	}
+	for provider, cfg := range config {
+		if !known[provider] {
+			return fmt.Errorf("unknown provider %q", provider)
+		}
+		if cfg == nil {
+			return fmt.Errorf("provider %q has no limits", provider)
+		}
+		if cfg.DailyLimit < 0 || cfg.MonthlyLimit < 0 {
+			return fmt.Errorf("provider %q: limits must be >= 0 (0 = unlimited)", provider)
+		}
+	}
+	return nil
+}
+
+// MarshalBudgetConfig encodes config as "json" or "yaml".
+func MarshalBudgetConfig(config map[string]*BudgetConfig, format string) ([]byte, error) {
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(config, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case "yaml":
+		return yaml.Marshal(config)
+	}
+	return nil, fmt.Errorf("unsupported format %q (use json or yaml)", format)
+}
+
+// UnmarshalBudgetConfig decodes a "json" or "yaml" budget config, rejecting
+// unknown fields so typos in limit names are not silently ignored.
+func UnmarshalBudgetConfig(data []byte, format string) (map[string]*BudgetConfig, error) {
+	config := make(map[string]*BudgetConfig)
+	switch format {
+	case "json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&config); err != nil {
+			return nil, fmt.Errorf("invalid budget config: %w", err)
+		}
+	case "yaml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&config); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("invalid budget config: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported format %q (use json or yaml)", format)
+	}
+	return config, nil
+}
+
+// BudgetConfigFormat infers "json" or "yaml" from a file name, defaulting to json.
+func BudgetConfigFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return "yaml"
+	}
+	return "json"
+}
+
 // ResetCounter resets the counter for a provider.
 func (bt *BudgetTracker) ResetCounter(provider string) error {
 	bt.mu.Lock()
@@ -0,0 +1,39 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// A truncated budget.json is moved aside and the tracker starts empty rather
+// than failing to load.
+func TestNewBudgetTrackerCorruptFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "budget.json")
+	truncated := []byte(`{"config": {"openai": {"daily_limit": 10`)
+	if err := os.WriteFile(file, truncated, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	bt, err := newBudgetTracker(file)
+	if err != nil {
+		t.Fatalf("newBudgetTracker: %v", err)
+	}
+	if len(bt.config) != 0 || len(bt.counters) != 0 {
+		t.Errorf("state not empty: config %v, counters %v", bt.config, bt.counters)
+	}
+	if stats := bt.GetAllStats(); len(stats) != 0 {
+		t.Errorf("GetAllStats = %v, want none", stats)
+	}
+
+	backup, err := os.ReadFile(file + ".corrupt")
+	if err != nil {
+		t.Fatalf("corrupt file not kept: %v", err)
+	}
+	if string(backup) != string(truncated) {
+		t.Errorf("budget.json.corrupt = %q, want the original contents", backup)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("budget.json still present after recovery: %v", err)
+	}
+}
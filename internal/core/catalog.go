@@ -0,0 +1,137 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CatalogEntry describes one key without its value. Field names follow the
+// MCP akm_list shape so both outputs can be consumed the same way.
+type CatalogEntry struct {
+	Name          string        `json:"name"`
+	Provider      string        `json:"provider"`
+	Description   *string       `json:"description,omitempty"`
+	SourceProject *string       `json:"source_project,omitempty"`
+	Tags          []string      `json:"tags,omitempty"`
+	IsActive      bool          `json:"is_active"`
+	ExpiresAt     *time.Time    `json:"expires_at,omitempty"`
+	Expired       bool          `json:"expired,omitempty"`
+	LastVerified  *VerifyStatus `json:"last_verified,omitempty"`
+	CreatedBy     *string       `json:"created_by,omitempty"`
+	UpdatedBy     *string       `json:"updated_by,omitempty"`
+}
+
+// Catalog returns a values-free inventory of the keys matching filter, sorted
+// by name. It never decrypts anything, so it is not audited.
+func (s *KeyStorage) Catalog(filter KeyFilter) ([]CatalogEntry, error) {
+	statuses, err := s.LoadVerifyStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	entries := []CatalogEntry{}
+	s.mu.RLock()
+	for _, key := range s.keysCache {
+		if !filter.matches(key) {
+			continue
+		}
+		entry := CatalogEntry{
+			Name:          key.Name,
+			Provider:      key.Provider,
+			Description:   key.Description,
+			SourceProject: key.SourceProject,
+			Tags:          key.Tags,
+			IsActive:      key.IsActive,
+			ExpiresAt:     key.ExpiresAt.Time,
+			Expired:       key.IsExpired(now),
+			LastVerified:  statuses[key.Name],
+			CreatedBy:     key.CreatedBy,
+			UpdatedBy:     key.UpdatedBy,
+		}
+		entries = append(entries, entry)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries, nil
+}
+
+// FormatCatalogMarkdown renders catalog entries as a Markdown table suitable
+// for a wiki page.
+func FormatCatalogMarkdown(entries []CatalogEntry) string {
+	var b strings.Builder
+	b.WriteString("# API Key Catalog\n\n")
+	fmt.Fprintf(&b, "Generated at %s · %d keys\n\n", time.Now().Format("2006-01-02 15:04"), len(entries))
+	// Ownership columns only appear once some key records them
+	showOwners := false
+	for _, e := range entries {
+		if e.CreatedBy != nil || e.UpdatedBy != nil {
+			showOwners = true
+			break
+		}
+	}
+
+	b.WriteString("| Name | Provider | Tags | Description | Status | Expires | Last verified |")
+	if showOwners {
+		b.WriteString(" Created by | Updated by |")
+	}
+	b.WriteString("\n|------|----------|------|-------------|--------|---------|---------------|")
+	if showOwners {
+		b.WriteString("------------|------------|")
+	}
+	b.WriteString("\n")
+
+	for _, e := range entries {
+		status := "active"
+		switch {
+		case e.Expired:
+			status = "expired"
+		case !e.IsActive:
+			status = "inactive"
+		}
+		expires := "-"
+		if e.ExpiresAt != nil {
+			expires = e.ExpiresAt.Format("2006-01-02")
+		}
+		verified := "-"
+		if e.LastVerified != nil {
+			verified = e.LastVerified.Status
+			if t, err := time.Parse(time.RFC3339, e.LastVerified.CheckedAt); err == nil {
+				verified += " (" + t.Format("2006-01-02") + ")"
+			}
+		}
+		description := "-"
+		if e.Description != nil && *e.Description != "" {
+			description = *e.Description
+		}
+		tags := "-"
+		if len(e.Tags) > 0 {
+			tags = strings.Join(e.Tags, ", ")
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s | %s |",
+			markdownCell(e.Name), markdownCell(e.Provider), markdownCell(tags),
+			markdownCell(description), status, expires, markdownCell(verified))
+		if showOwners {
+			fmt.Fprintf(&b, " %s | %s |", markdownCell(optionalString(e.CreatedBy)), markdownCell(optionalString(e.UpdatedBy)))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// optionalString returns *s, or "-" when unset.
+func optionalString(s *string) string {
+	if s == nil || *s == "" {
+		return "-"
+	}
+	return *s
+}
+
+// markdownCell keeps user text from breaking the table layout.
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ").Replace(s)
+}
@@ -6,7 +6,10 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"os"
+	"strings"
 	"sync"
 
 	"github.com/fernet/fernet-go"
@@ -18,39 +21,110 @@ const (
 	ServiceName = "apikey-manager"
 	// MasterKeyAccount is the keyring account name for the master key.
 	MasterKeyAccount = "master_key"
+	// PreviousMasterKeyAccount is the keyring account name for the previous master key,
+	// kept for decryption only while records are migrated to the current one.
+	PreviousMasterKeyAccount = "master_key_previous"
+)
+
+// Key source labels reported by DecryptWithSource.
+const (
+	KeySourcePrimary  = "primary"
+	KeySourcePrevious = "previous"
 )
 
 // KeyEncryption handles encryption/decryption with Fernet and system keychain.
 type KeyEncryption struct {
-	masterKey *fernet.Key
-	mu        sync.RWMutex
+	masterKey   *fernet.Key
+	previousKey *fernet.Key // optional, decrypt-only
+	// identityFile is set when the master key was unwrapped with a team
+	// member identity instead of read from the keychain
+	identityFile string
+	// envSource names the environment variable the master key was read from
+	// (AKM_MASTER_KEY or AKM_MASTER_KEY_FILE), bypassing the keychain
+	envSource string
+	// passphraseFile is set when the master key was unlocked from master.enc;
+	// envelope and kek are kept to rewrite it on rotation
+	passphraseFile string
+	envelope       *masterEnvelope
+	kek            *fernet.Key
+	mu             sync.RWMutex
 }
 
 var (
 	cryptoInstance *KeyEncryption
 	cryptoOnce     sync.Once
+	cryptoErr      error // sticky: later calls see the first failure
 )
 
 // GetCrypto returns the singleton KeyEncryption instance.
 func GetCrypto() (*KeyEncryption, error) {
-	var initErr error
 	cryptoOnce.Do(func() {
 		cryptoInstance = &KeyEncryption{}
-		initErr = cryptoInstance.Initialize()
+		cryptoErr = cryptoInstance.Initialize()
 	})
-	if initErr != nil {
-		return nil, initErr
+	if cryptoErr != nil {
+		return nil, cryptoErr
 	}
 	return cryptoInstance, nil
 }
 
+// NewKeyEncryption returns a KeyEncryption using the given encoded Fernet key
+// without touching the system keychain (for tests and alternative backends).
+func NewKeyEncryption(encodedKey string) (*KeyEncryption, error) {
+	key, err := fernet.DecodeKey(encodedKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid master key format: %w", err)
+	}
+	return &KeyEncryption{masterKey: key}, nil
+}
+
 // Initialize loads or generates the master key from system keychain.
 func (k *KeyEncryption) Initialize() error {
 	k.mu.Lock()
 	defer k.mu.Unlock()
 
-	// Try to get master key from keychain
-	masterKeyB64, err := keyring.Get(ServiceName, MasterKeyAccount)
+	// A master key supplied through the environment (CI runners have no
+	// keychain) wins over everything else
+	if key, source, err := masterKeyFromEnv(); err != nil {
+		return err
+	} else if key != nil {
+		if existing, err := keychain.Get(ServiceName, MasterKeyAccount); err == nil && existing != "" {
+			fmt.Fprintf(os.Stderr, "⚠️  Both %s and a keychain master key are present; using %s\n", source, source)
+		}
+		k.masterKey, k.envSource = key, source
+		markRevealAuth()
+		return nil
+	}
+
+	// An explicitly configured team identity takes precedence over the keychain
+	identityPath, explicit := identityFileFromEnv()
+	if explicit {
+		key, err := masterKeyFromIdentity(identityPath)
+		if err != nil {
+			return err
+		}
+		k.masterKey, k.identityFile = key, identityPath
+		markRevealAuth()
+		return nil
+	}
+
+	// A passphrase-protected master.enc replaces the keychain entry
+	if path, err := MasterKeyFile(); err != nil {
+		return err
+	} else if _, err := os.Stat(path); err == nil {
+		if err := k.loadPassphraseFile(path); err != nil {
+			return err
+		}
+		markRevealAuth()
+		return nil
+	}
+
+	// Try to get master key from keychain. Only a definite "not found" may
+	// lead to a new key; any other failure must not replace the real one.
+	masterKeyB64, err := keychainGet(MasterKeyAccount)
+	if err != nil && !errors.Is(err, keyring.ErrNotFound) {
+		return fmt.Errorf("failed to read master key from keychain: %w", err)
+	}
 	if err == nil && masterKeyB64 != "" {
 		// Decode and parse existing key
 		keyBytes, err := base64.StdEncoding.DecodeString(masterKeyB64)
@@ -62,9 +136,24 @@ func (k *KeyEncryption) Initialize() error {
 			return fmt.Errorf("failed to parse master key: %w", err)
 		}
 		k.masterKey = key
+		k.previousKey = loadPreviousKey()
+		markRevealAuth()
 		return nil
 	}
 
+	// A member of a shared vault has no keychain entry, only an identity
+	if identityPath != "" {
+		key, err := masterKeyFromIdentity(identityPath)
+		if err == nil {
+			k.masterKey, k.identityFile = key, identityPath
+			markRevealAuth()
+			return nil
+		}
+		if !errors.Is(err, errNotMember) {
+			return err
+		}
+	}
+
 	// Generate new master key
 	key := fernet.Key{}
 	if err := key.Generate(); err != nil {
@@ -74,14 +163,144 @@ func (k *KeyEncryption) Initialize() error {
 	// Store in keychain (base64 of the key bytes)
 	keyStr := key.Encode()
 	masterKeyB64 = base64.StdEncoding.EncodeToString([]byte(keyStr))
-	if err := keyring.Set(ServiceName, MasterKeyAccount, masterKeyB64); err != nil {
+	if err := keychainSet(MasterKeyAccount, masterKeyB64); err != nil {
 		return fmt.Errorf("failed to store master key in keychain: %w", err)
 	}
 
 	k.masterKey = &key
+	markRevealAuth()
+	return nil
+}
+
+// Reauthenticate re-reads the master key from the keychain (or unwraps it
+// again with the team identity or passphrase) and checks it still matches the
+// one in memory. It fails when the keychain is locked, access is denied, or
+// the key was replaced since this process loaded it.
+func (k *KeyEncryption) Reauthenticate() error {
+	k.mu.RLock()
+	identityFile, envSource, passphraseFile := k.identityFile, k.envSource, k.passphraseFile
+	k.mu.RUnlock()
+	if envSource != "" {
+		key, _, err := masterKeyFromEnv()
+		if err != nil {
+			return err
+		}
+		k.mu.RLock()
+		defer k.mu.RUnlock()
+		if key == nil || k.masterKey == nil || k.masterKey.Encode() != key.Encode() {
+			return fmt.Errorf("master key from %s no longer matches the loaded key", envSource)
+		}
+		return nil
+	}
+	if passphraseFile != "" {
+		env, err := readMasterEnvelope(passphraseFile)
+		if err != nil {
+			return err
+		}
+		passphrase, err := masterPassphrase()
+		if err != nil {
+			return err
+		}
+		_, key, _, err := env.unlock(passphrase)
+		if err != nil {
+			return err
+		}
+		k.mu.RLock()
+		defer k.mu.RUnlock()
+		if k.masterKey == nil || k.masterKey.Encode() != key.Encode() {
+			return fmt.Errorf("master key in %s no longer matches the loaded key", masterKeyFileName)
+		}
+		return nil
+	}
+	if identityFile != "" {
+		key, err := masterKeyFromIdentity(identityFile)
+		if err != nil {
+			return err
+		}
+		k.mu.RLock()
+		defer k.mu.RUnlock()
+		if k.masterKey == nil || k.masterKey.Encode() != key.Encode() {
+			return fmt.Errorf("master key unwrapped from identity no longer matches the loaded key")
+		}
+		return nil
+	}
+
+	masterKeyB64, err := keychainGet(MasterKeyAccount)
+	if err != nil {
+		return fmt.Errorf("keychain access failed: %w", err)
+	}
+	keyBytes, err := base64.StdEncoding.DecodeString(masterKeyB64)
+	if err != nil {
+		return fmt.Errorf("failed to decode master key: %w", err)
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if k.masterKey == nil || k.masterKey.Encode() != string(keyBytes) {
+		return fmt.Errorf("master key in keychain no longer matches the loaded key")
+	}
 	return nil
 }
 
+// masterKeyFromEnv reads the master key from AKM_MASTER_KEY, or from the file
+// named by AKM_MASTER_KEY_FILE, in the same encoding ExportMasterKey prints.
+// It returns a nil key when neither is set.
+func masterKeyFromEnv() (*fernet.Key, string, error) {
+	source := "AKM_MASTER_KEY"
+	encoded := strings.TrimSpace(os.Getenv(source))
+	if encoded == "" {
+		path := strings.TrimSpace(os.Getenv("AKM_MASTER_KEY_FILE"))
+		if path == "" {
+			return nil, "", nil
+		}
+		source = "AKM_MASTER_KEY_FILE"
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read %s: %w", source, err)
+		}
+		encoded = strings.TrimSpace(string(data))
+	}
+	key, err := fernet.DecodeKey(encoded)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid master key in %s: %w", source, err)
+	}
+	return key, source, nil
+}
+
+// MasterKeySource reports where the loaded master key came from: the
+// environment variable name, "identity", "passphrase", or "keychain".
+func (k *KeyEncryption) MasterKeySource() string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	switch {
+	case k.envSource != "":
+		return k.envSource
+	case k.identityFile != "":
+		return "identity"
+	case k.passphraseFile != "":
+		return "passphrase"
+	default:
+		return "keychain"
+	}
+}
+
+// loadPreviousKey reads the optional previous master key from keychain.
+func loadPreviousKey() *fernet.Key {
+	previousB64, err := keychainGet(PreviousMasterKeyAccount)
+	if err != nil || previousB64 == "" {
+		return nil
+	}
+	keyBytes, err := base64.StdEncoding.DecodeString(previousB64)
+	if err != nil {
+		return nil
+	}
+	key, err := fernet.DecodeKey(string(keyBytes))
+	if err != nil {
+		return nil
+	}
+	return key
+}
+
 // Encrypt encrypts plaintext and returns base64-encoded ciphertext.
 func (k *KeyEncryption) Encrypt(plaintext string) (string, error) {
 	k.mu.RLock()
@@ -101,24 +320,35 @@ func (k *KeyEncryption) Encrypt(plaintext string) (string, error) {
 
 // Decrypt decrypts base64-encoded ciphertext and returns plaintext.
 func (k *KeyEncryption) Decrypt(encrypted string) (string, error) {
+	plaintext, _, err := k.DecryptWithSource(encrypted)
+	return plaintext, err
+}
+
+// DecryptWithSource decrypts like Decrypt and also reports which master key
+// succeeded (KeySourcePrimary or KeySourcePrevious).
+func (k *KeyEncryption) DecryptWithSource(encrypted string) (string, string, error) {
 	k.mu.RLock()
 	defer k.mu.RUnlock()
 
 	if k.masterKey == nil {
-		return "", fmt.Errorf("encryption system not initialized")
+		return "", "", fmt.Errorf("encryption system not initialized")
 	}
 
 	ciphertext, err := base64.StdEncoding.DecodeString(encrypted)
 	if err != nil {
-		return "", fmt.Errorf("failed to decode ciphertext: %w", err)
+		return "", "", fmt.Errorf("failed to decode ciphertext: %w", err)
 	}
 
-	plaintext := fernet.VerifyAndDecrypt(ciphertext, 0, []*fernet.Key{k.masterKey})
-	if plaintext == nil {
-		return "", fmt.Errorf("decryption failed: invalid token or key")
+	if plaintext := fernet.VerifyAndDecrypt(ciphertext, 0, []*fernet.Key{k.masterKey}); plaintext != nil {
+		return string(plaintext), KeySourcePrimary, nil
+	}
+	if k.previousKey != nil {
+		if plaintext := fernet.VerifyAndDecrypt(ciphertext, 0, []*fernet.Key{k.previousKey}); plaintext != nil {
+			return string(plaintext), KeySourcePrevious, nil
+		}
 	}
 
-	return string(plaintext), nil
+	return "", "", fmt.Errorf("decryption failed: invalid token or key")
 }
 
 // SignMessage creates an HMAC-SHA256 signature of the message.
@@ -130,11 +360,7 @@ func (k *KeyEncryption) SignMessage(message string) (string, error) {
 		return "", fmt.Errorf("encryption system not initialized")
 	}
 
-	// Use the raw key bytes for HMAC
-	keyBytes := []byte(k.masterKey.Encode())
-	h := hmac.New(sha256.New, keyBytes)
-	h.Write([]byte(message))
-	return hex.EncodeToString(h.Sum(nil)), nil
+	return hmacHex(k.masterKey, message), nil
 }
 
 // VerifySignature verifies an HMAC-SHA256 signature.
@@ -146,6 +372,32 @@ func (k *KeyEncryption) VerifySignature(message, signature string) (bool, error)
 	return hmac.Equal([]byte(expected), []byte(signature)), nil
 }
 
+// VerifySignatureWithSource verifies signature against the current master key,
+// then the previous one, and reports which matched ("" if neither did).
+func (k *KeyEncryption) VerifySignatureWithSource(message, signature string) (string, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	if k.masterKey == nil {
+		return "", fmt.Errorf("encryption system not initialized")
+	}
+
+	if hmac.Equal([]byte(hmacHex(k.masterKey, message)), []byte(signature)) {
+		return KeySourcePrimary, nil
+	}
+	if k.previousKey != nil && hmac.Equal([]byte(hmacHex(k.previousKey, message)), []byte(signature)) {
+		return KeySourcePrevious, nil
+	}
+	return "", nil
+}
+
+// hmacHex signs message with the raw encoded key bytes.
+func hmacHex(key *fernet.Key, message string) string {
+	h := hmac.New(sha256.New, []byte(key.Encode()))
+	h.Write([]byte(message))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
 // ExportMasterKey returns the base64-encoded master key for backup purposes.
 func (k *KeyEncryption) ExportMasterKey() (string, error) {
 	k.mu.RLock()
@@ -169,9 +421,17 @@ func (k *KeyEncryption) ImportMasterKey(encodedKey string) error {
 		return fmt.Errorf("invalid master key format: %w", err)
 	}
 
+	if stored, err := k.persistPassphraseLocked(key, k.previousKey); stored {
+		if err != nil {
+			return err
+		}
+		k.masterKey = key
+		return nil
+	}
+
 	// Store in keychain
 	masterKeyB64 := base64.StdEncoding.EncodeToString([]byte(encodedKey))
-	if err := keyring.Set(ServiceName, MasterKeyAccount, masterKeyB64); err != nil {
+	if err := keychainSet(MasterKeyAccount, masterKeyB64); err != nil {
 		return fmt.Errorf("failed to store master key in keychain: %w", err)
 	}
 
@@ -179,12 +439,113 @@ func (k *KeyEncryption) ImportMasterKey(encodedKey string) error {
 	return nil
 }
 
-// ResetMasterKey deletes the master key from keychain (dangerous operation).
+// ImportPreviousMasterKey stores a decrypt-only previous master key in keychain,
+// so records encrypted under it remain readable until they are rekeyed.
+func (k *KeyEncryption) ImportPreviousMasterKey(encodedKey string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	key, err := fernet.DecodeKey(encodedKey)
+	if err != nil {
+		return fmt.Errorf("invalid master key format: %w", err)
+	}
+
+	if stored, err := k.persistPassphraseLocked(k.masterKey, key); stored {
+		if err != nil {
+			return err
+		}
+		k.previousKey = key
+		return nil
+	}
+
+	previousB64 := base64.StdEncoding.EncodeToString([]byte(encodedKey))
+	if err := keychainSet(PreviousMasterKeyAccount, previousB64); err != nil {
+		return fmt.Errorf("failed to store previous master key in keychain: %w", err)
+	}
+
+	k.previousKey = key
+	return nil
+}
+
+// HasPreviousKey reports whether a decrypt-only previous master key is loaded.
+func (k *KeyEncryption) HasPreviousKey() bool {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.previousKey != nil
+}
+
+// DecryptsWithPrimary reports whether encrypted decrypts under the current
+// master key alone, i.e. it no longer depends on the previous key.
+func (k *KeyEncryption) DecryptsWithPrimary(encrypted string) bool {
+	_, source, err := k.DecryptWithSource(encrypted)
+	return err == nil && source == KeySourcePrimary
+}
+
+// dropPreviousKey deletes the previous master key from keychain, ending the
+// rotation transition window.
+func (k *KeyEncryption) dropPreviousKey() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if stored, err := k.persistPassphraseLocked(k.masterKey, nil); stored {
+		if err != nil {
+			return err
+		}
+	} else if err := keychainDelete(PreviousMasterKeyAccount); err != nil && !errors.Is(err, keyring.ErrNotFound) {
+		return fmt.Errorf("failed to delete previous master key: %w", err)
+	}
+	k.previousKey = nil
+	return nil
+}
+
+// promote makes newKey the master key, keeping the current one in keychain
+// as the previous (decrypt-only) key.
+func (k *KeyEncryption) promote(newKey *fernet.Key) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.masterKey == nil {
+		return fmt.Errorf("encryption system not initialized")
+	}
+	if k.envSource != "" {
+		return fmt.Errorf("master key is supplied by %s and cannot be rotated here; rotate on a machine using the keychain", k.envSource)
+	}
+
+	if stored, err := k.persistPassphraseLocked(newKey, k.masterKey); stored {
+		if err != nil {
+			return err
+		}
+	} else {
+		previousB64 := base64.StdEncoding.EncodeToString([]byte(k.masterKey.Encode()))
+		if err := keychainSet(PreviousMasterKeyAccount, previousB64); err != nil {
+			return fmt.Errorf("failed to store previous master key in keychain: %w", err)
+		}
+		masterKeyB64 := base64.StdEncoding.EncodeToString([]byte(newKey.Encode()))
+		if err := keychainSet(MasterKeyAccount, masterKeyB64); err != nil {
+			return fmt.Errorf("failed to store master key in keychain: %w", err)
+		}
+	}
+
+	k.previousKey = k.masterKey
+	k.masterKey = newKey
+	return nil
+}
+
+// ResetMasterKey deletes the master key from keychain, or master.enc in
+// passphrase mode (dangerous operation).
 func (k *KeyEncryption) ResetMasterKey() error {
 	k.mu.Lock()
 	defer k.mu.Unlock()
 
-	if err := keyring.Delete(ServiceName, MasterKeyAccount); err != nil {
+	if k.passphraseFile != "" {
+		if err := os.Remove(k.passphraseFile); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", masterKeyFileName, err)
+		}
+		k.masterKey, k.previousKey = nil, nil
+		k.passphraseFile, k.envelope, k.kek = "", nil, nil
+		return nil
+	}
+	if err := keychainDelete(MasterKeyAccount); err != nil {
 		return fmt.Errorf("failed to delete master key: %w", err)
 	}
 	k.masterKey = nil
@@ -0,0 +1,92 @@
+package core
+
+import (
+	"sync"
+	"time"
+)
+
+// decryptCache holds recently decrypted values so hot paths such as the proxy
+// skip a Fernet decrypt per request. Entries are tied to the ciphertext they
+// came from, so a value re-encrypted by any path simply misses, and expire
+// after ttl, when their plaintext bytes are zeroed.
+type decryptCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]*decryptEntry
+}
+
+type decryptEntry struct {
+	encrypted string
+	plaintext []byte
+	timer     *time.Timer
+}
+
+func newDecryptCache(ttl time.Duration) *decryptCache {
+	return &decryptCache{ttl: ttl, entries: make(map[string]*decryptEntry)}
+}
+
+// get returns the cached value of name if it was decrypted from encrypted.
+func (c *decryptCache) get(name, encrypted string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[name]
+	if !ok || e.encrypted != encrypted {
+		return "", false
+	}
+	return string(e.plaintext), true
+}
+
+// put caches value for name until the TTL elapses, replacing any entry.
+func (c *decryptCache) put(name, encrypted, value string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.removeLocked(name)
+	e := &decryptEntry{encrypted: encrypted, plaintext: []byte(value)}
+	e.timer = time.AfterFunc(c.ttl, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.entries[name] == e {
+			c.removeLocked(name)
+		}
+	})
+	c.entries[name] = e
+}
+
+// invalidate drops name's entry, if any.
+func (c *decryptCache) invalidate(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.removeLocked(name)
+}
+
+// removeLocked stops the entry's timer, zeroes its plaintext and deletes it.
+// Caller must hold c.mu.
+func (c *decryptCache) removeLocked(name string) {
+	e, ok := c.entries[name]
+	if !ok {
+		return
+	}
+	e.timer.Stop()
+	clear(e.plaintext)
+	delete(c.entries, name)
+}
+
+// WithDecryptCache keeps decrypted values in memory for ttl so repeated reads
+// of the same key skip decryption; 0 (the default) disables the cache.
+// Passphrase-protected keys are never cached, and every read is still audited.
+func WithDecryptCache(ttl time.Duration) StorageOption {
+	return func(s *KeyStorage) {
+		if ttl > 0 {
+			s.decrypted = newDecryptCache(ttl)
+		} else {
+			s.decrypted = nil
+		}
+	}
+}
+
+// invalidateDecrypted evicts name from the decrypt cache when it is enabled.
+func (s *KeyStorage) invalidateDecrypted(name string) {
+	if s.decrypted != nil {
+		s.decrypted.invalidate(name)
+	}
+}
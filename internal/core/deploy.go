@@ -0,0 +1,80 @@
+package core
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultSecretName is the metadata.name FormatK8sSecret uses when none is given.
+const DefaultSecretName = "akm-secrets"
+
+// k8sNamePattern is a Kubernetes DNS subdomain name (RFC 1123).
+var k8sNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+
+// k8sDataKeyPattern is what Kubernetes accepts as a Secret data key.
+var k8sDataKeyPattern = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
+
+// ValidateK8sName checks that name is usable as a Secret's metadata.name.
+func ValidateK8sName(name string) error {
+	if len(name) > 253 || !k8sNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid Kubernetes name %q: use lowercase letters, digits, '-' and '.'", name)
+	}
+	return nil
+}
+
+func sortedNames(keys map[string]string) []string {
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FormatDockerfile renders keys as Dockerfile `ENV KEY="value"` lines, sorted
+// by name. Backslashes, quotes and '$' are escaped so the builder neither
+// ends the string nor expands variables; a value spanning lines cannot be
+// written as one ENV instruction and is rejected.
+func FormatDockerfile(keys map[string]string) (string, error) {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`)
+	var b strings.Builder
+	for _, name := range sortedNames(keys) {
+		value := keys[name]
+		if strings.ContainsAny(value, "\r\n") {
+			return "", fmt.Errorf("key '%s' contains a newline, which a Dockerfile ENV cannot hold", name)
+		}
+		fmt.Fprintf(&b, "ENV %s=\"%s\"\n", name, replacer.Replace(value))
+	}
+	return b.String(), nil
+}
+
+// FormatK8sSecret renders keys as a v1 Opaque Secret manifest named name,
+// ready for `kubectl apply -f`. Values are base64-encoded under data.
+func FormatK8sSecret(name string, keys map[string]string) (string, error) {
+	if name == "" {
+		name = DefaultSecretName
+	}
+	if err := ValidateK8sName(name); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	b.WriteString("apiVersion: v1\nkind: Secret\nmetadata:\n")
+	fmt.Fprintf(&b, "  name: %s\n", name)
+	b.WriteString("type: Opaque\n")
+	if len(keys) == 0 {
+		b.WriteString("data: {}\n")
+		return b.String(), nil
+	}
+	b.WriteString("data:\n")
+	for _, key := range sortedNames(keys) {
+		if !k8sDataKeyPattern.MatchString(key) {
+			return "", fmt.Errorf("key '%s' is not a valid Secret data key", key)
+		}
+		// Quoted so names like TRUE or 1E3 stay strings
+		fmt.Fprintf(&b, "  %q: %s\n", key, base64.StdEncoding.EncodeToString([]byte(keys[key])))
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,157 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DotenvOptions controls how FormatDotenv renders a .env file.
+// The zero value (plus header lines) matches akm's historical output.
+type DotenvOptions struct {
+	Header    []string // comment lines, written as "# <line>"; empty for no header
+	NoQuote   bool     // write KEY=value; values that need quoting stay quoted
+	Sort      bool     // kept for compatibility: keys are always sorted by name
+	Timestamp bool     // add a "# Generated at" header line
+}
+
+// DotenvConfig is the optional `dotenv` section of akm.yaml / akm.json.
+type DotenvConfig struct {
+	Header    *string `yaml:"header,omitempty" json:"header,omitempty"` // "" disables the header
+	NoQuote   bool    `yaml:"no_quote,omitempty" json:"no_quote,omitempty"`
+	Sort      bool    `yaml:"sort,omitempty" json:"sort,omitempty"`
+	Timestamp bool    `yaml:"timestamp,omitempty" json:"timestamp,omitempty"`
+}
+
+// FormatDotenv renders keys as .env content.
+func FormatDotenv(keys map[string]string, opts DotenvOptions) string {
+	var lines []string
+	for _, h := range opts.Header {
+		lines = append(lines, "# "+h)
+	}
+	if opts.Timestamp {
+		lines = append(lines, "# Generated at "+time.Now().Format(time.RFC3339))
+	}
+	if len(lines) > 0 {
+		lines = append(lines, "")
+	}
+
+	// Always sorted, so regenerating the file gives a clean diff
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := keys[name]
+		if opts.NoQuote && !needsDotenvQuote(value) {
+			lines = append(lines, fmt.Sprintf("%s=%s", name, value))
+		} else {
+			lines = append(lines, fmt.Sprintf("%s=\"%s\"", name, EscapeDotenvValue(value)))
+		}
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// FormatPOSIX renders keys as `export KEY='value'` lines, sorted by name.
+// Unlike .env quoting, single quotes are literal to every POSIX shell, so the
+// output is safe for `set -a; . file` and `eval` whatever the value contains.
+func FormatPOSIX(keys map[string]string) string {
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "export %s=%s\n", name, QuotePOSIX(keys[name]))
+	}
+	return b.String()
+}
+
+// QuotePOSIX single-quotes value for a POSIX shell. Each embedded single
+// quote closes the quoting, adds an escaped quote and reopens it.
+func QuotePOSIX(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// UnescapeDotenvValue reverses EscapeDotenvValue.
+func UnescapeDotenvValue(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i == len(value)-1 {
+			b.WriteByte(value[i])
+			continue
+		}
+		i++
+		switch value[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		default: // \\ and \" (and any unknown escape) keep the escaped char
+			b.WriteByte(value[i])
+		}
+	}
+	return b.String()
+}
+
+// ParseEnvLine parses one NAME=value line as written by FormatDotenv or
+// FormatPOSIX: an optional "export " prefix, then a double-quoted (dotenv
+// escapes), single-quoted (literal) or bare value. Blank lines and comments
+// return ok=false.
+func ParseEnvLine(line string) (name, value string, ok bool, err error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", "", false, nil
+	}
+	line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+
+	name, raw, found := strings.Cut(line, "=")
+	if !found {
+		return "", "", false, fmt.Errorf("expected NAME=value")
+	}
+	name, raw = strings.TrimSpace(name), strings.TrimSpace(raw)
+
+	switch {
+	case len(raw) >= 2 && raw[0] == '"' && raw[len(raw)-1] == '"':
+		value = UnescapeDotenvValue(raw[1 : len(raw)-1])
+	case len(raw) >= 2 && raw[0] == '\'' && raw[len(raw)-1] == '\'':
+		value = strings.ReplaceAll(raw[1:len(raw)-1], `'\''`, "'")
+	case strings.HasPrefix(raw, "\"") || strings.HasPrefix(raw, "'"):
+		return name, "", true, fmt.Errorf("unterminated quote")
+	default:
+		value = raw
+	}
+	return name, value, true, nil
+}
+
+// LoadDotenvFile reads a dotenv file with ParseEnvLine. Later assignments of
+// the same name win, as with most dotenv loaders.
+func LoadDotenvFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string)
+	for i, line := range strings.Split(string(data), "\n") {
+		name, value, ok, err := ParseEnvLine(strings.TrimSuffix(line, "\r"))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, i+1, err)
+		}
+		if ok {
+			values[name] = value
+		}
+	}
+	return values, nil
+}
+
+// needsDotenvQuote reports whether an unquoted value would be misparsed.
+func needsDotenvQuote(value string) bool {
+	return value == "" || strings.ContainsAny(value, " \t\r\n\"'#\\$`")
+}
@@ -0,0 +1,71 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Calendar-ish units accepted by ParseRelativeDuration in addition to the
+// time.ParseDuration ones. A month is a fixed 30 days.
+var relativeDurationUnits = []struct {
+	suffix string
+	unit   time.Duration
+}{
+	{"mo", 30 * 24 * time.Hour},
+	{"w", 7 * 24 * time.Hour},
+	{"d", 24 * time.Hour},
+}
+
+// ParseRelativeDuration parses durations such as "90d", "2w", "3mo" as well as
+// anything time.ParseDuration accepts ("36h", "90m").
+func ParseRelativeDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(strings.ToLower(s))
+	for _, u := range relativeDurationUnits {
+		if !strings.HasSuffix(s, u.suffix) {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSuffix(s, u.suffix))
+		if err != nil {
+			break // e.g. "5ms" ends in "s", not ours; let the stdlib decide
+		}
+		return time.Duration(n) * u.unit, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration '%s': use e.g. 30d, 2w, 3mo, 36h", s)
+	}
+	return d, nil
+}
+
+// ExpiryFromNow returns now plus the relative duration s, rejecting results
+// that are not in the future.
+func ExpiryFromNow(s string) (time.Time, error) {
+	d, err := ParseRelativeDuration(s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if d <= 0 {
+		return time.Time{}, fmt.Errorf("expiry must be in the future, got '%s'", s)
+	}
+	return time.Now().Add(d), nil
+}
+
+// ParseExpiry accepts an absolute RFC3339 timestamp, a YYYY-MM-DD date (local
+// midnight) or a relative duration such as "90d", and rejects results that are
+// not in the future.
+func ParseExpiry(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	var t time.Time
+	var err error
+	if t, err = time.Parse(time.RFC3339, s); err != nil {
+		if t, err = time.ParseInLocation("2006-01-02", s, time.Local); err != nil {
+			return ExpiryFromNow(s)
+		}
+	}
+	if !t.After(time.Now()) {
+		return time.Time{}, fmt.Errorf("expiry must be in the future, got '%s'", s)
+	}
+	return t, nil
+}
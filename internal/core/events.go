@@ -0,0 +1,79 @@
+package core
+
+import (
+	"sync"
+	"time"
+)
+
+// Key event types published by KeyStorage.
+const (
+	EventKeyAdded      = "key.added"
+	EventKeyUpdated    = "key.updated"
+	EventKeyDeleted    = "key.deleted"
+	EventVerifyChanged = "verify.changed"
+)
+
+// KeyEvent describes a change to a key. It never carries a key value.
+type KeyEvent struct {
+	Type      string    `json:"type"`
+	Name      string    `json:"name"`
+	Action    string    `json:"action,omitempty"` // the audited action, e.g. "rotate"
+	Status    string    `json:"status,omitempty"` // verification status for verify.changed
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// keyEventType maps an audited mutation to the event it publishes.
+func keyEventType(action string) string {
+	switch action {
+	case "add":
+		return EventKeyAdded
+	case "delete", "prune":
+		return EventKeyDeleted
+	}
+	return EventKeyUpdated
+}
+
+// eventObservers holds the callbacks registered with OnChange.
+type eventObservers struct {
+	mu     sync.RWMutex
+	nextID int
+	fns    map[int]func(KeyEvent)
+}
+
+// OnChange registers fn to be called after every committed key mutation and
+// verification status change in this process. fn runs synchronously, often
+// with storage locks held, so it must not block or call back into storage.
+// The returned function unregisters it.
+func (s *KeyStorage) OnChange(fn func(KeyEvent)) (unsubscribe func()) {
+	o := &s.observers
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.fns == nil {
+		o.fns = make(map[int]func(KeyEvent))
+	}
+	id := o.nextID
+	o.nextID++
+	o.fns[id] = fn
+	return func() {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		delete(o.fns, id)
+	}
+}
+
+// publish notifies observers of ev.
+func (s *KeyStorage) publish(ev KeyEvent) {
+	if ev.Timestamp.IsZero() {
+		ev.Timestamp = time.Now()
+	}
+	s.observers.mu.RLock()
+	defer s.observers.mu.RUnlock()
+	for _, fn := range s.observers.fns {
+		fn(ev)
+	}
+}
+
+// publishMutation publishes the event for an audited mutation of name.
+func (s *KeyStorage) publishMutation(name, action string) {
+	s.publish(KeyEvent{Type: keyEventType(action), Name: name, Action: action})
+}
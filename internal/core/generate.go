@@ -0,0 +1,66 @@
+package core
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// SecretFormats lists the encodings GenerateSecret supports.
+var SecretFormats = []string{"base64url", "base64", "hex", "alnum"}
+
+const (
+	// DefaultSecretLength is the default GenerateSecret length.
+	DefaultSecretLength = 32
+	// DefaultSecretFormat is the default GenerateSecret encoding: URL- and
+	// env-safe, without padding.
+	DefaultSecretFormat = "base64url"
+
+	minSecretLength = 16
+	maxSecretLength = 1024
+)
+
+const alnumAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// GenerateSecret returns a cryptographically random secret. For hex, base64
+// and base64url, length is the number of random bytes before encoding (as in
+// `openssl rand -hex 32`); for alnum it is the number of characters.
+func GenerateSecret(length int, format string) (string, error) {
+	if length < minSecretLength || length > maxSecretLength {
+		return "", fmt.Errorf("length must be between %d and %d", minSecretLength, maxSecretLength)
+	}
+
+	if format == "alnum" {
+		var b strings.Builder
+		b.Grow(length)
+		limit := big.NewInt(int64(len(alnumAlphabet)))
+		for range length {
+			n, err := rand.Int(rand.Reader, limit)
+			if err != nil {
+				return "", fmt.Errorf("failed to generate secret: %w", err)
+			}
+			b.WriteByte(alnumAlphabet[n.Int64()])
+		}
+		return b.String(), nil
+	}
+
+	var encode func([]byte) string
+	switch format {
+	case "base64url":
+		encode = base64.RawURLEncoding.EncodeToString
+	case "base64":
+		encode = base64.StdEncoding.EncodeToString
+	case "hex":
+		encode = hex.EncodeToString
+	default:
+		return "", fmt.Errorf("unknown format '%s' (valid: %s)", format, strings.Join(SecretFormats, ", "))
+	}
+	raw := make([]byte, length)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return encode(raw), nil
+}
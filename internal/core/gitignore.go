@@ -0,0 +1,139 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FindGitRoot walks up from dir looking for a .git directory (or the .git
+// file of a worktree/submodule) and returns the repository root.
+func FindGitRoot(dir string) (string, bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// UnignoredInRepo reports whether the file at path sits in a git repository
+// without being ignored, i.e. would show up as committable. It returns the
+// repository root when it does.
+//
+// Matching is deliberately minimal: .gitignore files from the repository root
+// down to the file's directory plus .git/info/exclude, with basename globs,
+// anchored paths, "**/" prefixes and "!" negation. Directory patterns and
+// ignores inherited from parent directories are not evaluated.
+func UnignoredInRepo(path string) (string, bool) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+	root, ok := FindGitRoot(filepath.Dir(abs))
+	if !ok {
+		return "", false
+	}
+
+	// Pattern files in increasing precedence: exclude, then root to leaf
+	files := []string{filepath.Join(root, ".git", "info", "exclude")}
+	rel, _ := filepath.Rel(root, filepath.Dir(abs))
+	dir := root
+	files = append(files, filepath.Join(dir, ".gitignore"))
+	if rel != "." {
+		for _, part := range strings.Split(rel, string(filepath.Separator)) {
+			dir = filepath.Join(dir, part)
+			files = append(files, filepath.Join(dir, ".gitignore"))
+		}
+	}
+
+	ignored := false
+	for i, file := range files {
+		base := filepath.Dir(file)
+		if i == 0 {
+			base = root // info/exclude patterns are relative to the root
+		}
+		fileRel, err := filepath.Rel(base, abs)
+		if err != nil {
+			continue
+		}
+		if matched, negated := matchIgnoreFile(file, filepath.ToSlash(fileRel)); matched {
+			ignored = !negated
+		}
+	}
+	if ignored {
+		return "", false
+	}
+	return root, true
+}
+
+// matchIgnoreFile applies one ignore file to rel (slash-separated, relative to
+// the file's directory). It reports whether the last matching pattern matched
+// and whether that pattern was a negation.
+func matchIgnoreFile(file, rel string) (matched, negated bool) {
+	f, err := os.Open(file)
+	if err != nil {
+		return false, false
+	}
+	defer f.Close()
+
+	name := rel[strings.LastIndex(rel, "/")+1:]
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		neg := strings.HasPrefix(line, "!")
+		line = strings.TrimPrefix(line, "!")
+		if strings.HasSuffix(line, "/") {
+			continue // directory-only pattern; we only check files
+		}
+		line = strings.TrimPrefix(line, "**/")
+
+		var ok bool
+		if strings.Contains(line, "/") {
+			ok, _ = filepath.Match(strings.TrimPrefix(line, "/"), rel)
+		} else {
+			ok, _ = filepath.Match(line, name)
+		}
+		if ok {
+			matched, negated = true, neg
+		}
+	}
+	return matched, negated
+}
+
+// AppendGitignore adds entry on its own line to root/.gitignore, creating it
+// if needed.
+func AppendGitignore(root, entry string) error {
+	file := filepath.Join(root, ".gitignore")
+	existing, err := os.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	var b strings.Builder
+	if len(existing) > 0 && !strings.HasSuffix(string(existing), "\n") {
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "%s\n", entry)
+
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString(b.String())
+	return err
+}
@@ -0,0 +1,70 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ImportFormat picks the import format for path from its extension: ".json"
+// is JSON, anything else (.env, .env.local, ...) is dotenv.
+func ImportFormat(path string) string {
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return "json"
+	}
+	return "env"
+}
+
+// ParseImportFile reads keys to import from a dotenv file (NAME=value lines,
+// parsed with ParseEnvLine) or a JSON array of {name, value, provider,
+// description}. An empty format is detected with ImportFormat.
+func ParseImportFile(path, format string) ([]BatchKey, error) {
+	if format == "" {
+		format = ImportFormat(path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case "env":
+		var entries []BatchKey
+		for i, line := range strings.Split(string(data), "\n") {
+			name, value, ok, err := ParseEnvLine(strings.TrimSuffix(line, "\r"))
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, i+1, err)
+			}
+			if ok {
+				entries = append(entries, BatchKey{Name: name, Value: value})
+			}
+		}
+		return entries, nil
+
+	case "json":
+		var records []struct {
+			Name        string `json:"name"`
+			Value       string `json:"value"`
+			Provider    string `json:"provider"`
+			Description string `json:"description"`
+		}
+		if err := json.Unmarshal(data, &records); err != nil {
+			return nil, fmt.Errorf("%s: expected a JSON array of {name, value, provider, description}: %w", path, err)
+		}
+		entries := make([]BatchKey, 0, len(records))
+		for _, r := range records {
+			entries = append(entries, BatchKey{
+				Name:        r.Name,
+				Value:       r.Value,
+				Provider:    strings.TrimSpace(r.Provider),
+				Description: r.Description,
+			})
+		}
+		return entries, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported import format: %s (use env or json)", format)
+	}
+}
@@ -0,0 +1,96 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zalando/go-keyring"
+)
+
+// Keychain retry defaults: a few quick attempts ride out contention (e.g. the
+// login keychain still unlocking) without stalling a genuine failure for long.
+const (
+	defaultKeychainAttempts = 3
+	keychainBackoff         = 100 * time.Millisecond
+)
+
+// keychainStore is the subset of go-keyring used by akm. It is a variable so
+// alternative backends can be swapped in.
+type keychainStore interface {
+	Get(service, user string) (string, error)
+	Set(service, user, password string) error
+	Delete(service, user string) error
+}
+
+type systemKeychain struct{}
+
+func (systemKeychain) Get(service, user string) (string, error) {
+	return keyring.Get(service, user)
+}
+
+func (systemKeychain) Set(service, user, password string) error {
+	return keyring.Set(service, user, password)
+}
+
+func (systemKeychain) Delete(service, user string) error {
+	return keyring.Delete(service, user)
+}
+
+var keychain keychainStore = systemKeychain{}
+
+// keychainAttempts reads AKM_KEYCHAIN_RETRIES, the total number of attempts
+// per keychain operation (default 3, minimum 1).
+func keychainAttempts() int {
+	if n, err := strconv.Atoi(strings.TrimSpace(os.Getenv("AKM_KEYCHAIN_RETRIES"))); err == nil && n >= 1 {
+		return n
+	}
+	return defaultKeychainAttempts
+}
+
+// withKeychainRetry runs op with bounded exponential backoff. keyring.ErrNotFound
+// is an answer, not a transient failure, so it is returned immediately.
+func withKeychainRetry(op func() error) error {
+	attempts := keychainAttempts()
+	var err error
+	for i := 0; i < attempts; i++ {
+		if err = op(); err == nil || errors.Is(err, keyring.ErrNotFound) {
+			return err
+		}
+		if i < attempts-1 {
+			time.Sleep(keychainBackoff << i)
+		}
+	}
+	if attempts > 1 {
+		return fmt.Errorf("%w (after %d attempts)", err, attempts)
+	}
+	return err
+}
+
+// keychainGet reads account from the akm keychain service.
+func keychainGet(account string) (string, error) {
+	var value string
+	err := withKeychainRetry(func() error {
+		var err error
+		value, err = keychain.Get(ServiceName, account)
+		return err
+	})
+	return value, err
+}
+
+// keychainSet writes account in the akm keychain service.
+func keychainSet(account, value string) error {
+	return withKeychainRetry(func() error {
+		return keychain.Set(ServiceName, account, value)
+	})
+}
+
+// keychainDelete removes account from the akm keychain service.
+func keychainDelete(account string) error {
+	return withKeychainRetry(func() error {
+		return keychain.Delete(ServiceName, account)
+	})
+}
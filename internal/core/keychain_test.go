@@ -0,0 +1,130 @@
+package core
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/fernet/fernet-go"
+	"github.com/zalando/go-keyring"
+)
+
+// flakyKeychain is an in-memory keychainStore whose next failures calls fail
+// with a transient error.
+type flakyKeychain struct {
+	failures int
+	calls    int
+	values   map[string]string
+}
+
+func (f *flakyKeychain) fail() error {
+	f.calls++
+	if f.failures > 0 {
+		f.failures--
+		return errors.New("keychain is locked")
+	}
+	return nil
+}
+
+func (f *flakyKeychain) Get(service, user string) (string, error) {
+	if err := f.fail(); err != nil {
+		return "", err
+	}
+	v, ok := f.values[service+"/"+user]
+	if !ok {
+		return "", keyring.ErrNotFound
+	}
+	return v, nil
+}
+
+func (f *flakyKeychain) Set(service, user, password string) error {
+	if err := f.fail(); err != nil {
+		return err
+	}
+	f.values[service+"/"+user] = password
+	return nil
+}
+
+func (f *flakyKeychain) Delete(service, user string) error {
+	if err := f.fail(); err != nil {
+		return err
+	}
+	delete(f.values, service+"/"+user)
+	return nil
+}
+
+// useFlakyKeychain swaps keychain for a fake holding values.
+func useFlakyKeychain(t *testing.T, failures int, values map[string]string) *flakyKeychain {
+	t.Helper()
+	if values == nil {
+		values = make(map[string]string)
+	}
+	fake := &flakyKeychain{failures: failures, values: values}
+	orig := keychain
+	keychain = fake
+	t.Cleanup(func() { keychain = orig })
+	return fake
+}
+
+func TestKeychainGetRetriesTransientFailure(t *testing.T) {
+	t.Setenv("AKM_KEYCHAIN_RETRIES", "")
+	fake := useFlakyKeychain(t, 1, map[string]string{ServiceName + "/acct": "secret"})
+
+	got, err := keychainGet("acct")
+	if err != nil || got != "secret" {
+		t.Fatalf("keychainGet = %q, %v; want secret", got, err)
+	}
+	if fake.calls != 2 {
+		t.Errorf("calls = %d, want 2", fake.calls)
+	}
+}
+
+func TestKeychainGetNotFoundIsNotRetried(t *testing.T) {
+	t.Setenv("AKM_KEYCHAIN_RETRIES", "")
+	fake := useFlakyKeychain(t, 0, nil)
+
+	if _, err := keychainGet("missing"); !errors.Is(err, keyring.ErrNotFound) {
+		t.Fatalf("keychainGet error = %v, want ErrNotFound", err)
+	}
+	if fake.calls != 1 {
+		t.Errorf("calls = %d, want 1", fake.calls)
+	}
+}
+
+func TestKeychainGetGivesUpAfterRetries(t *testing.T) {
+	t.Setenv("AKM_KEYCHAIN_RETRIES", "2")
+	fake := useFlakyKeychain(t, 5, nil)
+
+	_, err := keychainGet("acct")
+	if err == nil || !strings.Contains(err.Error(), "after 2 attempts") {
+		t.Fatalf("keychainGet error = %v, want failure after 2 attempts", err)
+	}
+	if fake.calls != 2 {
+		t.Errorf("calls = %d, want 2", fake.calls)
+	}
+}
+
+// Initialize rides out a transient keychain failure and loads the existing
+// master key instead of failing or generating a new one.
+func TestInitializeRetriesKeychain(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("AKM_MASTER_KEY", "")
+	t.Setenv("AKM_MASTER_KEY_FILE", "")
+	t.Setenv("AKM_IDENTITY_FILE", "")
+	t.Setenv("AKM_KEYCHAIN_RETRIES", "")
+	var key fernet.Key
+	if err := key.Generate(); err != nil {
+		t.Fatal(err)
+	}
+	stored := base64.StdEncoding.EncodeToString([]byte(key.Encode()))
+	useFlakyKeychain(t, 1, map[string]string{ServiceName + "/" + MasterKeyAccount: stored})
+
+	k := &KeyEncryption{}
+	if err := k.Initialize(); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	if k.masterKey == nil || k.masterKey.Encode() != key.Encode() {
+		t.Error("Initialize did not load the stored master key")
+	}
+}
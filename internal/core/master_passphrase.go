@@ -0,0 +1,305 @@
+package core
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fernet/fernet-go"
+	"github.com/zalando/go-keyring"
+	"golang.org/x/crypto/argon2"
+)
+
+// Passphrase mode keeps the master key out of the keychain: master.enc holds
+// it (and the previous key during a rotation) encrypted under a key derived
+// from a passphrase with Argon2id. It is for headless machines and containers
+// without a keychain; the keychain stays the default elsewhere.
+
+const (
+	masterKeyFileName = "master.enc"
+	// Argon2id parameters for new envelopes (RFC 9106's memory-constrained
+	// recommendation). They are stored in the file, so they can be raised
+	// later without breaking existing ones.
+	argonTime     = 3
+	argonMemory   = 64 * 1024 // KiB
+	argonThreads  = 4
+	argonSaltSize = 16
+)
+
+// masterEnvelope is the on-disk format of master.enc.
+type masterEnvelope struct {
+	Version  int    `json:"version"`
+	KDF      string `json:"kdf"`
+	Time     uint32 `json:"time"`
+	Memory   uint32 `json:"memory"`
+	Threads  uint8  `json:"threads"`
+	Salt     string `json:"salt"`
+	Master   string `json:"master"`             // Fernet token of the encoded master key
+	Previous string `json:"previous,omitempty"` // same for the decrypt-only previous key
+}
+
+// PassphrasePrompt asks for the master passphrase when AKM_PASSPHRASE is
+// unset. The CLI installs a terminal prompt; without one, passphrase mode
+// needs the environment variable.
+var PassphrasePrompt func() (string, error)
+
+// MasterKeyFile returns the path of master.enc. Like the keychain entry it is
+// shared by all profiles.
+func MasterKeyFile() (string, error) {
+	dataDir, err := sharedDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, masterKeyFileName), nil
+}
+
+// masterPassphrase returns AKM_PASSPHRASE, or asks PassphrasePrompt.
+func masterPassphrase() (string, error) {
+	if passphrase := os.Getenv("AKM_PASSPHRASE"); passphrase != "" {
+		return passphrase, nil
+	}
+	if PassphrasePrompt == nil {
+		return "", fmt.Errorf("master key is passphrase-protected: set AKM_PASSPHRASE")
+	}
+	return PassphrasePrompt()
+}
+
+// newMasterEnvelope returns an empty envelope with fresh salt and the current
+// Argon2id parameters.
+func newMasterEnvelope() (*masterEnvelope, error) {
+	salt := make([]byte, argonSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	return &masterEnvelope{
+		Version: 1,
+		KDF:     "argon2id",
+		Time:    argonTime,
+		Memory:  argonMemory,
+		Threads: argonThreads,
+		Salt:    base64.StdEncoding.EncodeToString(salt),
+	}, nil
+}
+
+// readMasterEnvelope loads master.enc from path.
+func readMasterEnvelope(path string) (*masterEnvelope, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var env masterEnvelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", masterKeyFileName, err)
+	}
+	if env.Version != 1 || env.KDF != "argon2id" {
+		return nil, fmt.Errorf("unsupported %s (version %d, kdf %q)", masterKeyFileName, env.Version, env.KDF)
+	}
+	return &env, nil
+}
+
+// kek derives the key-encryption key for passphrase.
+func (e *masterEnvelope) kek(passphrase string) (*fernet.Key, error) {
+	salt, err := base64.StdEncoding.DecodeString(e.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid salt in %s: %w", masterKeyFileName, err)
+	}
+	var key fernet.Key
+	copy(key[:], argon2.IDKey([]byte(passphrase), salt, e.Time, e.Memory, e.Threads, 32))
+	return &key, nil
+}
+
+// unlock derives the KEK and decrypts the master and previous keys.
+func (e *masterEnvelope) unlock(passphrase string) (kek, master, previous *fernet.Key, err error) {
+	if kek, err = e.kek(passphrase); err != nil {
+		return nil, nil, nil, err
+	}
+	open := func(token string) (*fernet.Key, error) {
+		plain := fernet.VerifyAndDecrypt([]byte(token), 0, []*fernet.Key{kek})
+		if plain == nil {
+			return nil, ErrBadPassphrase
+		}
+		return fernet.DecodeKey(string(plain))
+	}
+	if master, err = open(e.Master); err != nil {
+		return nil, nil, nil, err
+	}
+	if e.Previous != "" {
+		if previous, err = open(e.Previous); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	return kek, master, previous, nil
+}
+
+// seal encrypts master and previous under kek into a copy of e and writes it
+// atomically to path.
+func (e *masterEnvelope) seal(path string, kek, master, previous *fernet.Key) error {
+	env := *e
+	token, err := fernet.EncryptAndSign([]byte(master.Encode()), kek)
+	if err != nil {
+		return fmt.Errorf("encryption failed: %w", err)
+	}
+	env.Master, env.Previous = string(token), ""
+	if previous != nil {
+		token, err := fernet.EncryptAndSign([]byte(previous.Encode()), kek)
+		if err != nil {
+			return fmt.Errorf("encryption failed: %w", err)
+		}
+		env.Previous = string(token)
+	}
+
+	data, err := json.MarshalIndent(env, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+	tempFile := path + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0600); err != nil {
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := os.Rename(tempFile, path); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to replace %s: %w", masterKeyFileName, err)
+	}
+	return nil
+}
+
+// loadPassphraseFile unlocks master.enc at path into k. Callers hold k.mu.
+func (k *KeyEncryption) loadPassphraseFile(path string) error {
+	env, err := readMasterEnvelope(path)
+	if err != nil {
+		return err
+	}
+	passphrase, err := masterPassphrase()
+	if err != nil {
+		return err
+	}
+	kek, master, previous, err := env.unlock(passphrase)
+	if err != nil {
+		return fmt.Errorf("failed to unlock %s: %w", masterKeyFileName, err)
+	}
+	k.masterKey, k.previousKey = master, previous
+	k.passphraseFile, k.envelope, k.kek = path, env, kek
+	return nil
+}
+
+// persistPassphraseLocked writes master and previous to master.enc when k is
+// in passphrase mode, reporting whether it was. Callers hold k.mu.
+func (k *KeyEncryption) persistPassphraseLocked(master, previous *fernet.Key) (bool, error) {
+	if k.passphraseFile == "" {
+		return false, nil
+	}
+	if err := k.envelope.seal(k.passphraseFile, k.kek, master, previous); err != nil {
+		return true, fmt.Errorf("failed to store master key in %s: %w", masterKeyFileName, err)
+	}
+	return true, nil
+}
+
+// PassphraseMode reports whether the master key is stored in master.enc.
+func (k *KeyEncryption) PassphraseMode() bool {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.passphraseFile != ""
+}
+
+// SetPassphrase stores the loaded master key (and previous key, if any) in
+// master.enc under passphrase, switching to passphrase mode; in passphrase
+// mode it changes the passphrase. The keychain entries are left alone:
+// master.enc takes precedence once it exists.
+func (k *KeyEncryption) SetPassphrase(passphrase string) error {
+	if passphrase == "" {
+		return fmt.Errorf("passphrase must not be empty")
+	}
+	path, err := MasterKeyFile()
+	if err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.masterKey == nil {
+		return fmt.Errorf("encryption system not initialized")
+	}
+	if k.envSource != "" {
+		return fmt.Errorf("master key is supplied by %s; unset it to manage passphrase mode", k.envSource)
+	}
+	if k.identityFile != "" {
+		return fmt.Errorf("master key is unwrapped from a team identity; keep using the identity")
+	}
+
+	env, err := newMasterEnvelope()
+	if err != nil {
+		return err
+	}
+	kek, err := env.kek(passphrase)
+	if err != nil {
+		return err
+	}
+	if err := env.seal(path, kek, k.masterKey, k.previousKey); err != nil {
+		return err
+	}
+	k.passphraseFile, k.envelope, k.kek = path, env, kek
+	return nil
+}
+
+// CreatePassphraseMasterKey generates a new master key straight into
+// master.enc, for machines that have no keychain to create one in. It refuses
+// when master.enc or any keys already exist, since those belong to another key.
+func CreatePassphraseMasterKey(passphrase string) error {
+	if passphrase == "" {
+		return fmt.Errorf("passphrase must not be empty")
+	}
+	path, err := MasterKeyFile()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%s already exists", path)
+	}
+	dataDir, err := DataDir()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "keys.json")); err == nil {
+		return fmt.Errorf("keys already exist under another master key; import it first (AKM_MASTER_KEY or master-key import), then set a passphrase")
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	var master fernet.Key
+	if err := master.Generate(); err != nil {
+		return fmt.Errorf("failed to generate master key: %w", err)
+	}
+	env, err := newMasterEnvelope()
+	if err != nil {
+		return err
+	}
+	kek, err := env.kek(passphrase)
+	if err != nil {
+		return err
+	}
+	return env.seal(path, kek, &master, nil)
+}
+
+// RemoveKeychainMasterKey deletes the keychain copies of the master and
+// previous keys once master.enc holds them, so the passphrase is the only way
+// in. It refuses outside passphrase mode, where that would lose the key.
+func (k *KeyEncryption) RemoveKeychainMasterKey() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.passphraseFile == "" {
+		return fmt.Errorf("master key is not stored in %s", masterKeyFileName)
+	}
+	for _, account := range []string{MasterKeyAccount, PreviousMasterKeyAccount} {
+		if err := keychainDelete(account); err != nil && !errors.Is(err, keyring.ErrNotFound) {
+			return fmt.Errorf("failed to delete %s from keychain: %w", account, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,381 @@
+package core
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fernet/fernet-go"
+)
+
+// Team mode wraps the data-encryption key (the Fernet master key) for each
+// member's X25519 public key, so members decrypt the shared vault with their
+// own private key instead of a shared master secret. Keys use age's encoding:
+// recipients are "age1..." and identities "AGE-SECRET-KEY-1...", so keys made
+// by age-keygen work as well.
+const (
+	recipientHRP = "age"
+	identityHRP  = "age-secret-key-"
+	memberWrapV1 = "akm member wrap v1"
+)
+
+// errNotMember means the identity has no wrapped key in the vault.
+var errNotMember = errors.New("identity is not a member of this vault")
+
+// Member is one wrapped copy of the master key.
+type Member struct {
+	Name       string    `json:"name"`
+	Recipient  string    `json:"recipient"`
+	WrappedKey string    `json:"wrapped_key"` // base64(ephemeral pub || nonce || AES-GCM ciphertext)
+	AddedAt    time.Time `json:"added_at"`
+}
+
+// membersFileData is the members.json format. It is plain JSON: it must be
+// readable before the master key is known.
+type membersFileData struct {
+	Version int       `json:"version"`
+	Members []*Member `json:"members"`
+}
+
+// membersMu serializes read-modify-write of members.json within a process.
+var membersMu sync.Mutex
+
+// The members wrap the master key, which every profile shares, so the file
+// lives in the default data directory whatever profile is active.
+func (s *KeyStorage) membersFile() string {
+	if dir, err := sharedDataDir(); err == nil {
+		return filepath.Join(dir, "members.json")
+	}
+	return filepath.Join(s.dataDir, "members.json")
+}
+
+// DefaultIdentityFile is where `akm member keygen` writes by default.
+func DefaultIdentityFile() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".apikey-manager", "identity.txt"), nil
+}
+
+// GenerateIdentity creates an X25519 key pair, returning the identity
+// (private) and recipient (public) strings.
+func GenerateIdentity() (identity, recipient string, err error) {
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	identity, err = bech32Encode(identityHRP, priv.Bytes())
+	if err != nil {
+		return "", "", err
+	}
+	recipient, err = bech32Encode(recipientHRP, priv.PublicKey().Bytes())
+	if err != nil {
+		return "", "", err
+	}
+	return strings.ToUpper(identity), recipient, nil
+}
+
+// ParseRecipient decodes an "age1..." X25519 public key.
+func ParseRecipient(s string) (*ecdh.PublicKey, error) {
+	hrp, data, err := bech32Decode(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient: %w", err)
+	}
+	if hrp != recipientHRP {
+		return nil, fmt.Errorf("invalid recipient: expected age1... public key")
+	}
+	return ecdh.X25519().NewPublicKey(data)
+}
+
+// parseIdentity decodes an "AGE-SECRET-KEY-1..." X25519 private key.
+func parseIdentity(s string) (*ecdh.PrivateKey, error) {
+	hrp, data, err := bech32Decode(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("invalid identity: %w", err)
+	}
+	if hrp != identityHRP {
+		return nil, fmt.Errorf("invalid identity: expected AGE-SECRET-KEY-1... private key")
+	}
+	return ecdh.X25519().NewPrivateKey(data)
+}
+
+// LoadIdentityFile reads the first identity in an age-keygen style file
+// (comment lines start with #).
+func LoadIdentityFile(path string) (*ecdh.PrivateKey, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		return parseIdentity(line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("no identity found in %s", path)
+}
+
+// wrapKeyFor encrypts key for recipient: an ephemeral X25519 exchange feeds
+// HKDF-SHA256, whose output keys AES-256-GCM.
+func wrapKeyFor(recipient *ecdh.PublicKey, key []byte) (string, error) {
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", err
+	}
+	shared, err := ephemeral.ECDH(recipient)
+	if err != nil {
+		return "", err
+	}
+	aead, err := memberAEAD(shared, ephemeral.PublicKey(), recipient)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	out := append(ephemeral.PublicKey().Bytes(), nonce...)
+	out = aead.Seal(out, nonce, key, nil)
+	return base64.StdEncoding.EncodeToString(out), nil
+}
+
+// unwrapKey reverses wrapKeyFor with the member's private key.
+func unwrapKey(identity *ecdh.PrivateKey, wrapped string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(wrapped)
+	if err != nil || len(data) < 32 {
+		return nil, errors.New("malformed wrapped key")
+	}
+	ephemeral, err := ecdh.X25519().NewPublicKey(data[:32])
+	if err != nil {
+		return nil, err
+	}
+	shared, err := identity.ECDH(ephemeral)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := memberAEAD(shared, ephemeral, identity.PublicKey())
+	if err != nil {
+		return nil, err
+	}
+	rest := data[32:]
+	if len(rest) < aead.NonceSize() {
+		return nil, errors.New("malformed wrapped key")
+	}
+	return aead.Open(nil, rest[:aead.NonceSize()], rest[aead.NonceSize():], nil)
+}
+
+// memberAEAD derives the wrapping cipher from an X25519 shared secret. The
+// salt binds the ephemeral and recipient public keys.
+func memberAEAD(shared []byte, ephemeral, recipient *ecdh.PublicKey) (cipher.AEAD, error) {
+	salt := append(ephemeral.Bytes(), recipient.Bytes()...)
+	key, err := hkdf.Key(sha256.New, shared, salt, memberWrapV1, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// loadMembersFile reads members.json; a missing file means no members.
+func loadMembersFile(path string) ([]*Member, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var file membersFileData
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid members file: %w", err)
+	}
+	return file.Members, nil
+}
+
+// saveMembersLocked atomically writes members.json. Caller must hold membersMu.
+func (s *KeyStorage) saveMembersLocked(members []*Member) error {
+	data, err := json.MarshalIndent(membersFileData{Version: 1, Members: members}, "", "  ")
+	if err != nil {
+		return err
+	}
+	tempFile := s.membersFile() + ".tmp"
+	if err := os.WriteFile(tempFile, data, s.filePerm); err != nil {
+		return err
+	}
+	return os.Rename(tempFile, s.membersFile())
+}
+
+// ListMembers returns the members the master key is wrapped for.
+func (s *KeyStorage) ListMembers() ([]*Member, error) {
+	membersMu.Lock()
+	defer membersMu.Unlock()
+	return loadMembersFile(s.membersFile())
+}
+
+// AddMember wraps the current master key for recipient. It needs the master
+// key, so only someone who can already decrypt the vault can add members.
+func (s *KeyStorage) AddMember(name, recipient string) (*Member, error) {
+	pub, err := ParseRecipient(recipient)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		return nil, errors.New("member name is required")
+	}
+
+	masterKey, err := s.crypto.ExportMasterKey()
+	if err != nil {
+		return nil, err
+	}
+
+	membersMu.Lock()
+	defer membersMu.Unlock()
+
+	members, err := loadMembersFile(s.membersFile())
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range members {
+		if m.Name == name || m.Recipient == recipient {
+			return nil, fmt.Errorf("member '%s' already exists", m.Name)
+		}
+	}
+
+	wrapped, err := wrapKeyFor(pub, []byte(masterKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap master key: %w", err)
+	}
+	member := &Member{Name: name, Recipient: recipient, WrappedKey: wrapped, AddedAt: time.Now()}
+	if err := s.saveMembersLocked(append(members, member)); err != nil {
+		return nil, err
+	}
+	s.logUsage("member:"+name, "member-add", "system")
+	return member, nil
+}
+
+// RemoveMember drops a member's wrapped key, matched by name or recipient.
+// The member may have kept the master key, so full revocation also needs
+// RotateMasterKey, which re-wraps the new key for the remaining members only.
+func (s *KeyStorage) RemoveMember(nameOrRecipient string) (*Member, error) {
+	membersMu.Lock()
+	defer membersMu.Unlock()
+
+	members, err := loadMembersFile(s.membersFile())
+	if err != nil {
+		return nil, err
+	}
+	var removed *Member
+	kept := members[:0]
+	for _, m := range members {
+		if removed == nil && (m.Name == nameOrRecipient || m.Recipient == nameOrRecipient) {
+			removed = m
+			continue
+		}
+		kept = append(kept, m)
+	}
+	if removed == nil {
+		return nil, fmt.Errorf("member '%s' not found", nameOrRecipient)
+	}
+	if err := s.saveMembersLocked(kept); err != nil {
+		return nil, err
+	}
+	s.logUsage("member:"+removed.Name, "member-remove", "system")
+	return removed, nil
+}
+
+// rewrapMembers wraps newKey for every current member, replacing their old
+// wrapped keys. Nothing is written unless every member could be re-wrapped.
+func (s *KeyStorage) rewrapMembers(newKey *fernet.Key) error {
+	membersMu.Lock()
+	defer membersMu.Unlock()
+
+	members, err := loadMembersFile(s.membersFile())
+	if err != nil || len(members) == 0 {
+		return err
+	}
+	rewrapped := make([]*Member, 0, len(members))
+	for _, m := range members {
+		pub, err := ParseRecipient(m.Recipient)
+		if err != nil {
+			return fmt.Errorf("member '%s': %w", m.Name, err)
+		}
+		wrapped, err := wrapKeyFor(pub, []byte(newKey.Encode()))
+		if err != nil {
+			return fmt.Errorf("member '%s': %w", m.Name, err)
+		}
+		copied := *m
+		copied.WrappedKey = wrapped
+		rewrapped = append(rewrapped, &copied)
+	}
+	return s.saveMembersLocked(rewrapped)
+}
+
+// identityFileFromEnv returns the identity file to unlock with: AKM_IDENTITY_FILE
+// when set (explicit), otherwise the default path if it exists.
+func identityFileFromEnv() (path string, explicit bool) {
+	if path := strings.TrimSpace(os.Getenv("AKM_IDENTITY_FILE")); path != "" {
+		return path, true
+	}
+	path, err := DefaultIdentityFile()
+	if err != nil {
+		return "", false
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", false
+	}
+	return path, false
+}
+
+// masterKeyFromIdentity unwraps the master key from the default vault's
+// members.json using the identity at identityPath.
+func masterKeyFromIdentity(identityPath string) (*fernet.Key, error) {
+	identity, err := LoadIdentityFile(identityPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load identity: %w", err)
+	}
+	dataDir, err := sharedDataDir()
+	if err != nil {
+		return nil, err
+	}
+	members, err := loadMembersFile(filepath.Join(dataDir, "members.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	self := identity.PublicKey().Bytes()
+	for _, m := range members {
+		pub, err := ParseRecipient(m.Recipient)
+		if err != nil || string(pub.Bytes()) != string(self) {
+			continue
+		}
+		raw, err := unwrapKey(identity, m.WrappedKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unwrap master key for member '%s': %w", m.Name, err)
+		}
+		return fernet.DecodeKey(string(raw))
+	}
+	return nil, errNotMember
+}
@@ -0,0 +1,107 @@
+package core
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/baobao/akm-go/internal/models"
+	"github.com/fernet/fernet-go"
+)
+
+const (
+	// passphrasePrefix marks a value wrapped by wrapWithPassphrase (format v1).
+	passphrasePrefix = "pp1:"
+	// passphraseIterations is the PBKDF2-SHA256 work factor.
+	passphraseIterations = 600000
+	passphraseSaltSize   = 16
+)
+
+var (
+	// ErrPassphraseRequired is returned when reading a passphrase-protected key without one.
+	ErrPassphraseRequired = errors.New("key is passphrase-protected: passphrase required")
+	// ErrBadPassphrase is returned when the passphrase does not unlock the key.
+	ErrBadPassphrase = errors.New("incorrect passphrase")
+)
+
+// WithPassphrase protects the key value with a passphrase in addition to the
+// master key; the passphrase is needed for every later read.
+func WithPassphrase(passphrase string) KeyOption {
+	return func(k *models.APIKey) {
+		k.Passphrase = passphrase
+	}
+}
+
+// passphraseKey derives the Fernet key for passphrase and salt.
+func passphraseKey(passphrase string, salt []byte) (*fernet.Key, error) {
+	raw, err := pbkdf2.Key(sha256.New, passphrase, salt, passphraseIterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	var key fernet.Key
+	copy(key[:], raw)
+	return &key, nil
+}
+
+// wrapWithPassphrase encrypts value under a passphrase-derived key, producing
+// "pp1:<salt>:<token>" which is then encrypted again with the master key.
+func wrapWithPassphrase(value, passphrase string) (string, error) {
+	salt := make([]byte, passphraseSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+	key, err := passphraseKey(passphrase, salt)
+	if err != nil {
+		return "", fmt.Errorf("failed to derive passphrase key: %w", err)
+	}
+	token, err := fernet.EncryptAndSign([]byte(value), key)
+	if err != nil {
+		return "", fmt.Errorf("encryption failed: %w", err)
+	}
+	return passphrasePrefix + base64.StdEncoding.EncodeToString(salt) + ":" + string(token), nil
+}
+
+// unwrapWithPassphrase reverses wrapWithPassphrase.
+func unwrapWithPassphrase(wrapped, passphrase string) (string, error) {
+	if passphrase == "" {
+		return "", ErrPassphraseRequired
+	}
+	rest, ok := strings.CutPrefix(wrapped, passphrasePrefix)
+	if !ok {
+		return "", fmt.Errorf("unknown passphrase envelope")
+	}
+	saltB64, token, ok := strings.Cut(rest, ":")
+	if !ok {
+		return "", fmt.Errorf("malformed passphrase envelope")
+	}
+	salt, err := base64.StdEncoding.DecodeString(saltB64)
+	if err != nil {
+		return "", fmt.Errorf("malformed passphrase envelope: %w", err)
+	}
+	key, err := passphraseKey(passphrase, salt)
+	if err != nil {
+		return "", fmt.Errorf("failed to derive passphrase key: %w", err)
+	}
+	plaintext := fernet.VerifyAndDecrypt([]byte(token), 0, []*fernet.Key{key})
+	if plaintext == nil {
+		return "", ErrBadPassphrase
+	}
+	return string(plaintext), nil
+}
+
+// decryptValue decrypts an encrypted value of key with the master key and,
+// for passphrase-protected keys, unwraps it with passphrase.
+func (s *KeyStorage) decryptValue(key *models.APIKey, encrypted, passphrase string) (string, error) {
+	value, err := s.crypto.Decrypt(encrypted)
+	if err != nil {
+		return "", err
+	}
+	if !key.PassphraseProtected {
+		return value, nil
+	}
+	return unwrapWithPassphrase(value, passphrase)
+}
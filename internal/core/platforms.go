@@ -0,0 +1,271 @@
+package core
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/baobao/akm-go/internal/models"
+)
+
+//go:embed platforms.json
+var platformsJSON []byte
+
+// builtinPlatforms is the platform registry, loaded from the bundled
+// platforms.json. Model auto-detection (proxy, exports) is driven by
+// ModelPrefixes and SupportedModels, and the proxy and verifier take their
+// API base URLs from it, so adding a platform there is enough to teach the
+// proxy its models.
+var builtinPlatforms = mustLoadPlatforms()
+
+// LoadPlatforms parses the bundled platforms.json.
+func LoadPlatforms() (*models.PlatformsFile, error) {
+	var file models.PlatformsFile
+	if err := json.Unmarshal(platformsJSON, &file); err != nil {
+		return nil, fmt.Errorf("invalid bundled platforms.json: %w", err)
+	}
+	for i, p := range file.Platforms {
+		if p.ID == "" || p.APIBase == "" {
+			return nil, fmt.Errorf("invalid bundled platforms.json: platform %d needs id and api_base", i)
+		}
+	}
+	return &file, nil
+}
+
+// mustLoadPlatforms loads the registry at startup; the file is compiled in,
+// so a parse error is a build defect.
+func mustLoadPlatforms() []models.Platform {
+	file, err := LoadPlatforms()
+	if err != nil {
+		panic(err)
+	}
+	return file.Platforms
+}
+
+// customModelPrefixes maps providers configured outside the registry (e.g.
+// the proxy's providers.yaml) to extra model prefixes for ProviderForModel.
+var customModelPrefixes map[string][]string
+
+// SetCustomModelPrefixes registers extra model prefixes per provider, added
+// to any ModelPrefixes the registry already lists for it. Call it before
+// serving requests; it is not synchronized.
+func SetCustomModelPrefixes(prefixes map[string][]string) {
+	customModelPrefixes = prefixes
+}
+
+// Platforms returns a copy of the platform registry.
+func Platforms() []models.Platform {
+	result := make([]models.Platform, len(builtinPlatforms))
+	copy(result, builtinPlatforms)
+	return result
+}
+
+// PlatformAPIBase returns the registry's API base URL for a platform ID.
+func PlatformAPIBase(id string) (string, bool) {
+	for _, p := range builtinPlatforms {
+		if p.ID == id {
+			return p.APIBase, true
+		}
+	}
+	return "", false
+}
+
+// ProviderForModel returns the platform ID that serves model. Exact matches in
+// SupportedModels win; otherwise the longest matching ModelPrefixes entry does.
+func ProviderForModel(model string) (string, bool) {
+	model = strings.ToLower(strings.TrimSpace(model))
+	if model == "" {
+		return "", false
+	}
+
+	for _, p := range builtinPlatforms {
+		for _, m := range p.SupportedModels {
+			if strings.ToLower(m) == model {
+				return p.ID, true
+			}
+		}
+	}
+
+	best, bestLen := "", 0
+	match := func(id string, prefixes []string) {
+		for _, prefix := range prefixes {
+			if len(prefix) > bestLen && strings.HasPrefix(model, strings.ToLower(prefix)) {
+				best, bestLen = id, len(prefix)
+			}
+		}
+	}
+	for _, p := range builtinPlatforms {
+		match(p.ID, p.ModelPrefixes)
+	}
+	for id, prefixes := range customModelPrefixes {
+		match(id, prefixes)
+	}
+	return best, best != ""
+}
+
+// snapshotSuffix matches a dated snapshot suffix such as "-20250929" or
+// "-2024-08-06", so pinned snapshots count as their base model.
+var snapshotSuffix = regexp.MustCompile(`-(\d{8}|\d{4}-\d{2}-\d{2})$`)
+
+// CheckSupportedModel reports whether model is in the SupportedModels list of
+// the platform its name belongs to. It returns ok=true when the model's
+// platform is unknown or lists no models, since there is nothing to check
+// against; otherwise it also returns the platform and its valid models.
+func CheckSupportedModel(model string) (provider string, valid []string, ok bool) {
+	provider, found := ProviderForModel(model)
+	if !found {
+		return "", nil, true
+	}
+	for _, p := range builtinPlatforms {
+		if p.ID != provider || len(p.SupportedModels) == 0 {
+			continue
+		}
+		base := snapshotSuffix.ReplaceAllString(strings.ToLower(strings.TrimSpace(model)), "")
+		for _, m := range p.SupportedModels {
+			if strings.ToLower(m) == base {
+				return provider, nil, true
+			}
+		}
+		return provider, p.SupportedModels, false
+	}
+	return provider, nil, true
+}
+
+// ModelCandidates returns, sorted, the platforms whose model family name
+// (a ModelPrefixes entry without its trailing "-", at least 3 chars) or ID
+// appears anywhere in model. It is a heuristic fallback for names such as
+// "openrouter/claude-x" or "my-gpt-finetune" that ProviderForModel rejects.
+func ModelCandidates(model string) []string {
+	model = strings.ToLower(model)
+	var candidates []string
+	for _, p := range builtinPlatforms {
+		needles := []string{strings.ToLower(p.ID)}
+		for _, prefix := range p.ModelPrefixes {
+			if family := strings.TrimRight(strings.ToLower(prefix), "-"); len(family) >= 3 {
+				needles = append(needles, family)
+			}
+		}
+		for _, n := range needles {
+			if strings.Contains(model, n) {
+				candidates = append(candidates, p.ID)
+				break
+			}
+		}
+	}
+	sort.Strings(candidates)
+	return candidates
+}
+
+// ReachabilityResult is the outcome of probing one platform's API base URL.
+type ReachabilityResult struct {
+	Platform    string        `json:"platform"`
+	URL         string        `json:"url"`
+	Reachable   bool          `json:"reachable"`
+	StatusCode  int           `json:"status_code,omitempty"`
+	Latency     time.Duration `json:"latency"`
+	Error       string        `json:"error,omitempty"`
+	RequiresVPN bool          `json:"requires_vpn"`
+	Skipped     bool          `json:"skipped,omitempty"` // provider not in the registry, nothing to probe
+}
+
+// ProbePlatforms sends an unauthenticated HEAD request to every active
+// platform's API base concurrently. Any HTTP response (even 404) counts as
+// reachable: the goal is to tell network problems apart from bad keys.
+func ProbePlatforms(timeout time.Duration) []ReachabilityResult {
+	var targets []ReachabilityResult
+	for _, p := range Platforms() {
+		if p.IsActive {
+			targets = append(targets, ReachabilityResult{Platform: p.ID, URL: p.APIBase, RequiresVPN: p.RequiresVPN})
+		}
+	}
+	probeAll(targets, timeout)
+	return targets
+}
+
+// ProbeProviders probes, like ProbePlatforms, the API base of each provider
+// that has at least one active key, so a deep health check only touches the
+// networks the stored keys actually need. Providers missing from the registry
+// are reported as skipped. Results are sorted by provider.
+func ProbeProviders(storage *KeyStorage, timeout time.Duration) []ReachabilityResult {
+	var targets []ReachabilityResult
+	for _, provider := range storage.activeProviders() {
+		r := ReachabilityResult{Platform: provider}
+		if p, ok := platformByID(provider); ok {
+			r.URL, r.RequiresVPN = p.APIBase, p.RequiresVPN
+		} else {
+			r.Skipped, r.Error = true, "no API base URL in platform registry"
+		}
+		targets = append(targets, r)
+	}
+	probeAll(targets, timeout)
+	return targets
+}
+
+// probeAll fills in the probe outcome of each target with a URL concurrently,
+// through the verifier's shared client.
+func probeAll(targets []ReachabilityResult, timeout time.Duration) {
+	var wg sync.WaitGroup
+	for i := range targets {
+		if targets[i].URL == "" {
+			continue
+		}
+		wg.Add(1)
+		go func(r *ReachabilityResult) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			req, err := http.NewRequestWithContext(ctx, http.MethodHead, r.URL, nil)
+			if err != nil {
+				r.Error = err.Error()
+				return
+			}
+			start := time.Now()
+			resp, err := verifyClient.Do(req)
+			r.Latency = time.Since(start)
+			if err != nil {
+				r.Error = err.Error()
+				return
+			}
+			resp.Body.Close()
+			r.Reachable = true
+			r.StatusCode = resp.StatusCode
+		}(&targets[i])
+	}
+	wg.Wait()
+}
+
+// activeProviders returns the sorted, normalized providers of active keys.
+func (s *KeyStorage) activeProviders() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	for _, key := range s.keysCache {
+		if key.IsActive && key.Provider != "" {
+			seen[normalizeProvider(key.Provider)] = true
+		}
+	}
+	providers := make([]string, 0, len(seen))
+	for p := range seen {
+		providers = append(providers, p)
+	}
+	sort.Strings(providers)
+	return providers
+}
+
+// platformByID looks up a registry platform.
+func platformByID(id string) (models.Platform, bool) {
+	for _, p := range builtinPlatforms {
+		if p.ID == id {
+			return p, true
+		}
+	}
+	return models.Platform{}, false
+}
@@ -0,0 +1,42 @@
+package core
+
+import "testing"
+
+// Every model a built-in platform lists resolves back to that platform, so a
+// model added to platforms.json is routed without touching the proxy.
+func TestProviderForModelBuiltinPlatforms(t *testing.T) {
+	platforms := Platforms()
+	if len(platforms) == 0 {
+		t.Fatal("no built-in platforms")
+	}
+	for _, p := range platforms {
+		t.Run(p.ID, func(t *testing.T) {
+			for _, model := range p.SupportedModels {
+				got, ok := ProviderForModel(model)
+				if !ok || got != p.ID {
+					t.Errorf("ProviderForModel(%q) = %q, %v; want %q", model, got, ok, p.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestProviderForModelPrefixes(t *testing.T) {
+	tests := []struct {
+		model string
+		want  string
+		ok    bool
+	}{
+		{"gpt-4o-mini-2024-07-18", "openai", true},
+		{"  GPT-4o  ", "openai", true},
+		{"claude-opus-4-1-20250805", "anthropic", true},
+		{"unknown-model", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := ProviderForModel(tt.model)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ProviderForModel(%q) = %q, %v; want %q, %v", tt.model, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// DefaultProfile keeps its data directly in ~/.apikey-manager/data, as
+// before profiles existed.
+const DefaultProfile = "default"
+
+var validProfilePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+// activeProfile selects the data directory used by GetStorage and
+// GetBudgetTracker. Set it with SetProfile before either is first called.
+var activeProfile = DefaultProfile
+
+// SetProfile selects the named storage profile; "" means DefaultProfile.
+// Each profile has its own keys, audit log, budget and backups; the master
+// key and team members are shared.
+func SetProfile(name string) error {
+	if name == "" {
+		name = DefaultProfile
+	}
+	if !validProfilePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q: use letters, digits, '.', '_' or '-'", name)
+	}
+	activeProfile = name
+	return nil
+}
+
+// ActiveProfile returns the selected profile name.
+func ActiveProfile() string {
+	return activeProfile
+}
+
+// BaseDir returns the akm root directory, ~/.apikey-manager.
+func BaseDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".apikey-manager"), nil
+}
+
+// DataDir returns the active profile's data directory:
+// ~/.apikey-manager/data for the default profile, otherwise
+// ~/.apikey-manager/profiles/<name>/data.
+func DataDir() (string, error) {
+	base, err := BaseDir()
+	if err != nil {
+		return "", err
+	}
+	if activeProfile == DefaultProfile {
+		return filepath.Join(base, "data"), nil
+	}
+	return filepath.Join(base, "profiles", activeProfile, "data"), nil
+}
+
+// sharedDataDir holds state tied to the master key rather than to a profile.
+func sharedDataDir() (string, error) {
+	base, err := BaseDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "data"), nil
+}
@@ -1,40 +1,185 @@
 package core
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
-// ProjectConfig represents an akm.yaml project configuration file.
+// ProjectConfig represents an akm.yaml (or akm.json) project configuration file.
 type ProjectConfig struct {
-	Keys     []string `yaml:"keys"`
-	Provider string   `yaml:"provider,omitempty"`
+	Keys     []ProjectKey  `yaml:"keys" json:"keys"`
+	Provider string        `yaml:"provider,omitempty" json:"provider,omitempty"`
+	Dotenv   *DotenvConfig `yaml:"dotenv,omitempty" json:"dotenv,omitempty"`
 }
 
-// LoadProjectConfig loads akm.yaml from the given directory.
+// ProjectKey is one akm.yaml `keys` entry. A plain string names a stored key
+// that is written under the same name; a single-entry mapping such as
+// `OPENAI_API_KEY: ${provider:openai}` writes Env from the given Source.
+type ProjectKey struct {
+	Env    string // environment variable name written to .env
+	Source string // stored key name, or a ${provider:NAME} reference
+}
+
+var providerRefPattern = regexp.MustCompile(`^\$\{provider:([^}]+)\}$`)
+
+// UnmarshalYAML implements yaml.Unmarshaler for ProjectKey.
+func (k *ProjectKey) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		k.Env = node.Value
+		k.Source = node.Value
+	case yaml.MappingNode:
+		if len(node.Content) != 2 {
+			return fmt.Errorf("line %d: key entry must map exactly one name to a source", node.Line)
+		}
+		k.Env = node.Content[0].Value
+		k.Source = strings.TrimSpace(node.Content[1].Value)
+	default:
+		return fmt.Errorf("line %d: key entry must be a name or a NAME: source mapping", node.Line)
+	}
+	if k.Env == "" || k.Source == "" {
+		return fmt.Errorf("line %d: key entry has an empty name or source", node.Line)
+	}
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler for ProjectKey, accepting the same
+// shapes as YAML: "NAME" or {"NAME": "source"}.
+func (k *ProjectKey) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		k.Env, k.Source = name, name
+	} else {
+		var m map[string]string
+		if err := json.Unmarshal(data, &m); err != nil || len(m) != 1 {
+			return fmt.Errorf("key entry must be a name or a single {\"NAME\": \"source\"} object")
+		}
+		for env, source := range m {
+			k.Env, k.Source = env, strings.TrimSpace(source)
+		}
+	}
+	if k.Env == "" || k.Source == "" {
+		return fmt.Errorf("key entry has an empty name or source")
+	}
+	return nil
+}
+
+// ProviderRef returns the provider of a ${provider:NAME} source.
+func (k ProjectKey) ProviderRef() (string, bool) {
+	m := providerRefPattern.FindStringSubmatch(k.Source)
+	if m == nil {
+		return "", false
+	}
+	return strings.TrimSpace(m[1]), true
+}
+
+// ProjectConfigFiles are the config file names tried in order.
+var ProjectConfigFiles = []string{"akm.yaml", "akm.json"}
+
+// ProjectConfigSchema is a JSON Schema for akm.yaml / akm.json.
+const ProjectConfigSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "title": "akm project config",
+  "type": "object",
+  "required": ["keys"],
+  "properties": {
+    "provider": {
+      "type": "string",
+      "description": "Only inject plain key names from this provider"
+    },
+    "dotenv": {
+      "type": "object",
+      "description": "Formatting of the generated .env",
+      "properties": {
+        "header": {"type": "string", "description": "Header comment; empty string for none"},
+        "no_quote": {"type": "boolean"},
+        "sort": {"type": "boolean"},
+        "timestamp": {"type": "boolean"}
+      },
+      "additionalProperties": false
+    },
+    "keys": {
+      "type": "array",
+      "minItems": 1,
+      "items": {
+        "oneOf": [
+          {"type": "string", "description": "Stored key name, written under the same name"},
+          {
+            "type": "object",
+            "description": "ENV_NAME: stored key name or ${provider:NAME}",
+            "minProperties": 1,
+            "maxProperties": 1,
+            "additionalProperties": {"type": "string", "minLength": 1}
+          }
+        ]
+      }
+    }
+  }
+}`
+
+// LoadProjectConfig loads akm.yaml or akm.json from the given directory.
+// If both exist they must declare the same configuration.
 func LoadProjectConfig(dir string) (*ProjectConfig, error) {
-	path := filepath.Join(dir, "akm.yaml")
+	var config *ProjectConfig
+	var configFile string
+	for _, name := range ProjectConfigFiles {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+		loaded, err := loadProjectConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if config == nil {
+			config, configFile = loaded, name
+			continue
+		}
+		if !reflect.DeepEqual(config, loaded) {
+			return nil, fmt.Errorf("%s and %s both exist but disagree; keep only one", configFile, name)
+		}
+	}
+
+	if config == nil {
+		return nil, fmt.Errorf("cannot read %s: no %s found", filepath.Join(dir, ProjectConfigFiles[0]), strings.Join(ProjectConfigFiles, " or "))
+	}
+	return config, nil
+}
+
+func loadProjectConfigFile(path string) (*ProjectConfig, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("cannot read %s: %w", path, err)
 	}
 
+	name := filepath.Base(path)
 	var config ProjectConfig
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("invalid akm.yaml: %w", err)
+	if strings.HasSuffix(name, ".json") {
+		err = json.Unmarshal(data, &config)
+	} else {
+		err = yaml.Unmarshal(data, &config)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", name, err)
 	}
 
 	if len(config.Keys) == 0 {
-		return nil, fmt.Errorf("akm.yaml has no keys defined")
+		return nil, fmt.Errorf("%s has no keys defined", name)
 	}
 
 	return &config, nil
 }
 
-// FindProjectConfigs scans a parent directory for subdirectories containing akm.yaml.
+// FindProjectConfigs scans a parent directory for subdirectories containing akm.yaml or akm.json.
 func FindProjectConfigs(parentDir string) (map[string]*ProjectConfig, error) {
 	entries, err := os.ReadDir(parentDir)
 	if err != nil {
@@ -49,9 +194,72 @@ func FindProjectConfigs(parentDir string) (map[string]*ProjectConfig, error) {
 		dir := filepath.Join(parentDir, entry.Name())
 		config, err := LoadProjectConfig(dir)
 		if err != nil {
-			continue // no akm.yaml or invalid, skip
+			continue // no config or invalid, skip
 		}
 		configs[dir] = config
 	}
 	return configs, nil
 }
+
+// ResolveProjectKeys decrypts the values declared in a project config, keyed by
+// env var name. Plain names missing from the store are skipped so callers can
+// warn; an expired key, or a provider reference with no active unexpired
+// local key, is an error.
+func (s *KeyStorage) ResolveProjectKeys(project string, config *ProjectConfig) (map[string]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make(map[string]string)
+	var unresolved []string
+	for _, entry := range config.Keys {
+		var keyName string
+		if provider, ok := entry.ProviderRef(); ok {
+			keyName = s.activeKeyNameLocked(provider)
+			if keyName == "" {
+				unresolved = append(unresolved, fmt.Sprintf("%s (%s)", entry.Env, entry.Source))
+				continue
+			}
+		} else {
+			key := s.keysCache[entry.Source]
+			if key == nil || (config.Provider != "" && key.Provider != config.Provider) {
+				continue
+			}
+			if key.IsExpired(time.Now()) {
+				return nil, expiredError(key)
+			}
+			keyName = key.Name
+		}
+
+		if s.keysCache[keyName].PassphraseProtected {
+			return nil, fmt.Errorf("key '%s' is passphrase-protected and cannot be injected", keyName)
+		}
+		value, err := s.crypto.Decrypt(s.keysCache[keyName].ValueEncrypted)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt key '%s': %w", keyName, err)
+		}
+		result[entry.Env] = value
+		s.logUsage(keyName, "inject", project)
+	}
+
+	if len(unresolved) > 0 {
+		return nil, fmt.Errorf("no active key for reference: %s", strings.Join(unresolved, ", "))
+	}
+	return result, nil
+}
+
+// activeKeyNameLocked returns the first active, unexpired key (by name) for
+// provider. Caller must hold s.mu.
+func (s *KeyStorage) activeKeyNameLocked(provider string) string {
+	now := time.Now()
+	var names []string
+	for _, key := range s.keysCache {
+		if key.IsActive && !key.IsExpired(now) && strings.EqualFold(key.Provider, provider) {
+			names = append(names, key.Name)
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	sort.Strings(names)
+	return names[0]
+}
@@ -0,0 +1,123 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RevealPolicy controls whether plaintext values may be displayed. It does not
+// affect injection, export, or the proxy, which hand values to programs.
+type RevealPolicy string
+
+const (
+	// RevealFull allows showing plaintext values (default).
+	RevealFull RevealPolicy = "full"
+	// RevealMasked only ever shows masked values.
+	RevealMasked RevealPolicy = "masked"
+	// RevealNever refuses every value-revealing path.
+	RevealNever RevealPolicy = "never"
+)
+
+// ErrRevealForbidden is returned when the reveal policy forbids showing a value.
+var ErrRevealForbidden = errors.New("revealing key values is disabled by AKM_REVEAL_POLICY=never")
+
+// CurrentRevealPolicy reads AKM_REVEAL_POLICY. Unknown values fall back to the
+// most restrictive policy so a typo never widens access.
+func CurrentRevealPolicy() RevealPolicy {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv("AKM_REVEAL_POLICY"))) {
+	case "", string(RevealFull):
+		return RevealFull
+	case string(RevealMasked):
+		return RevealMasked
+	default:
+		return RevealNever
+	}
+}
+
+// CheckReveal returns ErrRevealForbidden if no value may be shown at all, and
+// enforces the idle re-authentication policy (AKM_REVEAL_IDLE_TIMEOUT).
+// Call it before decrypting so a forbidden reveal isn't audited as a read.
+func CheckReveal() error {
+	if CurrentRevealPolicy() == RevealNever {
+		return ErrRevealForbidden
+	}
+	return checkRevealIdle()
+}
+
+var (
+	revealMu sync.Mutex
+	// lastRevealAuth is when this process last accessed the keychain or
+	// revealed a value; in memory only, so every new process starts fresh.
+	lastRevealAuth time.Time
+)
+
+// revealIdleTimeout reads AKM_REVEAL_IDLE_TIMEOUT (a duration such as "15m").
+// Unset, invalid or non-positive values disable idle re-authentication.
+func revealIdleTimeout() time.Duration {
+	d, err := time.ParseDuration(strings.TrimSpace(os.Getenv("AKM_REVEAL_IDLE_TIMEOUT")))
+	if err != nil || d <= 0 {
+		return 0
+	}
+	return d
+}
+
+// markRevealAuth records a fresh authentication (keychain access or reveal).
+func markRevealAuth() {
+	revealMu.Lock()
+	lastRevealAuth = time.Now()
+	revealMu.Unlock()
+}
+
+// checkRevealIdle forces a fresh keychain access before revealing when the
+// last reveal (or keychain access) is older than the idle timeout, so an
+// unattended long-running process can't keep handing out secrets once the
+// keychain is locked.
+func checkRevealIdle() error {
+	idle := revealIdleTimeout()
+	if idle == 0 {
+		return nil
+	}
+
+	// Load crypto before locking: first-time initialization marks revealMu too
+	crypto, err := GetCrypto()
+	if err != nil {
+		return err
+	}
+
+	revealMu.Lock()
+	defer revealMu.Unlock()
+	if !lastRevealAuth.IsZero() && time.Since(lastRevealAuth) > idle {
+		if err := crypto.Reauthenticate(); err != nil {
+			return fmt.Errorf("re-authentication required after %s idle: %w", idle, err)
+		}
+	}
+	lastRevealAuth = time.Now()
+	return nil
+}
+
+// RevealValue applies the reveal policy to a decrypted value. masked requests
+// masking regardless of policy; the policy can only make display stricter.
+func RevealValue(value string, masked bool) (string, error) {
+	switch CurrentRevealPolicy() {
+	case RevealNever:
+		return "", ErrRevealForbidden
+	case RevealMasked:
+		return MaskValue(value), nil
+	}
+	if masked {
+		return MaskValue(value), nil
+	}
+	return value, nil
+}
+
+// MaskValue masks the middle part of a value for display.
+func MaskValue(value string) string {
+	if len(value) <= 8 {
+		return strings.Repeat("*", len(value))
+	}
+	return value[:4] + strings.Repeat("*", len(value)-8) + value[len(value)-4:]
+}
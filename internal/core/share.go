@@ -0,0 +1,103 @@
+package core
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Share link lifetimes.
+const (
+	DefaultShareTTL = 10 * time.Minute
+	MaxShareTTL     = 24 * time.Hour
+)
+
+// ErrInvalidShare is returned for an unknown, expired or already used share token.
+var ErrInvalidShare = errors.New("share link is invalid, expired or already used")
+
+// ShareLink is a pending one-time reveal of a key. It holds only the key name;
+// the value is decrypted when the link is redeemed.
+type ShareLink struct {
+	KeyName   string    `json:"name"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+var (
+	sharesMu sync.Mutex
+	// shares maps a token hash to its link. In memory only: links die with
+	// the server process that minted them.
+	shares = make(map[string]*ShareLink)
+)
+
+// CreateShare mints a single-use token that reveals name's value once within
+// ttl. The raw token is returned once; only its hash is kept.
+func (s *KeyStorage) CreateShare(name string, ttl time.Duration) (string, *ShareLink, error) {
+	if CurrentRevealPolicy() != RevealFull {
+		return "", nil, ErrRevealForbidden
+	}
+	if ttl <= 0 {
+		ttl = DefaultShareTTL
+	}
+	if ttl > MaxShareTTL {
+		return "", nil, fmt.Errorf("share TTL %s exceeds the maximum of %s", ttl, MaxShareTTL)
+	}
+
+	key := s.GetKey(name)
+	if key == nil {
+		return "", nil, fmt.Errorf("key '%s' not found", name)
+	}
+	if key.PassphraseProtected {
+		return "", nil, fmt.Errorf("key '%s' is passphrase-protected and cannot be shared", key.Name)
+	}
+
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, err
+	}
+	raw := base64.RawURLEncoding.EncodeToString(secret)
+	link := &ShareLink{KeyName: key.Name, ExpiresAt: time.Now().Add(ttl)}
+
+	sharesMu.Lock()
+	pruneSharesLocked()
+	shares[hashToken(raw)] = link
+	sharesMu.Unlock()
+
+	s.logUsage(key.Name, "share-create", "share")
+	return raw, link, nil
+}
+
+// RedeemShare consumes a share token and returns the key name and value. The
+// token is invalidated before decrypting, so it never works twice.
+func (s *KeyStorage) RedeemShare(raw string) (string, string, error) {
+	sharesMu.Lock()
+	pruneSharesLocked()
+	link, ok := shares[hashToken(raw)]
+	delete(shares, hashToken(raw))
+	sharesMu.Unlock()
+	if !ok {
+		return "", "", ErrInvalidShare
+	}
+
+	// Re-check: the policy may have been tightened since the link was minted
+	if CurrentRevealPolicy() != RevealFull {
+		return "", "", ErrRevealForbidden
+	}
+	value, err := s.GetKeyValue(link.KeyName, "share")
+	if err != nil {
+		return "", "", err
+	}
+	return link.KeyName, value, nil
+}
+
+// pruneSharesLocked drops expired links. Caller must hold sharesMu.
+func pruneSharesLocked() {
+	now := time.Now()
+	for hash, link := range shares {
+		if now.After(link.ExpiresAt) {
+			delete(shares, hash)
+		}
+	}
+}
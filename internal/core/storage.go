@@ -1,17 +1,22 @@
 package core
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
+	"math"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/baobao/akm-go/internal/models"
+	"github.com/fernet/fernet-go"
 )
 
 var validKeyNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
@@ -24,6 +29,50 @@ func ValidateKeyName(name string) bool {
 	return validKeyNamePattern.MatchString(name)
 }
 
+const (
+	// MinKeyValueLength is the length below which a key value is flagged as suspicious.
+	MinKeyValueLength = 16
+	// MinKeyValueEntropy is the Shannon entropy (bits per byte) below which a value is flagged.
+	MinKeyValueEntropy = 3.0
+)
+
+// ShannonEntropy returns the Shannon entropy of value in bits per byte.
+func ShannonEntropy(value string) float64 {
+	if value == "" {
+		return 0
+	}
+	var counts [256]int
+	for i := 0; i < len(value); i++ {
+		counts[value[i]]++
+	}
+	n := float64(len(value))
+	var entropy float64
+	for _, c := range counts {
+		if c == 0 {
+			continue
+		}
+		p := float64(c) / n
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// CheckValueStrength returns an error if a key value looks truncated or low-entropy.
+// Callers decide whether to treat it as a warning or reject the value.
+func CheckValueStrength(value string) error {
+	var problems []string
+	if len(value) < MinKeyValueLength {
+		problems = append(problems, fmt.Sprintf("value is only %d chars (expected at least %d)", len(value), MinKeyValueLength))
+	}
+	if entropy := ShannonEntropy(value); entropy < MinKeyValueEntropy {
+		problems = append(problems, fmt.Sprintf("low entropy %.2f bits/byte (expected at least %.1f)", entropy, MinKeyValueEntropy))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
 // EscapeDotenvValue escapes a value for .env file format.
 func EscapeDotenvValue(value string) string {
 	value = strings.ReplaceAll(value, "\\", "\\\\")
@@ -39,53 +88,112 @@ type KeyStorage struct {
 	keysFile  string
 	auditFile string
 	crypto    *KeyEncryption
+	filePerm  os.FileMode
+	dirPerm   os.FileMode
 
 	keysCache  map[string]*models.APIKey
+	trash      map[string]*models.APIKey // deleted keys by name, see trash.go
 	loadFailed bool
 	mu         sync.RWMutex
+
+	observers eventObservers // see OnChange
+	decrypted *decryptCache  // nil unless WithDecryptCache
 }
 
 var (
 	storageInstance *KeyStorage
 	storageOnce     sync.Once
+	storageErr      error // sticky: later calls see the first failure
 )
 
-// GetStorage returns the singleton KeyStorage instance.
+// GetStorage returns the singleton KeyStorage instance for the active profile.
 func GetStorage() (*KeyStorage, error) {
-	var initErr error
 	storageOnce.Do(func() {
-		homeDir, err := os.UserHomeDir()
+		dataDir, err := DataDir()
 		if err != nil {
-			initErr = err
+			storageErr = err
 			return
 		}
-		dataDir := filepath.Join(homeDir, ".apikey-manager", "data")
-		storageInstance, initErr = NewKeyStorage(dataDir)
+		var opts []StorageOption
+		if auditFile := strings.TrimSpace(os.Getenv("AKM_AUDIT_FILE")); auditFile != "" {
+			opts = append(opts, WithAuditFile(auditFile))
+		}
+		if raw := strings.TrimSpace(os.Getenv("AKM_DECRYPT_CACHE_TTL")); raw != "" {
+			ttl, err := time.ParseDuration(raw)
+			if err != nil || ttl < 0 {
+				storageErr = fmt.Errorf("invalid AKM_DECRYPT_CACHE_TTL %q: expected a duration such as 30s", raw)
+				return
+			}
+			opts = append(opts, WithDecryptCache(ttl))
+		}
+		storageInstance, storageErr = NewKeyStorage(dataDir, opts...)
 	})
-	if initErr != nil {
-		return nil, initErr
+	if storageErr != nil {
+		return nil, storageErr
 	}
 	return storageInstance, nil
 }
 
-// NewKeyStorage creates a new KeyStorage with the specified data directory.
-func NewKeyStorage(dataDir string) (*KeyStorage, error) {
-	// Create data directory with restricted permissions
-	if err := os.MkdirAll(dataDir, 0700); err != nil {
-		return nil, fmt.Errorf("failed to create data directory: %w", err)
+// StorageOption is a functional option for configuring a KeyStorage.
+type StorageOption func(*KeyStorage)
+
+// WithCrypto uses kc instead of the keychain-backed GetCrypto singleton.
+func WithCrypto(kc *KeyEncryption) StorageOption {
+	return func(s *KeyStorage) {
+		s.crypto = kc
 	}
+}
 
-	crypto, err := GetCrypto()
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize crypto: %w", err)
+// WithFilePerm sets the permissions for files the storage creates (default 0600).
+func WithFilePerm(mode os.FileMode) StorageOption {
+	return func(s *KeyStorage) {
+		s.filePerm = mode
 	}
+}
+
+// WithDirPerm sets the permissions for the data directory (default 0700).
+func WithDirPerm(mode os.FileMode) StorageOption {
+	return func(s *KeyStorage) {
+		s.dirPerm = mode
+	}
+}
 
+// WithAuditFile sets the audit log path (default <dataDir>/audit.jsonl).
+func WithAuditFile(path string) StorageOption {
+	return func(s *KeyStorage) {
+		s.auditFile = path
+	}
+}
+
+// NewKeyStorage creates a new KeyStorage with the specified data directory.
+func NewKeyStorage(dataDir string, opts ...StorageOption) (*KeyStorage, error) {
 	s := &KeyStorage{
 		dataDir:   dataDir,
 		keysFile:  filepath.Join(dataDir, "keys.json"),
 		auditFile: filepath.Join(dataDir, "audit.jsonl"),
-		crypto:    crypto,
+		filePerm:  0600,
+		dirPerm:   0700,
 		keysCache: make(map[string]*models.APIKey),
+		trash:     make(map[string]*models.APIKey),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	// Create data directory with restricted permissions
+	if err := os.MkdirAll(dataDir, s.dirPerm); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.auditFile), s.dirPerm); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+
+	if s.crypto == nil {
+		crypto, err := GetCrypto()
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize crypto: %w", err)
+		}
+		s.crypto = crypto
 	}
 
 	if err := s.loadKeys(); err != nil {
@@ -117,6 +225,9 @@ func (s *KeyStorage) loadKeys() error {
 		for _, key := range keysFile.Keys {
 			s.keysCache[key.Name] = key
 		}
+		for _, key := range keysFile.Deleted {
+			s.trash[key.Name] = key
+		}
 		return nil
 	}
 
@@ -133,6 +244,9 @@ func (s *KeyStorage) loadKeys() error {
 	for _, key := range keysFile.Keys {
 		s.keysCache[key.Name] = key
 	}
+	for _, key := range keysFile.Deleted {
+		s.trash[key.Name] = key
+	}
 
 	return nil
 }
@@ -149,10 +263,12 @@ func (s *KeyStorage) saveKeys() error {
 		keys = append(keys, key)
 	}
 
+	// Trash past its retention window is simply not written back
 	keysFile := models.KeysFile{
 		Version:   "2.0",
 		UpdatedAt: time.Now().Format(time.RFC3339),
 		Keys:      keys,
+		Deleted:   s.liveTrashLocked(time.Now()),
 	}
 
 	jsonBytes, err := json.MarshalIndent(keysFile, "", "  ")
@@ -167,7 +283,7 @@ func (s *KeyStorage) saveKeys() error {
 
 	// Atomic write: write to temp file, then rename
 	tempFile := filepath.Join(s.dataDir, ".keys_temp.json")
-	if err := os.WriteFile(tempFile, []byte(encrypted), 0600); err != nil {
+	if err := os.WriteFile(tempFile, []byte(encrypted), s.filePerm); err != nil {
 		return fmt.Errorf("failed to write temp file: %w", err)
 	}
 
@@ -188,19 +304,33 @@ func (s *KeyStorage) AddKey(name, value, provider string, opts ...KeyOption) (*m
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// Encrypt the value
-	encrypted, err := s.crypto.Encrypt(value)
-	if err != nil {
-		return nil, fmt.Errorf("failed to encrypt key value: %w", err)
-	}
-
-	key := models.NewAPIKey(name, encrypted, provider)
+	key := models.NewAPIKey(name, "", provider)
 
 	// Apply options
 	for _, opt := range opts {
 		opt(key)
 	}
 
+	stampCreatedBy(key)
+
+	// Wrap with the per-key passphrase first, if any
+	if key.Passphrase != "" {
+		wrapped, err := wrapWithPassphrase(value, key.Passphrase)
+		key.Passphrase = ""
+		if err != nil {
+			return nil, err
+		}
+		value = wrapped
+		key.PassphraseProtected = true
+	}
+
+	// Encrypt the value
+	encrypted, err := s.crypto.Encrypt(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt key value: %w", err)
+	}
+	key.ValueEncrypted = encrypted
+
 	s.keysCache[name] = key
 
 	if err := s.saveKeys(); err != nil {
@@ -209,11 +339,177 @@ func (s *KeyStorage) AddKey(name, value, provider string, opts ...KeyOption) (*m
 	}
 
 	// Audit log
-	s.logUsage(name, "add", "system")
+	if err := s.auditMutation(name, "add", func() { delete(s.keysCache, name) }); err != nil {
+		return nil, err
+	}
 
 	return key, nil
 }
 
+// BatchKey is one NAME=value entry for AddKeysBatch.
+type BatchKey struct {
+	Name  string
+	Value string
+	// Optional per-entry metadata for new keys; Provider overrides the
+	// batch provider when set
+	Provider    string
+	Description string
+}
+
+// BatchAddReport summarizes AddKeysBatch. Names are in input order.
+type BatchAddReport struct {
+	Added       []string
+	Overwritten []string
+	Skipped     []string     // already existed and overwrite was off
+	Failed      []BatchError // invalid name, duplicate in input, ...
+}
+
+// BatchError is a per-entry AddKeysBatch failure.
+type BatchError struct {
+	Name string
+	Err  error
+}
+
+// AddKeysBatch adds all entries under provider in a single save. Existing
+// keys are skipped unless overwrite is set, in which case their value is
+// replaced as by RotateKeyValue (old value kept in history). Per-entry
+// problems are reported in the returned report; only a failed save (after
+// which nothing is changed) or a strict-mode audit failure returns an error.
+func (s *KeyStorage) AddKeysBatch(entries []BatchKey, provider string, overwrite bool) (*BatchAddReport, error) {
+	report := &BatchAddReport{}
+	fail := func(name string, err error) {
+		report.Failed = append(report.Failed, BatchError{Name: name, Err: err})
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var undos []func()
+	undoAll := func() {
+		for i := len(undos) - 1; i >= 0; i-- {
+			undos[i]()
+		}
+	}
+
+	now := time.Now()
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		switch {
+		case !ValidateKeyName(e.Name):
+			fail(e.Name, fmt.Errorf("invalid key name"))
+			continue
+		case seen[e.Name]:
+			fail(e.Name, fmt.Errorf("duplicate name in input"))
+			continue
+		case e.Value == "":
+			fail(e.Name, fmt.Errorf("empty value"))
+			continue
+		}
+		seen[e.Name] = true
+
+		encrypted, err := s.crypto.Encrypt(e.Value)
+		if err != nil {
+			fail(e.Name, fmt.Errorf("failed to encrypt key value: %w", err))
+			continue
+		}
+
+		existing := s.keysCache[e.Name]
+		switch {
+		case existing == nil:
+			keyProvider := provider
+			if e.Provider != "" {
+				keyProvider = e.Provider
+			}
+			key := models.NewAPIKey(e.Name, encrypted, keyProvider)
+			if e.Description != "" {
+				description := e.Description
+				key.Description = &description
+			}
+			stampCreatedBy(key)
+			s.keysCache[e.Name] = key
+			name := e.Name
+			undos = append(undos, func() { delete(s.keysCache, name) })
+			report.Added = append(report.Added, e.Name)
+		case !overwrite:
+			report.Skipped = append(report.Skipped, e.Name)
+		case existing.PassphraseProtected:
+			fail(e.Name, fmt.Errorf("key is passphrase-protected; delete and re-add it"))
+		default:
+			key := existing
+			oldEncrypted, oldHistory, oldUpdated, oldUpdatedBy := key.ValueEncrypted, key.ValueHistory, key.UpdatedAt, key.UpdatedBy
+			key.ValueHistory = pushValueHistory(key.ValueHistory, key.ValueEncrypted, now)
+			key.ValueEncrypted = encrypted
+			key.UpdatedAt = models.FlexTime{Time: now}
+			stampUpdatedBy(key)
+			undos = append(undos, func() {
+				key.ValueEncrypted, key.ValueHistory, key.UpdatedAt, key.UpdatedBy = oldEncrypted, oldHistory, oldUpdated, oldUpdatedBy
+			})
+			report.Overwritten = append(report.Overwritten, e.Name)
+		}
+	}
+
+	if len(undos) == 0 {
+		return report, nil
+	}
+	if err := s.saveKeys(); err != nil {
+		undoAll()
+		return report, err
+	}
+
+	// One audit entry per key, as if each had been added on its own
+	var auditErr error
+	for _, name := range report.Added {
+		if err := s.logUsage(name, "add", "system"); err != nil && auditErr == nil {
+			auditErr = err
+		}
+	}
+	for _, name := range report.Overwritten {
+		if err := s.logUsage(name, "rotate", "system"); err != nil && auditErr == nil {
+			auditErr = err
+		}
+	}
+	if auditErr != nil && auditStrict() {
+		undoAll()
+		if saveErr := s.saveKeys(); saveErr != nil {
+			return report, fmt.Errorf("audit write failed (%v) and rollback failed: %w", auditErr, saveErr)
+		}
+		return report, fmt.Errorf("audit write failed, batch rolled back: %w", auditErr)
+	}
+	for _, name := range report.Added {
+		s.publishMutation(name, "add")
+	}
+	for _, name := range report.Overwritten {
+		s.publishMutation(name, "rotate")
+	}
+	return report, nil
+}
+
+// CurrentUser names who is making a change: AKM_USER, else $USER (or
+// %USERNAME% on Windows). It returns "" when none is set.
+func CurrentUser() string {
+	for _, env := range []string{"AKM_USER", "USER", "USERNAME"} {
+		if user := strings.TrimSpace(os.Getenv(env)); user != "" {
+			return user
+		}
+	}
+	return ""
+}
+
+// stampCreatedBy records the current user as creator and last modifier.
+func stampCreatedBy(key *models.APIKey) {
+	if user := CurrentUser(); user != "" {
+		key.CreatedBy = &user
+		key.UpdatedBy = &user
+	}
+}
+
+// stampUpdatedBy records the current user as the key's last modifier.
+func stampUpdatedBy(key *models.APIKey) {
+	if user := CurrentUser(); user != "" {
+		key.UpdatedBy = &user
+	}
+}
+
 // KeyOption is a functional option for configuring a key.
 type KeyOption func(*models.APIKey)
 
@@ -231,6 +527,13 @@ func WithSourceProject(project string) KeyOption {
 	}
 }
 
+// WithExpiresAt sets when the key expires.
+func WithExpiresAt(t time.Time) KeyOption {
+	return func(k *models.APIKey) {
+		k.ExpiresAt = models.FlexTimePtr{Time: &t}
+	}
+}
+
 // WithTags sets the key tags.
 func WithTags(tags []string) KeyOption {
 	return func(k *models.APIKey) {
@@ -238,47 +541,213 @@ func WithTags(tags []string) KeyOption {
 	}
 }
 
+// WithModel sets the model the key is meant for.
+func WithModel(name string) KeyOption {
+	return func(k *models.APIKey) {
+		k.ModelName = &name
+	}
+}
+
+// WithModelVersion sets the model version.
+func WithModelVersion(version string) KeyOption {
+	return func(k *models.APIKey) {
+		k.ModelVersion = &version
+	}
+}
+
+// WithCapabilities sets the model capabilities (e.g. chat, vision, embeddings).
+func WithCapabilities(capabilities []string) KeyOption {
+	return func(k *models.APIKey) {
+		k.ModelCapabilities = capabilities
+	}
+}
+
+// caseInsensitiveLookup reports whether lookups fall back to matching names
+// ignoring case (AKM_KEY_CASE_INSENSITIVE, default off). The fallback also
+// applies to delete and update, so it must be asked for.
+func caseInsensitiveLookup() bool {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv("AKM_KEY_CASE_INSENSITIVE"))) {
+	case "1", "true", "yes", "on":
+		return true
+	}
+	return false
+}
+
+// lookupLocked finds a key by exact name, falling back to a case-insensitive
+// match when caseInsensitiveLookup allows it. Storage stays keyed by the original name; a fallback match is
+// reported on stderr and an ambiguous one is an error. Caller must hold s.mu.
+func (s *KeyStorage) lookupLocked(name string) (*models.APIKey, error) {
+	if key := s.keysCache[name]; key != nil {
+		return key, nil
+	}
+	if !caseInsensitiveLookup() {
+		return nil, fmt.Errorf("key '%s' not found", name)
+	}
+
+	var match *models.APIKey
+	for _, key := range s.keysCache {
+		if !strings.EqualFold(key.Name, name) {
+			continue
+		}
+		if match != nil {
+			return nil, fmt.Errorf("key name '%s' is ambiguous: matches both '%s' and '%s' ignoring case", name, match.Name, key.Name)
+		}
+		match = key
+	}
+	if match == nil {
+		return nil, fmt.Errorf("key '%s' not found", name)
+	}
+
+	fmt.Fprintf(os.Stderr, "⚠️  未找到 '%s'，按忽略大小写匹配到 '%s'\n", name, match.Name)
+	return match, nil
+}
+
 // GetKey returns the key metadata (not decrypted value).
 func (s *KeyStorage) GetKey(name string) *models.APIKey {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return s.keysCache[name]
+
+	key, err := s.lookupLocked(name)
+	if err != nil {
+		return nil
+	}
+	return key
 }
 
 // GetKeyValue returns the decrypted key value.
 func (s *KeyStorage) GetKeyValue(name, project string) (string, error) {
+	return s.GetKeyValueWithPassphrase(name, project, "")
+}
+
+// GetKeyValueWithPassphrase is GetKeyValue for keys that may be
+// passphrase-protected; passphrase is ignored for other keys.
+func (s *KeyStorage) GetKeyValueWithPassphrase(name, project, passphrase string) (string, error) {
 	s.mu.RLock()
-	key := s.keysCache[name]
+	key, err := s.lookupLocked(name)
 	s.mu.RUnlock()
 
-	if key == nil {
-		return "", fmt.Errorf("key '%s' not found", name)
+	if err != nil {
+		return "", err
 	}
+	name = key.Name
 
-	value, err := s.crypto.Decrypt(key.ValueEncrypted)
-	if err != nil {
-		return "", fmt.Errorf("failed to decrypt key '%s': %w", name, err)
+	if key.IsExpired(time.Now()) {
+		return "", expiredError(key)
+	}
+
+	// Protected keys always decrypt, so the passphrase is checked every time
+	cacheable := s.decrypted != nil && !key.PassphraseProtected
+	value, hit := "", false
+	if cacheable {
+		value, hit = s.decrypted.get(name, key.ValueEncrypted)
+	}
+	if !hit {
+		value, err = s.decryptValue(key, key.ValueEncrypted, passphrase)
+		if err != nil {
+			return "", fmt.Errorf("failed to decrypt key '%s': %w", name, err)
+		}
+		if cacheable {
+			s.decrypted.put(name, key.ValueEncrypted, value)
+		}
 	}
 
 	s.logUsage(name, "read", project)
 	return value, nil
 }
 
-// ListKeys returns all keys, optionally filtered by provider.
+// expiredError reports that key can no longer be read because it expired.
+func expiredError(key *models.APIKey) error {
+	return fmt.Errorf("key '%s' expired at %s", key.Name, key.ExpiresAt.Time.Format(time.RFC3339))
+}
+
+// MatchProvider reports whether provider matches pattern. An empty pattern
+// matches everything; a pattern containing glob metacharacters (*, ?, [...])
+// is matched with path.Match semantics, otherwise the match is exact.
+func MatchProvider(pattern, provider string) bool {
+	if pattern == "" {
+		return true
+	}
+	if !strings.ContainsAny(pattern, "*?[") {
+		return pattern == provider
+	}
+	ok, err := path.Match(pattern, provider)
+	return err == nil && ok
+}
+
+// ListKeys returns all keys sorted by name, optionally filtered by provider
+// (glob allowed).
 func (s *KeyStorage) ListKeys(provider string) []*models.APIKey {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	keys := make([]*models.APIKey, 0, len(s.keysCache))
 	for _, key := range s.keysCache {
-		if provider == "" || key.Provider == provider {
+		if MatchProvider(provider, key.Provider) {
 			keys = append(keys, key)
 		}
 	}
+	sortKeysByName(keys)
 	return keys
 }
 
-// SearchKeys searches keys by query string.
+// KeySortFields lists the orders SortKeys accepts.
+var KeySortFields = []string{"name", "provider", "created", "updated"}
+
+// SortKeys orders keys in place by name (case-insensitive), provider (then
+// name), or created/updated time (newest first). Ties always fall back to the
+// name, so the result is the same on every run.
+func SortKeys(keys []*models.APIKey, by string) error {
+	var less func(a, b *models.APIKey) bool
+	switch by {
+	case "", "name":
+		sortKeysByName(keys)
+		return nil
+	case "provider":
+		less = func(a, b *models.APIKey) bool {
+			return strings.ToLower(a.Provider) < strings.ToLower(b.Provider)
+		}
+	case "created":
+		less = func(a, b *models.APIKey) bool { return a.CreatedAt.Time.After(b.CreatedAt.Time) }
+	case "updated":
+		less = func(a, b *models.APIKey) bool { return a.UpdatedAt.Time.After(b.UpdatedAt.Time) }
+	default:
+		return fmt.Errorf("unknown sort field '%s' (valid: %s)", by, strings.Join(KeySortFields, ", "))
+	}
+	sortKeysByName(keys)
+	sort.SliceStable(keys, func(i, j int) bool { return less(keys[i], keys[j]) })
+	return nil
+}
+
+// sortKeysByName orders keys by case-insensitive name, exact name breaking ties.
+func sortKeysByName(keys []*models.APIKey) {
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := strings.ToLower(keys[i].Name), strings.ToLower(keys[j].Name)
+		if a != b {
+			return a < b
+		}
+		return keys[i].Name < keys[j].Name
+	})
+}
+
+// EachKey calls fn for each key, optionally filtered by provider, without
+// materializing a slice. Iteration stops early when fn returns false.
+func (s *KeyStorage) EachKey(provider string, fn func(*models.APIKey) bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, key := range s.keysCache {
+		if !MatchProvider(provider, key.Provider) {
+			continue
+		}
+		if !fn(key) {
+			return
+		}
+	}
+}
+
+// SearchKeys searches keys by query string, matching name, provider,
+// description, source project and tags case-insensitively. Results are
+// sorted by name.
 func (s *KeyStorage) SearchKeys(query string) []*models.APIKey {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -290,22 +759,36 @@ func (s *KeyStorage) SearchKeys(query string) []*models.APIKey {
 		if strings.Contains(strings.ToLower(key.Name), queryLower) ||
 			strings.Contains(strings.ToLower(key.Provider), queryLower) ||
 			(key.Description != nil && strings.Contains(strings.ToLower(*key.Description), queryLower)) ||
-			(key.SourceProject != nil && strings.Contains(strings.ToLower(*key.SourceProject), queryLower)) {
+			(key.SourceProject != nil && strings.Contains(strings.ToLower(*key.SourceProject), queryLower)) ||
+			tagsContain(key.Tags, queryLower) {
 			results = append(results, key)
 		}
 	}
+	sortKeysByName(results)
 	return results
 }
 
+// tagsContain reports whether any tag contains the lowercased query.
+func tagsContain(tags []string, queryLower string) bool {
+	for _, tag := range tags {
+		if strings.Contains(strings.ToLower(tag), queryLower) {
+			return true
+		}
+	}
+	return false
+}
+
 // UpdateKey updates key metadata (not the value).
 func (s *KeyStorage) UpdateKey(name string, updates map[string]interface{}) (*models.APIKey, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	key := s.keysCache[name]
-	if key == nil {
-		return nil, fmt.Errorf("key '%s' not found", name)
+	key, err := s.lookupLocked(name)
+	if err != nil {
+		return nil, err
 	}
+	name = key.Name
+	original := *key
 
 	// Apply updates
 	if v, ok := updates["provider"].(string); ok {
@@ -323,190 +806,1017 @@ func (s *KeyStorage) UpdateKey(name string, updates map[string]interface{}) (*mo
 	if v, ok := updates["is_active"].(bool); ok {
 		key.IsActive = v
 	}
+	if v, ok := updates["expires_at"].(time.Time); ok {
+		key.ExpiresAt = models.FlexTimePtr{Time: &v}
+	}
+	if v, ok := updates["model_name"].(string); ok {
+		key.ModelName = &v
+	}
+	if v, ok := updates["model_version"].(string); ok {
+		key.ModelVersion = &v
+	}
+	if v, ok := updates["model_capabilities"].([]string); ok {
+		key.ModelCapabilities = v
+	}
 
 	key.UpdatedAt = models.FlexTime{Time: time.Now()}
+	stampUpdatedBy(key)
 
 	if err := s.saveKeys(); err != nil {
+		*key = original // Rollback on failure
 		return nil, err
 	}
 
-	s.logUsage(name, "update", "system")
+	if err := s.auditMutation(name, "update", func() { *key = original }); err != nil {
+		return nil, err
+	}
 	return key, nil
 }
 
-// DeleteKey removes a key.
-func (s *KeyStorage) DeleteKey(name string) error {
+// MaxValueHistory caps how many previous values are kept per key.
+const MaxValueHistory = 5
+
+// RotateKeyValue replaces a key's value, keeping the old encrypted value in its history.
+func (s *KeyStorage) RotateKeyValue(name, value string) (*models.APIKey, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, exists := s.keysCache[name]; !exists {
-		return fmt.Errorf("key '%s' not found", name)
+	key, err := s.lookupLocked(name)
+	if err != nil {
+		return nil, err
+	}
+	name = key.Name
+	if key.PassphraseProtected {
+		// Mixing wrapped and unwrapped values in one history would be unreadable
+		return nil, fmt.Errorf("key '%s' is passphrase-protected: delete and re-add it with the new value", name)
 	}
 
-	delete(s.keysCache, name)
+	encrypted, err := s.crypto.Encrypt(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt key value: %w", err)
+	}
+
+	oldEncrypted, oldHistory, oldUpdated, oldUpdatedBy := key.ValueEncrypted, key.ValueHistory, key.UpdatedAt, key.UpdatedBy
+	now := time.Now()
+	key.ValueHistory = pushValueHistory(key.ValueHistory, key.ValueEncrypted, now)
+	key.ValueEncrypted = encrypted
+	key.UpdatedAt = models.FlexTime{Time: now}
+	stampUpdatedBy(key)
 
 	if err := s.saveKeys(); err != nil {
-		return err
+		key.ValueEncrypted, key.ValueHistory, key.UpdatedAt, key.UpdatedBy = oldEncrypted, oldHistory, oldUpdated, oldUpdatedBy // Rollback on failure
+		return nil, err
 	}
 
-	s.logUsage(name, "delete", "system")
-	return nil
-}
-
-// GetKeysForInjection returns decrypted keys for injection.
-func (s *KeyStorage) GetKeysForInjection(project, provider string, keyNames []string) (map[string]string, error) {
-	return s.getKeysBatch(project, provider, keyNames, "inject")
+	if err := s.auditMutation(name, "rotate", func() {
+		key.ValueEncrypted, key.ValueHistory, key.UpdatedAt, key.UpdatedBy = oldEncrypted, oldHistory, oldUpdated, oldUpdatedBy
+	}); err != nil {
+		return nil, err
+	}
+	s.invalidateDecrypted(name)
+	return key, nil
 }
 
-// GetKeysForExport returns decrypted keys for export.
-func (s *KeyStorage) GetKeysForExport(project, provider string, keyNames []string) (map[string]string, error) {
-	return s.getKeysBatch(project, provider, keyNames, "export")
-}
+// UpdateKeyValue replaces a key's value outright. Unlike RotateKeyValue the old
+// value is not kept in history, so it cannot be recovered afterwards.
+func (s *KeyStorage) UpdateKeyValue(name, value string) (*models.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-func (s *KeyStorage) getKeysBatch(project, provider string, keyNames []string, action string) (map[string]string, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	key, err := s.lookupLocked(name)
+	if err != nil {
+		return nil, err
+	}
+	name = key.Name
+	if key.PassphraseProtected {
+		return nil, fmt.Errorf("key '%s' is passphrase-protected: delete and re-add it with the new value", name)
+	}
 
-	keyNamesSet := make(map[string]bool)
-	for _, name := range keyNames {
-		keyNamesSet[name] = true
+	encrypted, err := s.crypto.Encrypt(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt key value: %w", err)
 	}
 
-	result := make(map[string]string)
-	for _, key := range s.keysCache {
-		// Filter by provider
-		if provider != "" && key.Provider != provider {
-			continue
-		}
-		// Filter by name list
-		if len(keyNames) > 0 && !keyNamesSet[key.Name] {
-			continue
-		}
+	oldEncrypted, oldUpdated, oldUpdatedBy := key.ValueEncrypted, key.UpdatedAt, key.UpdatedBy
+	key.ValueEncrypted = encrypted
+	key.UpdatedAt = models.FlexTime{Time: time.Now()}
+	stampUpdatedBy(key)
 
-		value, err := s.crypto.Decrypt(key.ValueEncrypted)
-		if err != nil {
-			return nil, fmt.Errorf("failed to decrypt key '%s': %w", key.Name, err)
-		}
-		result[key.Name] = value
-		s.logUsage(key.Name, action, project)
+	if err := s.saveKeys(); err != nil {
+		key.ValueEncrypted, key.UpdatedAt, key.UpdatedBy = oldEncrypted, oldUpdated, oldUpdatedBy // Rollback on failure
+		return nil, err
 	}
 
-	return result, nil
+	if err := s.auditMutation(name, "rotate", func() {
+		key.ValueEncrypted, key.UpdatedAt, key.UpdatedBy = oldEncrypted, oldUpdated, oldUpdatedBy
+	}); err != nil {
+		return nil, err
+	}
+	s.invalidateDecrypted(name)
+	return key, nil
+}
+
+// RollbackKeyValue restores the most recent previous value. The replaced current
+// value goes into history, so a rollback can itself be rolled back.
+func (s *KeyStorage) RollbackKeyValue(name string) (*models.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, err := s.lookupLocked(name)
+	if err != nil {
+		return nil, err
+	}
+	name = key.Name
+	if len(key.ValueHistory) == 0 {
+		return nil, fmt.Errorf("key '%s' has no previous value", name)
+	}
+
+	oldEncrypted, oldHistory, oldUpdated, oldUpdatedBy := key.ValueEncrypted, key.ValueHistory, key.UpdatedAt, key.UpdatedBy
+	now := time.Now()
+	previous := key.ValueHistory[0]
+	key.ValueHistory = pushValueHistory(key.ValueHistory[1:], key.ValueEncrypted, now)
+	key.ValueEncrypted = previous.ValueEncrypted
+	key.UpdatedAt = models.FlexTime{Time: now}
+	stampUpdatedBy(key)
+
+	if err := s.saveKeys(); err != nil {
+		key.ValueEncrypted, key.ValueHistory, key.UpdatedAt, key.UpdatedBy = oldEncrypted, oldHistory, oldUpdated, oldUpdatedBy // Rollback on failure
+		return nil, err
+	}
+
+	if err := s.auditMutation(name, "rollback", func() {
+		key.ValueEncrypted, key.ValueHistory, key.UpdatedAt, key.UpdatedBy = oldEncrypted, oldHistory, oldUpdated, oldUpdatedBy
+	}); err != nil {
+		return nil, err
+	}
+	s.invalidateDecrypted(name)
+	return key, nil
+}
+
+// GetKeyValueVersion returns a decrypted value by version: 0 is current,
+// 1 is the previous value, and so on. passphrase is used only for
+// passphrase-protected keys.
+func (s *KeyStorage) GetKeyValueVersion(name string, version int, project, passphrase string) (string, error) {
+	if version == 0 {
+		return s.GetKeyValueWithPassphrase(name, project, passphrase)
+	}
+
+	s.mu.RLock()
+	key, err := s.lookupLocked(name)
+	var encrypted string
+	if err == nil && version > 0 && version <= len(key.ValueHistory) {
+		encrypted = key.ValueHistory[version-1].ValueEncrypted
+	}
+	s.mu.RUnlock()
+
+	if err != nil {
+		return "", err
+	}
+	name = key.Name
+	if key.IsExpired(time.Now()) {
+		return "", expiredError(key)
+	}
+	if encrypted == "" {
+		return "", fmt.Errorf("key '%s' has no version %d", name, version)
+	}
+
+	value, err := s.decryptValue(key, encrypted, passphrase)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt key '%s' version %d: %w", name, version, err)
+	}
+
+	s.logUsage(name, "read", project)
+	return value, nil
+}
+
+// pushValueHistory prepends an encrypted value, trimming to MaxValueHistory.
+// It always returns a new slice so callers can restore the old one on failure.
+func pushValueHistory(history []models.KeyValueVersion, encrypted string, at time.Time) []models.KeyValueVersion {
+	result := make([]models.KeyValueVersion, 0, len(history)+1)
+	result = append(result, models.KeyValueVersion{ValueEncrypted: encrypted, ReplacedAt: models.FlexTime{Time: at}})
+	result = append(result, history...)
+	if len(result) > MaxValueHistory {
+		result = result[:MaxValueHistory]
+	}
+	return result
+}
+
+// reencryptedRecord is a key's value and history encrypted under another master key.
+type reencryptedRecord struct {
+	value   string
+	history []models.KeyValueVersion
+	source  string // master key the current value was decrypted with
+}
+
+// reencryptRecord decrypts key's value and history with the storage crypto
+// (current or previous master key) and encrypts them under target, checking
+// that target decrypts each result back to the same plaintext.
+func (s *KeyStorage) reencryptRecord(key *models.APIKey, target *KeyEncryption) (*reencryptedRecord, error) {
+	reencrypt := func(encrypted, label string) (string, string, error) {
+		plain, source, err := s.crypto.DecryptWithSource(encrypted)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to decrypt %s: %w", label, err)
+		}
+		out, err := target.Encrypt(plain)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to encrypt key value: %w", err)
+		}
+		if check, err := target.Decrypt(out); err != nil || check != plain {
+			return "", "", fmt.Errorf("round-trip check failed for %s", label)
+		}
+		return out, source, nil
+	}
+
+	value, source, err := reencrypt(key.ValueEncrypted, "value")
+	if err != nil {
+		return nil, err
+	}
+
+	// Previous values must move too, or they become unreadable once the old key is retired
+	history := make([]models.KeyValueVersion, len(key.ValueHistory))
+	for i, v := range key.ValueHistory {
+		encrypted, _, err := reencrypt(v.ValueEncrypted, fmt.Sprintf("version %d", i+1))
+		if err != nil {
+			return nil, err
+		}
+		history[i] = models.KeyValueVersion{ValueEncrypted: encrypted, ReplacedAt: v.ReplacedAt}
+	}
+
+	return &reencryptedRecord{value: value, history: history, source: source}, nil
+}
+
+// ReencryptKey re-encrypts a single key under the current master key and
+// returns which master key it was decrypted with (see DecryptWithSource).
+func (s *KeyStorage) ReencryptKey(name string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, err := s.lookupLocked(name)
+	if err != nil {
+		return "", err
+	}
+	name = key.Name
+
+	record, err := s.reencryptRecord(key, s.crypto)
+	if err != nil {
+		return "", fmt.Errorf("key '%s': %w", name, err)
+	}
+
+	oldEncrypted, oldHistory := key.ValueEncrypted, key.ValueHistory
+	key.ValueEncrypted = record.value
+	if len(record.history) > 0 {
+		key.ValueHistory = record.history
+	}
+	if err := s.saveKeys(); err != nil {
+		key.ValueEncrypted, key.ValueHistory = oldEncrypted, oldHistory // Rollback on failure
+		return "", err
+	}
+
+	if err := s.auditMutation(name, "rekey", func() {
+		key.ValueEncrypted, key.ValueHistory = oldEncrypted, oldHistory
+	}); err != nil {
+		return "", err
+	}
+	return record.source, nil
+}
+
+// ReencryptReport summarizes a whole-store re-encryption.
+type ReencryptReport struct {
+	Total    int
+	Rotated  int              // records that re-encrypted and round-tripped
+	Failures map[string]error // key name -> reason
+}
+
+// reencryptAllLocked re-encrypts every key, and every restorable key in the
+// trash, under target in memory only. Trash failures are reported as
+// "NAME (trash)". Caller must hold s.mu.
+func (s *KeyStorage) reencryptAllLocked(target *KeyEncryption) (records, trashRecords map[string]*reencryptedRecord, report *ReencryptReport) {
+	trashed := s.liveTrashLocked(time.Now())
+	report = &ReencryptReport{Total: len(s.keysCache) + len(trashed), Failures: make(map[string]error)}
+	records = make(map[string]*reencryptedRecord, len(s.keysCache))
+	for name, key := range s.keysCache {
+		record, err := s.reencryptRecord(key, target)
+		if err != nil {
+			report.Failures[name] = err
+			continue
+		}
+		records[name] = record
+		report.Rotated++
+	}
+	trashRecords = make(map[string]*reencryptedRecord, len(trashed))
+	for _, key := range trashed {
+		record, err := s.reencryptRecord(key, target)
+		if err != nil {
+			report.Failures[key.Name+" (trash)"] = err
+			continue
+		}
+		trashRecords[key.Name] = record
+		report.Rotated++
+	}
+	return records, trashRecords, report
+}
+
+// RotateMasterKey generates a new master key and re-encrypts every key and
+// keys.json under it. The old key is kept in keychain as the previous key, so
+// anything not yet migrated (including audit signatures) stays readable.
+// Nothing is written if any record fails. With dryRun the new key only exists
+// in memory: keys.json and the keychain are left untouched.
+func (s *KeyStorage) RotateMasterKey(dryRun bool) (*ReencryptReport, error) {
+	newKey := fernet.Key{}
+	if err := newKey.Generate(); err != nil {
+		return nil, fmt.Errorf("failed to generate master key: %w", err)
+	}
+	target := &KeyEncryption{masterKey: &newKey}
+
+	if !dryRun {
+		if err := s.autoBackup("master-key-rotate"); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records, trashRecords, report := s.reencryptAllLocked(target)
+	if dryRun {
+		return report, nil
+	}
+	if len(report.Failures) > 0 {
+		return report, fmt.Errorf("%d keys failed to re-encrypt; nothing was changed", len(report.Failures))
+	}
+
+	if err := s.crypto.promote(&newKey); err != nil {
+		return report, err
+	}
+
+	type applied struct {
+		key *models.APIKey
+		old reencryptedRecord
+	}
+	var changed []applied
+	apply := func(key *models.APIKey, record *reencryptedRecord) {
+		changed = append(changed, applied{key, reencryptedRecord{value: key.ValueEncrypted, history: key.ValueHistory}})
+		key.ValueEncrypted = record.value
+		if len(record.history) > 0 {
+			key.ValueHistory = record.history
+		}
+	}
+	for name, record := range records {
+		apply(s.keysCache[name], record)
+	}
+	for name, record := range trashRecords {
+		apply(s.trash[name], record)
+	}
+	if err := s.saveKeys(); err != nil {
+		// Old ciphertexts remain readable through the previous master key
+		for _, c := range changed {
+			c.key.ValueEncrypted, c.key.ValueHistory = c.old.value, c.old.history
+		}
+		return report, err
+	}
+
+	s.logUsage("*", "master-key-rotate", "system")
+
+	// Tokens stay readable through the previous key if this fails; retiring
+	// it is refused until tokens.json has been rewritten
+	tokensErr := s.reencryptTokens()
+
+	// Team members only ever get the new key; removed members are not re-wrapped
+	if err := s.rewrapMembers(&newKey); err != nil {
+		return report, fmt.Errorf("master key rotated, but re-wrapping it for team members failed (re-add them with 'akm member add'): %w", err)
+	}
+	if tokensErr != nil {
+		return report, fmt.Errorf("master key rotated, but re-encrypting tokens.json failed: %w", tokensErr)
+	}
+	return report, nil
+}
+
+// RetirePreviousMasterKey ends the transition window after a rotation by
+// deleting the previous master key. It refuses while any value or history
+// entry still only decrypts under the previous key, naming those keys, while
+// tokens.json does, or while audit entries are still signed with it (they
+// would then verify as tampered; CompactAuditLog re-signs them).
+func (s *KeyStorage) RetirePreviousMasterKey() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.crypto.HasPreviousKey() {
+		return fmt.Errorf("no previous master key is stored")
+	}
+
+	needsPrevious := func(key *models.APIKey) bool {
+		ok := s.crypto.DecryptsWithPrimary(key.ValueEncrypted)
+		for _, v := range key.ValueHistory {
+			ok = ok && s.crypto.DecryptsWithPrimary(v.ValueEncrypted)
+		}
+		return !ok
+	}
+	var pending []string
+	for name, key := range s.keysCache {
+		if needsPrevious(key) {
+			pending = append(pending, name)
+		}
+	}
+	// Restorable keys must stay readable too; emptying the trash also works
+	for _, key := range s.liveTrashLocked(time.Now()) {
+		if needsPrevious(key) {
+			pending = append(pending, key.Name+" (trash)")
+		}
+	}
+	if len(pending) > 0 {
+		sort.Strings(pending)
+		return fmt.Errorf("%d keys still need the previous master key (rekey them first; restore or purge trashed ones): %s",
+			len(pending), strings.Join(pending, ", "))
+	}
+	if pending, err := s.tokensNeedPreviousKey(); err != nil {
+		return err
+	} else if pending {
+		return fmt.Errorf("tokens.json still needs the previous master key (create or revoke a token to rewrite it)")
+	}
+	if signed, err := s.auditEntriesSignedWithPrevious(); err != nil {
+		return err
+	} else if signed > 0 {
+		return fmt.Errorf("%d audit entries are signed with the previous master key (run 'akm audit compact' first)", signed)
+	}
+
+	if err := s.crypto.dropPreviousKey(); err != nil {
+		return err
+	}
+	s.logUsage("*", "master-key-retire-previous", "system")
+	return nil
+}
+
+// DeleteKey moves a key to the trash, from which RestoreKey can bring it back
+// within TrashRetention. A later delete of the same name replaces the older
+// trash entry. With retention 0 the key is removed permanently.
+func (s *KeyStorage) DeleteKey(name string) error {
+	if err := s.autoBackup("delete"); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, err := s.lookupLocked(name)
+	if err != nil {
+		return err
+	}
+	name = key.Name
+
+	undo := s.trashLocked(key, time.Now())
+	if err := s.saveKeys(); err != nil {
+		undo() // Rollback on failure
+		return err
+	}
+
+	if err := s.auditMutation(name, "delete", undo); err != nil {
+		return err
+	}
+	s.invalidateDecrypted(name)
+	return nil
+}
+
+// trashLocked moves key from the live set into the trash (or drops it when
+// retention is 0) and returns a func that undoes the move. Caller must hold
+// s.mu and save.
+func (s *KeyStorage) trashLocked(key *models.APIKey, now time.Time) func() {
+	name := key.Name
+	previous, hadPrevious := s.trash[name]
+	delete(s.keysCache, name)
+	if TrashRetention() > 0 {
+		trashed := *key
+		trashed.DeletedAt = &models.FlexTime{Time: now}
+		s.trash[name] = &trashed
+	}
+	return func() {
+		s.keysCache[name] = key
+		if hadPrevious {
+			s.trash[name] = previous
+		} else {
+			delete(s.trash, name)
+		}
+	}
+}
+
+// PruneFilter selects keys for Prune. Set selectors combine with OR.
+type PruneFilter struct {
+	Inactive bool // keys with IsActive == false
+	Expired  bool // keys whose ExpiresAt is in the past
+}
+
+func (f PruneFilter) matches(key *models.APIKey, now time.Time) bool {
+	if f.Inactive && !key.IsActive {
+		return true
+	}
+	if f.Expired && key.IsExpired(now) {
+		return true
+	}
+	return false
+}
+
+// PruneCandidates returns the keys Prune would remove, without removing them.
+func (s *KeyStorage) PruneCandidates(filter PruneFilter) []*models.APIKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	var matched []*models.APIKey
+	for _, key := range s.keysCache {
+		if filter.matches(key, now) {
+			matched = append(matched, key)
+		}
+	}
+	return matched
+}
+
+// Prune moves all keys matching filter to the trash in a single save and
+// returns them. Like DeleteKey, they can be restored within TrashRetention.
+func (s *KeyStorage) Prune(filter PruneFilter) ([]*models.APIKey, error) {
+	if len(s.PruneCandidates(filter)) == 0 {
+		return nil, nil
+	}
+	if err := s.autoBackup("prune"); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var removed []*models.APIKey
+	for _, key := range s.keysCache {
+		if filter.matches(key, now) {
+			removed = append(removed, key)
+		}
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+	undos := make([]func(), len(removed))
+	for i, key := range removed {
+		undos[i] = s.trashLocked(key, now)
+	}
+
+	restore := func() {
+		for _, undo := range undos {
+			undo()
+		}
+	}
+	if err := s.saveKeys(); err != nil {
+		restore() // Rollback on failure
+		return nil, err
+	}
+
+	for _, key := range removed {
+		if err := s.auditMutation(key.Name, "prune", restore); err != nil {
+			return nil, err
+		}
+	}
+	for _, key := range removed {
+		s.invalidateDecrypted(key.Name)
+	}
+	return removed, nil
+}
+
+// KeyFilter selects keys for bulk operations. Set fields combine with AND;
+// Provider may be a glob (see MatchProvider).
+type KeyFilter struct {
+	Provider string
+	Name     string
+	Tag      string
+}
+
+func (f KeyFilter) matches(key *models.APIKey) bool {
+	return MatchProvider(f.Provider, key.Provider) &&
+		(f.Name == "" || key.Name == f.Name) &&
+		(f.Tag == "" || hasTag(key, f.Tag))
+}
+
+// AddTagsWhere adds tags to every key matching filter in a single save and
+// returns the keys that changed. Tags already present (ignoring case) are skipped.
+func (s *KeyStorage) AddTagsWhere(filter KeyFilter, tags []string) ([]*models.APIKey, error) {
+	return s.updateTagsWhere(filter, "tag", func(current []string) []string {
+		result := current
+		for _, tag := range tags {
+			if !containsFold(result, tag) {
+				result = append(result, tag)
+			}
+		}
+		return result
+	})
+}
+
+// RemoveTagsWhere removes tags (ignoring case) from every key matching filter
+// in a single save and returns the keys that changed.
+func (s *KeyStorage) RemoveTagsWhere(filter KeyFilter, tags []string) ([]*models.APIKey, error) {
+	return s.updateTagsWhere(filter, "untag", func(current []string) []string {
+		var result []string
+		for _, t := range current {
+			if !containsFold(tags, t) {
+				result = append(result, t)
+			}
+		}
+		return result
+	})
+}
+
+// updateTagsWhere applies edit to the tags of matching keys, saving once and
+// writing one audit entry per changed key.
+func (s *KeyStorage) updateTagsWhere(filter KeyFilter, action string, edit func([]string) []string) ([]*models.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	type change struct {
+		key     *models.APIKey
+		tags    []string
+		updated models.FlexTime
+	}
+	var changes []change
+	now := models.FlexTime{Time: time.Now()}
+	for _, key := range s.keysCache {
+		if !filter.matches(key) {
+			continue
+		}
+		// edit may append in place; give it a copy so the old slice stays intact
+		newTags := edit(append([]string(nil), key.Tags...))
+		if len(newTags) == len(key.Tags) {
+			continue // add/remove only ever grow or shrink the list
+		}
+		changes = append(changes, change{key: key, tags: key.Tags, updated: key.UpdatedAt})
+		key.Tags = newTags
+		key.UpdatedAt = now
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	restore := func() {
+		for _, c := range changes {
+			c.key.Tags, c.key.UpdatedAt = c.tags, c.updated
+		}
+	}
+	if err := s.saveKeys(); err != nil {
+		restore() // Rollback on failure
+		return nil, err
+	}
+
+	updated := make([]*models.APIKey, 0, len(changes))
+	for _, c := range changes {
+		if err := s.auditMutation(c.key.Name, action, restore); err != nil {
+			return nil, err
+		}
+		updated = append(updated, c.key)
+	}
+	return updated, nil
+}
+
+// containsFold reports whether list contains s, ignoring case.
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetKeysForInjection returns decrypted keys for injection.
+// Provider, tag, and name filters combine with AND; empty filters match all.
+func (s *KeyStorage) GetKeysForInjection(project, provider, tag string, keyNames []string) (map[string]string, error) {
+	return s.getKeysBatch(project, provider, tag, keyNames, "inject")
+}
+
+// GetKeysForExport returns decrypted keys for export.
+// Provider, tag, and name filters combine with AND; empty filters match all.
+func (s *KeyStorage) GetKeysForExport(project, provider, tag string, keyNames []string) (map[string]string, error) {
+	return s.getKeysBatch(project, provider, tag, keyNames, "export")
+}
+
+// GetKeysForExportPartial is GetKeysForExport that keeps going past keys that
+// fail to decrypt (e.g. encrypted under a retired master key): it returns the
+// keys that did decrypt plus a map of failures by key name.
+func (s *KeyStorage) GetKeysForExportPartial(project, provider, tag string, keyNames []string) (map[string]string, map[string]error) {
+	failures := make(map[string]error)
+	result, _ := s.collectKeys(project, provider, tag, keyNames, "export", failures)
+	return result, failures
+}
+
+// GetKeysForRead returns decrypted keys for a bulk read, audited per key as
+// "read" like a single get.
+func (s *KeyStorage) GetKeysForRead(project, provider, tag string) (map[string]string, error) {
+	return s.getKeysBatch(project, provider, tag, nil, "read")
+}
+
+// hasTag reports whether key carries tag (case-insensitive).
+func hasTag(key *models.APIKey, tag string) bool {
+	for _, t := range key.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *KeyStorage) getKeysBatch(project, provider, tag string, keyNames []string, action string) (map[string]string, error) {
+	return s.collectKeys(project, provider, tag, keyNames, action, nil)
+}
+
+// collectKeys decrypts the matching keys. A decrypt failure aborts the batch
+// unless failures is non-nil, in which case it is recorded there and skipped.
+func (s *KeyStorage) collectKeys(project, provider, tag string, keyNames []string, action string, failures map[string]error) (map[string]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keyNamesSet := make(map[string]bool)
+	for _, name := range keyNames {
+		keyNamesSet[name] = true
+	}
+
+	now := time.Now()
+	result := make(map[string]string)
+	for _, key := range s.keysCache {
+		// Filter by provider
+		if !MatchProvider(provider, key.Provider) {
+			continue
+		}
+		// Filter by tag
+		if tag != "" && !hasTag(key, tag) {
+			continue
+		}
+		// Filter by name list
+		if len(keyNames) > 0 && !keyNamesSet[key.Name] {
+			continue
+		}
+
+		if key.PassphraseProtected {
+			fmt.Fprintf(os.Stderr, "⚠️  跳过需要口令的密钥 '%s'\n", key.Name)
+			continue
+		}
+		if key.IsExpired(now) {
+			fmt.Fprintf(os.Stderr, "⚠️  跳过已过期的密钥 '%s' (%s)\n", key.Name, key.ExpiresAt.Time.Format("2006-01-02 15:04"))
+			continue
+		}
+
+		value, err := s.crypto.Decrypt(key.ValueEncrypted)
+		if err != nil {
+			if failures != nil {
+				failures[key.Name] = err
+				continue
+			}
+			return nil, fmt.Errorf("failed to decrypt key '%s': %w", key.Name, err)
+		}
+		result[key.Name] = value
+		s.logUsage(key.Name, action, project)
+	}
+
+	return result, nil
 }
 
 // AuditErrors tracks audit log write failures (use atomic operations).
 var AuditErrors atomic.Int64
 
-// logUsage writes an audit log entry.
-func (s *KeyStorage) logUsage(keyName, action, project string) {
-	log := models.NewKeyUsageLog(keyName, project, action)
+// auditMu serializes audit file appends against whole-file rewrites.
+var auditMu sync.Mutex
 
-	// Sign the log entry
+// auditSigningPayload returns the canonical message signed for an audit entry.
+// prev_hash is only part of it when set, so legacy signatures still verify.
+func auditSigningPayload(log *models.KeyUsageLog) string {
 	logJSON, _ := json.Marshal(struct {
-		KeyName   string `json:"key_name"`
-		Project   string `json:"project"`
-		Action    string `json:"action"`
-		Timestamp string `json:"timestamp"`
+		KeyName   string  `json:"key_name"`
+		Project   string  `json:"project"`
+		Action    string  `json:"action"`
+		Timestamp string  `json:"timestamp"`
+		PrevHash  *string `json:"prev_hash,omitempty"`
 	}{
 		KeyName:   log.KeyName,
 		Project:   log.Project,
 		Action:    log.Action,
 		Timestamp: log.Timestamp.Format(time.RFC3339Nano),
+		PrevHash:  log.PrevHash,
 	})
+	return string(logJSON)
+}
 
-	signature, _ := s.crypto.SignMessage(string(logJSON))
-	log.Signature = &signature
+// auditTailSize bounds how much of the audit file is read to find the last entry.
+const auditTailSize = 64 << 10
 
-	// Append to audit file
-	f, err := os.OpenFile(s.auditFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+// lastAuditSignature returns the signature of the last entry in the audit
+// file, or nil when the file is empty or the entry is unsigned. Caller must
+// hold auditMu. Read from disk each time, so other processes' appends chain.
+func (s *KeyStorage) lastAuditSignature() (*string, error) {
+	f, err := os.Open(s.auditFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
 	if err != nil {
-		cnt := AuditErrors.Add(1)
-		fmt.Fprintf(os.Stderr, "⚠️  审计日志写入失败 (累计 %d 次): %v\n", cnt, err)
-		return
+		return nil, err
 	}
 	defer f.Close()
 
-	logBytes, _ := json.Marshal(log)
-	if _, err := f.Write(logBytes); err != nil {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	offset := max(info.Size()-auditTailSize, 0)
+	buf := make([]byte, info.Size()-offset)
+	if _, err := f.ReadAt(buf, offset); err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(buf)), "\n")
+	last := lines[len(lines)-1]
+	if last == "" {
+		return nil, nil
+	}
+	var log models.KeyUsageLog
+	if err := json.Unmarshal([]byte(last), &log); err != nil {
+		// A mangled tail is reported by verification; start a new chain link anyway
+		return nil, nil
+	}
+	if log.Signature == nil || *log.Signature == "" {
+		return nil, nil
+	}
+	return log.Signature, nil
+}
+
+// auditStrict reports whether AKM_AUDIT_STRICT is set, making a failed audit
+// write fail (and roll back) the mutation it records. Default is best-effort.
+func auditStrict() bool {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv("AKM_AUDIT_STRICT"))) {
+	case "1", "true", "yes", "on":
+		return true
+	}
+	return false
+}
+
+// auditMutation logs a mutation and publishes it to OnChange observers. In
+// strict mode a failed audit write instead runs undo, saves the restored
+// state, and returns an error. Caller must hold s.mu.
+func (s *KeyStorage) auditMutation(keyName, action string, undo func()) error {
+	err := s.logUsage(keyName, action, "system")
+	if err == nil || !auditStrict() {
+		s.publishMutation(keyName, action)
+		return nil
+	}
+	undo()
+	if saveErr := s.saveKeys(); saveErr != nil {
+		return fmt.Errorf("audit write failed (%v) and rollback failed: %w", err, saveErr)
+	}
+	return fmt.Errorf("audit write failed, operation rolled back: %w", err)
+}
+
+// logUsage writes an audit log entry. Failures are counted and reported on
+// stderr; the error is returned for callers that enforce strict auditing.
+// A panic while auditing is converted into such a failure.
+func (s *KeyStorage) logUsage(keyName, action, project string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			cnt := AuditErrors.Add(1)
+			fmt.Fprintf(os.Stderr, "⚠️  审计日志写入失败 (累计 %d 次): %v\n", cnt, r)
+			err = fmt.Errorf("audit write panicked: %v", r)
+		}
+	}()
+
+	log := models.NewKeyUsageLog(keyName, project, action)
+	logBytes, err := s.appendAuditEntry(log)
+	if err != nil {
 		cnt := AuditErrors.Add(1)
 		fmt.Fprintf(os.Stderr, "⚠️  审计日志写入失败 (累计 %d 次): %v\n", cnt, err)
-		return
+		return err
 	}
-	f.WriteString("\n")
+
+	// The local file is authoritative; the external sink is best-effort
+	// unless strict auditing asks callers to treat its failure as fatal
+	return shipAuditEntry(logBytes)
 }
 
-// VerifyAuditLogs verifies the integrity of audit logs.
-func (s *KeyStorage) VerifyAuditLogs() (total, verified, unsigned, tampered int, err error) {
-	data, err := os.ReadFile(s.auditFile)
-	if os.IsNotExist(err) {
-		return 0, 0, 0, 0, nil
+// appendAuditEntry links log to the current last entry, signs it and appends
+// it to the local audit file, returning the line written.
+func (s *KeyStorage) appendAuditEntry(log *models.KeyUsageLog) ([]byte, error) {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+
+	prev, err := s.lastAuditSignature()
+	if err != nil {
+		return nil, err
 	}
+	log.PrevHash = prev
+
+	signature, _ := s.crypto.SignMessage(auditSigningPayload(log))
+	log.Signature = &signature
+	line, err := json.Marshal(log)
 	if err != nil {
-		return 0, 0, 0, 0, err
+		return nil, err
 	}
 
-	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
-	for _, line := range lines {
-		if line == "" {
-			continue
-		}
-		total++
+	f, err := os.OpenFile(s.auditFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, s.filePerm)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
 
-		var log models.KeyUsageLog
-		if err := json.Unmarshal([]byte(line), &log); err != nil {
-			tampered++
-			continue
-		}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return nil, err
+	}
+	return line, nil
+}
 
-		if log.Signature == nil || *log.Signature == "" {
-			unsigned++
-			continue
+// VerifyAuditLogs verifies the integrity of audit logs. Besides each entry's
+// own signature it checks the chain: brokenChain counts entries whose
+// prev_hash does not match the signature of the line before them, which is
+// what a deleted or reordered entry leaves behind. The first line starts the
+// chain, so a trailing slice of the log (e.g. a --since backup) still verifies.
+func (s *KeyStorage) VerifyAuditLogs() (total, verified, unsigned, tampered, brokenChain int, err error) {
+	entries, err := s.VerifyAuditEntries()
+	if err != nil {
+		return 0, 0, 0, 0, 0, err
+	}
+	for _, e := range entries {
+		total++
+		if e.BrokenChain {
+			brokenChain++
 		}
-
-		// Verify signature
-		logJSON, _ := json.Marshal(struct {
-			KeyName   string `json:"key_name"`
-			Project   string `json:"project"`
-			Action    string `json:"action"`
-			Timestamp string `json:"timestamp"`
-		}{
-			KeyName:   log.KeyName,
-			Project:   log.Project,
-			Action:    log.Action,
-			Timestamp: log.Timestamp.Format(time.RFC3339Nano),
-		})
-
-		valid, _ := s.crypto.VerifySignature(string(logJSON), *log.Signature)
-		if valid {
+		switch e.Status {
+		case AuditEntryValid:
 			verified++
-		} else {
+		case AuditEntryUnsigned:
+			unsigned++
+		default:
 			tampered++
 		}
 	}
-
-	return total, verified, unsigned, tampered, nil
+	return total, verified, unsigned, tampered, brokenChain, nil
 }
 
-// Backup creates a backup of keys and audit logs.
-func (s *KeyStorage) Backup(backupDir string) error {
-	if err := os.MkdirAll(backupDir, 0700); err != nil {
+// Backup creates a backup of keys and audit logs. If since is non-zero, only
+// audit entries at or after since are copied; keys.json is always copied in full.
+// Audit entries are signed individually, so a filtered copy still verifies.
+func (s *KeyStorage) Backup(backupDir string, since time.Time) error {
+	if err := os.MkdirAll(backupDir, s.dirPerm); err != nil {
 		return err
 	}
 
 	// Copy keys file
 	if data, err := os.ReadFile(s.keysFile); err == nil {
-		if err := os.WriteFile(filepath.Join(backupDir, "keys.json"), data, 0600); err != nil {
+		if err := os.WriteFile(filepath.Join(backupDir, "keys.json"), data, s.filePerm); err != nil {
 			return err
 		}
 	}
 
 	// Copy audit file
-	if data, err := os.ReadFile(s.auditFile); err == nil {
-		if err := os.WriteFile(filepath.Join(backupDir, "audit.jsonl"), data, 0600); err != nil {
-			return err
+	if since.IsZero() {
+		if data, err := os.ReadFile(s.auditFile); err == nil {
+			if err := os.WriteFile(filepath.Join(backupDir, "audit.jsonl"), data, s.filePerm); err != nil {
+				return err
+			}
 		}
+	} else if err := s.copyAuditSince(filepath.Join(backupDir, "audit.jsonl"), since); err != nil {
+		return err
 	}
 
 	s.logUsage("*", "backup", "system")
 	return nil
 }
+
+// copyAuditSince streams audit entries with a timestamp at or after since to dest.
+// Lines that cannot be parsed are kept so tampering stays visible in the backup.
+func (s *KeyStorage) copyAuditSince(dest string, since time.Time) error {
+	src, err := os.Open(s.auditFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, s.filePerm)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(out)
+
+	scanner := bufio.NewScanner(src)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var entry models.KeyUsageLog
+		if err := json.Unmarshal(line, &entry); err == nil && entry.Timestamp.Before(since) {
+			continue
+		}
+		w.Write(line)
+		w.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to read audit log: %w", err)
+	}
+	if err := w.Flush(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
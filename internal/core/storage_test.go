@@ -0,0 +1,264 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fernet/fernet-go"
+)
+
+// newTestKeyEncryption returns a KeyEncryption with a fresh random master key,
+// never touching the system keychain.
+func newTestKeyEncryption(t testing.TB) *KeyEncryption {
+	t.Helper()
+	var key fernet.Key
+	if err := key.Generate(); err != nil {
+		t.Fatal(err)
+	}
+	kc, err := NewKeyEncryption(key.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kc
+}
+
+// newTestStorage returns a KeyStorage in a temp directory with its own crypto.
+func newTestStorage(t testing.TB, opts ...StorageOption) *KeyStorage {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	opts = append([]StorageOption{WithCrypto(newTestKeyEncryption(t))}, opts...)
+	s, err := NewKeyStorage(filepath.Join(t.TempDir(), "data"), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestNewKeyStorageOptions(t *testing.T) {
+	kc := newTestKeyEncryption(t)
+	auditFile := filepath.Join(t.TempDir(), "audit.jsonl")
+	dataDir := filepath.Join(t.TempDir(), "data")
+	t.Setenv("HOME", t.TempDir())
+
+	s, err := NewKeyStorage(dataDir, WithCrypto(kc), WithFilePerm(0640), WithDirPerm(0750), WithAuditFile(auditFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.crypto != kc {
+		t.Fatal("WithCrypto was not applied")
+	}
+	if _, err := s.AddKey("OPENAI_API_KEY", "sk-test-0123456789abcdef", "openai"); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := info.Mode().Perm(); got != 0750 {
+		t.Errorf("data dir mode = %o, want 750", got)
+	}
+	info, err = os.Stat(filepath.Join(dataDir, "keys.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := info.Mode().Perm(); got != 0640 {
+		t.Errorf("keys.json mode = %o, want 640", got)
+	}
+	if _, err := os.Stat(auditFile); err != nil {
+		t.Errorf("audit log not written to WithAuditFile path: %v", err)
+	}
+
+	// The injected crypto is what the file is encrypted with
+	reopened, err := NewKeyStorage(dataDir, WithCrypto(kc), WithAuditFile(auditFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := reopened.GetKeyValue("OPENAI_API_KEY", "test")
+	if err != nil || value != "sk-test-0123456789abcdef" {
+		t.Fatalf("GetKeyValue = %q, %v", value, err)
+	}
+}
+
+func TestSearchKeysMatchesTags(t *testing.T) {
+	s := newTestStorage(t)
+	if _, err := s.AddKey("OPENAI_API_KEY", "sk-test-0123456789abcdef", "openai", WithTags([]string{"Prod", "team-a"})); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AddKey("ANTHROPIC_API_KEY", "sk-ant-REDACTED", "anthropic", WithTags([]string{"dev"})); err != nil {
+		t.Fatal(err)
+	}
+
+	results := s.SearchKeys("prod")
+	if len(results) != 1 || results[0].Name != "OPENAI_API_KEY" {
+		names := make([]string, len(results))
+		for i, k := range results {
+			names[i] = k.Name
+		}
+		t.Errorf("SearchKeys(prod) = %v, want [OPENAI_API_KEY]", names)
+	}
+}
+
+// Expired keys are refused on every read path, including old versions and
+// project injection, which decrypt without going through GetKeyValue.
+func TestExpiredKeyNotReadOrInjected(t *testing.T) {
+	s := newTestStorage(t)
+	past := time.Now().Add(-time.Hour)
+	if _, err := s.AddKey("OLD_OPENAI_KEY", "sk-old-0123456789abcdef", "openai", WithExpiresAt(time.Now().Add(time.Hour))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.RotateKeyValue("OLD_OPENAI_KEY", "sk-new-0123456789abcdef"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.UpdateKey("OLD_OPENAI_KEY", map[string]interface{}{"expires_at": past}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.GetKeyValueVersion("OLD_OPENAI_KEY", 1, "test", ""); err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Errorf("GetKeyValueVersion(1) error = %v, want expired", err)
+	}
+
+	plain := &ProjectConfig{Keys: []ProjectKey{{Env: "OLD_OPENAI_KEY", Source: "OLD_OPENAI_KEY"}}}
+	if _, err := s.ResolveProjectKeys("test", plain); err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Errorf("ResolveProjectKeys(plain) error = %v, want expired", err)
+	}
+
+	// A provider reference skips the expired key for an unexpired one
+	ref := &ProjectConfig{Keys: []ProjectKey{{Env: "OPENAI_API_KEY", Source: "${provider:openai}"}}}
+	if _, err := s.ResolveProjectKeys("test", ref); err == nil {
+		t.Error("ResolveProjectKeys(ref) resolved to an expired key")
+	}
+	if _, err := s.AddKey("ZZ_OPENAI_KEY", "sk-live-0123456789abcdef", "openai"); err != nil {
+		t.Fatal(err)
+	}
+	values, err := s.ResolveProjectKeys("test", ref)
+	if err != nil || values["OPENAI_API_KEY"] != "sk-live-0123456789abcdef" {
+		t.Errorf("ResolveProjectKeys(ref) = %v, %v; want the unexpired key", values, err)
+	}
+}
+
+// Case-insensitive name matching is opt-in, since it also picks the key that
+// delete and update act on.
+func TestCaseInsensitiveLookupOptIn(t *testing.T) {
+	s := newTestStorage(t)
+	if _, err := s.AddKey("OPENAI_API_KEY", "sk-test-0123456789abcdef", "openai"); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("AKM_KEY_CASE_INSENSITIVE", "")
+	if key := s.GetKey("openai_api_key"); key != nil {
+		t.Error("GetKey matched ignoring case by default")
+	}
+	if err := s.DeleteKey("openai_api_key"); err == nil {
+		t.Error("DeleteKey matched ignoring case by default")
+	}
+
+	t.Setenv("AKM_KEY_CASE_INSENSITIVE", "1")
+	if key := s.GetKey("openai_api_key"); key == nil || key.Name != "OPENAI_API_KEY" {
+		t.Errorf("GetKey with AKM_KEY_CASE_INSENSITIVE=1 = %v, want OPENAI_API_KEY", key)
+	}
+}
+
+// Every value change records who made it, not just metadata edits.
+func TestValueChangesStampUpdatedBy(t *testing.T) {
+	s := newTestStorage(t)
+	t.Setenv("AKM_USER", "alice")
+	if _, err := s.AddKey("OPENAI_API_KEY", "sk-test-0123456789abcdef", "openai"); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("AKM_USER", "bob")
+	key, err := s.RotateKeyValue("OPENAI_API_KEY", "sk-new-0123456789abcdef")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.CreatedBy == nil || *key.CreatedBy != "alice" {
+		t.Errorf("CreatedBy = %v, want alice", key.CreatedBy)
+	}
+	if key.UpdatedBy == nil || *key.UpdatedBy != "bob" {
+		t.Errorf("UpdatedBy after rotate = %v, want bob", key.UpdatedBy)
+	}
+
+	t.Setenv("AKM_USER", "carol")
+	if key, err = s.RollbackKeyValue("OPENAI_API_KEY"); err != nil {
+		t.Fatal(err)
+	}
+	if key.UpdatedBy == nil || *key.UpdatedBy != "carol" {
+		t.Errorf("UpdatedBy after rollback = %v, want carol", key.UpdatedBy)
+	}
+}
+
+// After a master key rotation, retiring the previous key must not strand
+// tokens.json or the audit log.
+func TestRetirePreviousMasterKeyKeepsTokensAndAudit(t *testing.T) {
+	useFlakyKeychain(t, 0, nil)
+	s := newTestStorage(t)
+	if _, err := s.AddKey("OPENAI_API_KEY", "sk-test-0123456789abcdef", "openai"); err != nil {
+		t.Fatal(err)
+	}
+	raw, _, err := s.CreateToken("ci", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.RotateMasterKey(false); err != nil {
+		t.Fatal(err)
+	}
+	if pending, err := s.tokensNeedPreviousKey(); err != nil || pending {
+		t.Errorf("tokensNeedPreviousKey after rotate = %v, %v; want false", pending, err)
+	}
+
+	// Entries written before the rotation are still signed with the old key
+	if err := s.RetirePreviousMasterKey(); err == nil || !strings.Contains(err.Error(), "audit compact") {
+		t.Fatalf("RetirePreviousMasterKey before compact error = %v, want audit compact hint", err)
+	}
+	if _, err := s.CompactAuditLog(false); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RetirePreviousMasterKey(); err != nil {
+		t.Fatalf("RetirePreviousMasterKey after compact: %v", err)
+	}
+
+	if _, err := s.AuthenticateToken(raw); err != nil {
+		t.Errorf("AuthenticateToken after retire: %v", err)
+	}
+	checks, err := s.VerifyAuditEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range checks {
+		if c.Status != AuditEntryValid {
+			t.Errorf("audit line %d status = %s, want valid", c.Line, c.Status)
+		}
+	}
+}
+
+// Pruned keys go to the trash like deleted ones, so they can be restored.
+func TestPrunedKeyCanBeRestored(t *testing.T) {
+	t.Setenv("AKM_TRASH_RETENTION", "")
+	s := newTestStorage(t)
+	if _, err := s.AddKey("OLD_OPENAI_KEY", "sk-old-0123456789abcdef", "openai"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.UpdateKey("OLD_OPENAI_KEY", map[string]interface{}{"is_active": false}); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := s.Prune(PruneFilter{Inactive: true})
+	if err != nil || len(removed) != 1 {
+		t.Fatalf("Prune = %d keys, %v; want 1", len(removed), err)
+	}
+	if s.GetKey("OLD_OPENAI_KEY") != nil {
+		t.Fatal("pruned key is still live")
+	}
+
+	if _, err := s.RestoreKey("OLD_OPENAI_KEY"); err != nil {
+		t.Fatalf("RestoreKey after prune: %v", err)
+	}
+	if value, err := s.GetKeyValue("OLD_OPENAI_KEY", "test"); err != nil || value != "sk-old-0123456789abcdef" {
+		t.Errorf("GetKeyValue after restore = %q, %v", value, err)
+	}
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// TemplateKey is one key as seen by an export template.
+type TemplateKey struct {
+	Name     string
+	Value    string
+	Provider string
+	Tags     []string
+}
+
+// TemplateFuncs are the helpers available to export templates:
+//
+//	dotenv  escape for a double-quoted dotenv value (without the quotes)
+//	shell   POSIX single-quoted word, quotes included
+//	json    JSON string literal, quotes included
+//	join    strings.Join with the separator first: {{join ", " .Tags}}
+//	upper / lower
+var TemplateFuncs = template.FuncMap{
+	"dotenv": EscapeDotenvValue,
+	"shell":  QuotePOSIX,
+	"json": func(s string) (string, error) {
+		b, err := json.Marshal(s)
+		return string(b), err
+	},
+	"join":  func(sep string, items []string) string { return strings.Join(items, sep) },
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// ParseKeysTemplate parses an export template with TemplateFuncs.
+func ParseKeysTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(TemplateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// TemplateKeys pairs decrypted values with their key metadata, sorted by name.
+func (s *KeyStorage) TemplateKeys(values map[string]string) []TemplateKey {
+	keys := make([]TemplateKey, 0, len(values))
+	for name, value := range values {
+		k := TemplateKey{Name: name, Value: value}
+		if meta := s.GetKey(name); meta != nil {
+			k.Provider = meta.Provider
+			k.Tags = meta.Tags
+		}
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Name < keys[j].Name })
+	return keys
+}
+
+// RenderKeysTemplate executes tmpl with keys as its data (range over ".").
+func RenderKeysTemplate(w io.Writer, tmpl *template.Template, keys []TemplateKey) error {
+	if err := tmpl.Execute(w, keys); err != nil {
+		return fmt.Errorf("template execution failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,288 @@
+package core
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Token scopes. A token may only call the HTTP routes its scopes cover.
+const (
+	ScopeRead  = "read"  // GET /api/... without plaintext values
+	ScopeWrite = "write" // mutating /api/... calls and value reveals
+	ScopeProxy = "proxy" // /v1/... provider proxy
+)
+
+// TokenScopes lists every valid scope.
+var TokenScopes = []string{ScopeRead, ScopeWrite, ScopeProxy}
+
+const (
+	tokenPrefix = "akm_"
+	// tokenTouchInterval throttles last-used writes so busy clients do not
+	// rewrite the token file on every request.
+	tokenTouchInterval = time.Minute
+)
+
+// ErrInvalidToken is returned when a bearer token is unknown or revoked.
+var ErrInvalidToken = errors.New("invalid or revoked token")
+
+// APIToken is a server access token. Only a hash of the secret is stored.
+type APIToken struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name,omitempty"`
+	Scopes     []string   `json:"scopes"`
+	Hash       string     `json:"hash"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// HasScope reports whether the token grants scope.
+func (t *APIToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// tokensMu serializes read-modify-write of the token file within a process.
+var tokensMu sync.Mutex
+
+func (s *KeyStorage) tokensFile() string {
+	return filepath.Join(s.dataDir, "tokens.json")
+}
+
+// loadTokens reads the token file fresh from disk, so revocations made by
+// another process (e.g. the CLI) apply to the next request. Caller must hold
+// tokensMu.
+func (s *KeyStorage) loadTokens() ([]*APIToken, error) {
+	data, err := os.ReadFile(s.tokensFile())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	decrypted, err := s.crypto.Decrypt(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt tokens file: %w", err)
+	}
+	var tokens []*APIToken
+	if err := json.Unmarshal([]byte(decrypted), &tokens); err != nil {
+		return nil, fmt.Errorf("failed to parse tokens JSON: %w", err)
+	}
+	return tokens, nil
+}
+
+// saveTokens encrypts and atomically writes the token file. Caller must hold
+// tokensMu.
+func (s *KeyStorage) saveTokens(tokens []*APIToken) error {
+	jsonBytes, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal tokens: %w", err)
+	}
+	encrypted, err := s.crypto.Encrypt(string(jsonBytes))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt tokens: %w", err)
+	}
+
+	tempFile := filepath.Join(s.dataDir, ".tokens_temp.json")
+	if err := os.WriteFile(tempFile, []byte(encrypted), s.filePerm); err != nil {
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := os.Rename(tempFile, s.tokensFile()); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+	return nil
+}
+
+// reencryptTokens rewrites the token file under the current master key.
+func (s *KeyStorage) reencryptTokens() error {
+	tokensMu.Lock()
+	defer tokensMu.Unlock()
+
+	if _, err := os.Stat(s.tokensFile()); os.IsNotExist(err) {
+		return nil
+	}
+	tokens, err := s.loadTokens()
+	if err != nil {
+		return err
+	}
+	return s.saveTokens(tokens)
+}
+
+// tokensNeedPreviousKey reports whether the token file exists but does not
+// decrypt under the current master key.
+func (s *KeyStorage) tokensNeedPreviousKey() (bool, error) {
+	tokensMu.Lock()
+	defer tokensMu.Unlock()
+
+	data, err := os.ReadFile(s.tokensFile())
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return !s.crypto.DecryptsWithPrimary(string(data)), nil
+}
+
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateToken issues a new token and returns its secret, which is shown once
+// and never stored. Empty scopes grant all scopes.
+func (s *KeyStorage) CreateToken(name string, scopes []string) (string, *APIToken, error) {
+	if len(scopes) == 0 {
+		scopes = append([]string(nil), TokenScopes...)
+	}
+	for _, scope := range scopes {
+		if !containsFold(TokenScopes, scope) {
+			return "", nil, fmt.Errorf("unknown scope '%s' (valid: %s)", scope, strings.Join(TokenScopes, ", "))
+		}
+	}
+
+	idBytes := make([]byte, 4)
+	secret := make([]byte, 24)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", nil, err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, err
+	}
+	id := hex.EncodeToString(idBytes)
+	raw := tokenPrefix + id + "_" + base64.RawURLEncoding.EncodeToString(secret)
+
+	tokensMu.Lock()
+	defer tokensMu.Unlock()
+
+	tokens, err := s.loadTokens()
+	if err != nil {
+		return "", nil, err
+	}
+	token := &APIToken{
+		ID:        id,
+		Name:      name,
+		Scopes:    normalizeScopes(scopes),
+		Hash:      hashToken(raw),
+		CreatedAt: time.Now(),
+	}
+	if err := s.saveTokens(append(tokens, token)); err != nil {
+		return "", nil, err
+	}
+	s.logUsage("token:"+id, "token-create", "system")
+	return raw, token, nil
+}
+
+func normalizeScopes(scopes []string) []string {
+	var out []string
+	for _, scope := range scopes {
+		scope = strings.ToLower(scope)
+		if !containsFold(out, scope) {
+			out = append(out, scope)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// ListTokens returns all tokens sorted by creation time.
+func (s *KeyStorage) ListTokens() ([]*APIToken, error) {
+	tokensMu.Lock()
+	defer tokensMu.Unlock()
+
+	tokens, err := s.loadTokens()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].CreatedAt.Before(tokens[j].CreatedAt)
+	})
+	return tokens, nil
+}
+
+// TokenAuthEnabled reports whether a token store exists. It stays enabled
+// after the last token is revoked, so revoking never reopens the server.
+func (s *KeyStorage) TokenAuthEnabled() bool {
+	_, err := os.Stat(s.tokensFile())
+	return err == nil
+}
+
+// RevokeToken deletes a token. It stops working for the next request.
+func (s *KeyStorage) RevokeToken(id string) error {
+	tokensMu.Lock()
+	defer tokensMu.Unlock()
+
+	tokens, err := s.loadTokens()
+	if err != nil {
+		return err
+	}
+	kept := tokens[:0]
+	found := false
+	for _, t := range tokens {
+		if t.ID == id {
+			found = true
+			continue
+		}
+		kept = append(kept, t)
+	}
+	if !found {
+		return fmt.Errorf("token '%s' not found", id)
+	}
+	if err := s.saveTokens(kept); err != nil {
+		return err
+	}
+	s.logUsage("token:"+id, "token-revoke", "system")
+	return nil
+}
+
+// AuthenticateToken looks up a presented token and records its last use.
+func (s *KeyStorage) AuthenticateToken(raw string) (*APIToken, error) {
+	rest, ok := strings.CutPrefix(raw, tokenPrefix)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	id, _, ok := strings.Cut(rest, "_")
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	tokensMu.Lock()
+	defer tokensMu.Unlock()
+
+	tokens, err := s.loadTokens()
+	if err != nil {
+		return nil, err
+	}
+	hash := hashToken(raw)
+	for _, t := range tokens {
+		if t.ID != id || subtle.ConstantTimeCompare([]byte(t.Hash), []byte(hash)) != 1 {
+			continue
+		}
+		now := time.Now()
+		if t.LastUsedAt == nil || now.Sub(*t.LastUsedAt) >= tokenTouchInterval {
+			t.LastUsedAt = &now
+			if err := s.saveTokens(tokens); err != nil {
+				fmt.Fprintf(os.Stderr, "⚠️  更新令牌使用时间失败: %v\n", err)
+			}
+		}
+		return t, nil
+	}
+	return nil, ErrInvalidToken
+}
@@ -0,0 +1,126 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/baobao/akm-go/internal/models"
+)
+
+// DefaultTrashRetention is how long deleted keys stay restorable.
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+// TrashRetention reads AKM_TRASH_RETENTION ("30d", "2w", "72h"; default 30d).
+// 0 turns the trash off: DeleteKey then removes keys permanently.
+func TrashRetention() time.Duration {
+	raw := strings.TrimSpace(os.Getenv("AKM_TRASH_RETENTION"))
+	if raw == "" {
+		return DefaultTrashRetention
+	}
+	d, err := ParseRelativeDuration(raw)
+	if err != nil || d < 0 {
+		return DefaultTrashRetention
+	}
+	return d
+}
+
+// trashExpired reports whether a trashed key is past the retention window.
+func trashExpired(key *models.APIKey, retention time.Duration, now time.Time) bool {
+	return key.DeletedAt == nil || now.Sub(key.DeletedAt.Time) > retention
+}
+
+// liveTrashLocked returns the trashed keys still within retention, newest
+// deletion first. Caller must hold s.mu.
+func (s *KeyStorage) liveTrashLocked(now time.Time) []*models.APIKey {
+	retention := TrashRetention()
+	keys := make([]*models.APIKey, 0, len(s.trash))
+	for _, key := range s.trash {
+		if !trashExpired(key, retention, now) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].DeletedAt.After(keys[j].DeletedAt.Time) })
+	return keys
+}
+
+// TrashedKeys returns the deleted keys that can still be restored, newest
+// deletion first. Keys past the retention window are dropped on the next save.
+func (s *KeyStorage) TrashedKeys() []*models.APIKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.liveTrashLocked(time.Now())
+}
+
+// RestoreKey moves a deleted key back out of the trash. It fails if the key
+// was never deleted, has passed the retention window, or its name has been
+// reused by a key added since.
+func (s *KeyStorage) RestoreKey(name string) (*models.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	trashed := s.trash[name]
+	if trashed == nil {
+		for n, key := range s.trash {
+			if caseInsensitiveLookup() && strings.EqualFold(n, name) {
+				trashed = key
+				break
+			}
+		}
+	}
+	if trashed == nil || trashExpired(trashed, TrashRetention(), time.Now()) {
+		return nil, fmt.Errorf("key '%s' is not in the trash", name)
+	}
+	name = trashed.Name
+	if _, exists := s.keysCache[name]; exists {
+		return nil, fmt.Errorf("key '%s' already exists: rename or delete it before restoring", name)
+	}
+
+	restored := *trashed
+	restored.DeletedAt = nil
+	delete(s.trash, name)
+	s.keysCache[name] = &restored
+
+	undo := func() {
+		delete(s.keysCache, name)
+		s.trash[name] = trashed
+	}
+	if err := s.saveKeys(); err != nil {
+		undo() // Rollback on failure
+		return nil, err
+	}
+	if err := s.auditMutation(name, "restore", undo); err != nil {
+		return nil, err
+	}
+	return &restored, nil
+}
+
+// EmptyTrash permanently removes every trashed key and returns their names.
+func (s *KeyStorage) EmptyTrash() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.trash) == 0 {
+		return nil, nil
+	}
+	previous := s.trash
+	s.trash = make(map[string]*models.APIKey)
+	if err := s.saveKeys(); err != nil {
+		s.trash = previous // Rollback on failure
+		return nil, err
+	}
+
+	names := make([]string, 0, len(previous))
+	for name := range previous {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := s.auditMutation(name, "purge", func() { s.trash = previous }); err != nil {
+			return nil, err
+		}
+	}
+	return names, nil
+}
@@ -1,43 +1,95 @@
 package core
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
 	"sync"
 	"time"
-
-	"github.com/baobao/akm-go/internal/models"
+	"unicode/utf8"
 )
 
 // VerifyResult holds the result of a key verification.
 type VerifyResult struct {
 	Name     string   `json:"name"`
 	Provider string   `json:"provider"`
-	Status   string   `json:"status"`  // "valid", "invalid", "error", "unsupported"
+	Status   string   `json:"status"` // "valid", "valid_limited", "invalid", "error", "unsupported"
 	Message  string   `json:"message"`
 	Models   []string `json:"models,omitempty"`
 }
 
+// StatusValidLimited marks a key that authenticates but lacks permission for
+// the verification endpoint (e.g. a restricted key that cannot list models).
+const StatusValidLimited = "valid_limited"
+
 // providerVerifier defines how to verify a specific provider's API key.
 type providerVerifier struct {
 	buildRequest func(apiKey string) (*http.Request, error)
+	// interpret maps the response to a status and message; nil uses defaultInterpret.
+	interpret func(statusCode int, body []byte) (status, message string)
+}
+
+// defaultInterpret treats 200 as valid and 401/403 as invalid. A 429 also
+// means valid: the provider authenticated the key before rate limiting it.
+func defaultInterpret(statusCode int, body []byte) (string, string) {
+	switch statusCode {
+	case http.StatusOK:
+		return "valid", "密钥有效"
+	case http.StatusTooManyRequests:
+		return "valid", "密钥有效（当前被限流, HTTP 429）"
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return "invalid", fmt.Sprintf("密钥无效 (HTTP %d)", statusCode)
+	default:
+		return "error", fmt.Sprintf("unexpected HTTP %d", statusCode)
+	}
+}
+
+// limitedOn403 is for providers that answer 401 for bad credentials but 403
+// for an authenticated key without permission on the verification endpoint.
+func limitedOn403(hint string) func(int, []byte) (string, string) {
+	return func(statusCode int, body []byte) (string, string) {
+		if statusCode == http.StatusForbidden {
+			return StatusValidLimited, hint
+		}
+		return defaultInterpret(statusCode, body)
+	}
+}
+
+// platformURL joins path onto the registry's API base for platform id.
+func platformURL(id, path string) string {
+	base, _ := PlatformAPIBase(id)
+	return strings.TrimRight(base, "/") + path
+}
+
+// bearerRequest builds a GET to url authenticated with "Authorization: Bearer".
+func bearerRequest(url, apiKey string) (*http.Request, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	return req, nil
 }
 
 var providerVerifiers = map[string]providerVerifier{
 	"openai": {
 		buildRequest: func(apiKey string) (*http.Request, error) {
-			req, err := http.NewRequest("GET", "https://api.openai.com/v1/models", nil)
+			req, err := http.NewRequest("GET", platformURL("openai", "/v1/models"), nil)
 			if err != nil {
 				return nil, err
 			}
 			req.Header.Set("Authorization", "Bearer "+apiKey)
 			return req, nil
 		},
+		// Restricted project keys without "Models: Read" get 403 here
+		interpret: limitedOn403("密钥可用，但无权列出模型（受限权限密钥）"),
 	},
 	"anthropic": {
 		buildRequest: func(apiKey string) (*http.Request, error) {
-			req, err := http.NewRequest("GET", "https://api.anthropic.com/v1/models", nil)
+			req, err := http.NewRequest("GET", platformURL("anthropic", "/v1/models"), nil)
 			if err != nil {
 				return nil, err
 			}
@@ -45,20 +97,32 @@ var providerVerifiers = map[string]providerVerifier{
 			req.Header.Set("anthropic-version", "2023-06-01")
 			return req, nil
 		},
+		interpret: limitedOn403("密钥可用，但无权访问模型列表 (permission_error)"),
 	},
 	"gemini": {
 		buildRequest: func(apiKey string) (*http.Request, error) {
-			req, err := http.NewRequest("GET", "https://generativelanguage.googleapis.com/v1beta/models", nil)
+			req, err := http.NewRequest("GET", platformURL("gemini", "/v1beta/models"), nil)
 			if err != nil {
 				return nil, err
 			}
 			req.Header.Set("x-goog-api-key", apiKey)
 			return req, nil
 		},
+		// Gemini reports a bad key as 400 API_KEY_INVALID; 403 means the key
+		// works but the API is not enabled or the key is restricted
+		interpret: func(statusCode int, body []byte) (string, string) {
+			if statusCode == http.StatusBadRequest && strings.Contains(string(body), "API_KEY_INVALID") {
+				return "invalid", "密钥无效 (API_KEY_INVALID)"
+			}
+			if statusCode == http.StatusForbidden {
+				return StatusValidLimited, "密钥可用，但 API 未启用或密钥受限"
+			}
+			return defaultInterpret(statusCode, body)
+		},
 	},
 	"deepseek": {
 		buildRequest: func(apiKey string) (*http.Request, error) {
-			req, err := http.NewRequest("GET", "https://api.deepseek.com/models", nil)
+			req, err := http.NewRequest("GET", platformURL("deepseek", "/models"), nil)
 			if err != nil {
 				return nil, err
 			}
@@ -68,7 +132,7 @@ var providerVerifiers = map[string]providerVerifier{
 	},
 	"zhipu": {
 		buildRequest: func(apiKey string) (*http.Request, error) {
-			req, err := http.NewRequest("GET", "https://open.bigmodel.cn/api/paas/v4/models", nil)
+			req, err := http.NewRequest("GET", platformURL("zhipu", "/v4/models"), nil)
 			if err != nil {
 				return nil, err
 			}
@@ -76,6 +140,33 @@ var providerVerifiers = map[string]providerVerifier{
 			return req, nil
 		},
 	},
+	"mistral": {
+		buildRequest: func(apiKey string) (*http.Request, error) {
+			return bearerRequest("https://api.mistral.ai/v1/models", apiKey)
+		},
+	},
+	"groq": {
+		buildRequest: func(apiKey string) (*http.Request, error) {
+			return bearerRequest("https://api.groq.com/openai/v1/models", apiKey)
+		},
+	},
+	"cohere": {
+		buildRequest: func(apiKey string) (*http.Request, error) {
+			return bearerRequest("https://api.cohere.com/v1/models", apiKey)
+		},
+	},
+	"openrouter": {
+		// The model list is public, so check the key endpoint instead
+		buildRequest: func(apiKey string) (*http.Request, error) {
+			req, err := bearerRequest("https://openrouter.ai/api/v1/key", apiKey)
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set("HTTP-Referer", "https://github.com/baobao/akm-go")
+			req.Header.Set("X-Title", "akm")
+			return req, nil
+		},
+	},
 }
 
 // providerAliases maps alternative provider names to canonical names.
@@ -92,8 +183,41 @@ func normalizeProvider(provider string) string {
 	return p
 }
 
-// VerifyKey verifies a single API key by calling the provider's API.
-func VerifyKey(name, provider, value string) *VerifyResult {
+// VerifyTimeout bounds each verification request.
+var VerifyTimeout = 10 * time.Second
+
+// verifyClient is shared by all verifications so bulk runs reuse connections
+// per provider host. Timeouts are applied per request via context.
+var verifyClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		MaxIdleConns:        50,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     90 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+		ForceAttemptHTTP2:   true,
+	},
+}
+
+// maxErrorDetail caps the provider error text appended to VerifyResult.Message.
+const maxErrorDetail = 200
+
+// VerifyKey verifies a single API key by calling the provider's API. The
+// request is bounded by timeout, or VerifyTimeout when timeout is 0.
+func VerifyKey(name, provider, value string, timeout time.Duration) *VerifyResult {
+	return verifyKey(context.Background(), name, provider, value, timeout)
+}
+
+// VerifyKeyContext is VerifyKey with a caller-supplied context; the request is
+// also bounded by VerifyTimeout.
+func VerifyKeyContext(ctx context.Context, name, provider, value string) *VerifyResult {
+	return verifyKey(ctx, name, provider, value, 0)
+}
+
+func verifyKey(ctx context.Context, name, provider, value string, timeout time.Duration) *VerifyResult {
+	if timeout <= 0 {
+		timeout = VerifyTimeout
+	}
 	normalized := normalizeProvider(provider)
 	verifier, ok := providerVerifiers[normalized]
 	if !ok {
@@ -115,8 +239,10 @@ func VerifyKey(name, provider, value string) *VerifyResult {
 		}
 	}
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	resp, err := verifyClient.Do(req.WithContext(ctx))
 	if err != nil {
 		return &VerifyResult{
 			Name:     name,
@@ -125,80 +251,186 @@ func VerifyKey(name, provider, value string) *VerifyResult {
 			Message:  fmt.Sprintf("请求失败: %v", err),
 		}
 	}
-	defer resp.Body.Close()
+	defer func() {
+		// Drain so the connection goes back to the pool
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+	}()
 
-	switch {
-	case resp.StatusCode == http.StatusOK:
-		return &VerifyResult{
-			Name:     name,
-			Provider: provider,
-			Status:   "valid",
-			Message:  "密钥有效",
+	var body []byte
+	if resp.StatusCode != http.StatusOK {
+		body, _ = io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+	}
+
+	interpret := verifier.interpret
+	if interpret == nil {
+		interpret = defaultInterpret
+	}
+	status, message := interpret(resp.StatusCode, body)
+	// Say why the provider refused (expired, revoked, wrong project...)
+	if detail := providerErrorDetail(body); detail != "" {
+		message += ": " + detail
+	}
+	return &VerifyResult{
+		Name:     name,
+		Provider: provider,
+		Status:   status,
+		Message:  message,
+	}
+}
+
+// providerErrorDetail extracts a short reason from an error response body:
+// error.message (OpenAI, Anthropic, Gemini, Zhipu...), a string error, or a
+// top-level message (Cohere); otherwise the raw body. The result is cut to
+// maxErrorDetail bytes on a rune boundary.
+func providerErrorDetail(body []byte) string {
+	var envelope struct {
+		Error   json.RawMessage `json:"error"`
+		Message string          `json:"message"`
+	}
+	detail := ""
+	if err := json.Unmarshal(body, &envelope); err == nil {
+		var nested struct {
+			Message string `json:"message"`
 		}
-	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
-		return &VerifyResult{
-			Name:     name,
-			Provider: provider,
-			Status:   "invalid",
-			Message:  fmt.Sprintf("密钥无效 (HTTP %d)", resp.StatusCode),
+		var plain string
+		switch {
+		case json.Unmarshal(envelope.Error, &nested) == nil && nested.Message != "":
+			detail = nested.Message
+		case json.Unmarshal(envelope.Error, &plain) == nil && plain != "":
+			detail = plain
+		default:
+			detail = envelope.Message
 		}
-	default:
-		return &VerifyResult{
-			Name:     name,
-			Provider: provider,
-			Status:   "error",
-			Message:  fmt.Sprintf("unexpected HTTP %d", resp.StatusCode),
+	}
+	if detail == "" {
+		detail = string(body)
+	}
+	detail = strings.Join(strings.Fields(detail), " ")
+	if len(detail) > maxErrorDetail {
+		cut := maxErrorDetail
+		for cut > 0 && !utf8.RuneStart(detail[cut]) {
+			cut--
 		}
+		detail = detail[:cut] + "..."
 	}
+	return detail
 }
 
-// VerifyAll verifies all keys concurrently with a concurrency limit.
-func VerifyAll(storage *KeyStorage, provider, name string) []*VerifyResult {
-	keys := storage.ListKeys(provider)
-
-	// Filter by name if specified (preserve provider filter)
-	if name != "" {
-		var filtered []*models.APIKey
-		for _, k := range keys {
-			if k.Name == name {
-				filtered = append(filtered, k)
-				break
-			}
+// verifyTarget is a point-in-time copy of the fields needed to verify one key.
+type verifyTarget struct {
+	name      string
+	provider  string
+	encrypted string
+	protected bool // passphrase-protected, cannot be verified unattended
+}
+
+// VerifyFilter selects keys to verify. Set fields combine with AND.
+type VerifyFilter struct {
+	Provider string
+	Name     string
+	Tag      string
+}
+
+// snapshotVerifyTargets captures keys to verify under a single read lock, so a
+// key deleted or updated mid-run cannot change what an in-flight verify sees.
+func (s *KeyStorage) snapshotVerifyTargets(filter VerifyFilter) []verifyTarget {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var targets []verifyTarget
+	for _, key := range s.keysCache {
+		if !MatchProvider(filter.Provider, key.Provider) {
+			continue
 		}
-		keys = filtered
+		if filter.Name != "" && key.Name != filter.Name {
+			continue
+		}
+		if filter.Tag != "" && !hasTag(key, filter.Tag) {
+			continue
+		}
+		targets = append(targets, verifyTarget{
+			name:      key.Name,
+			provider:  key.Provider,
+			encrypted: key.ValueEncrypted,
+			protected: key.PassphraseProtected,
+		})
 	}
+	return targets
+}
 
-	if len(keys) == 0 {
+// VerifyAll verifies all keys concurrently with a concurrency limit.
+func VerifyAll(storage *KeyStorage, provider, name string) []*VerifyResult {
+	return VerifyStream(context.Background(), storage, VerifyFilter{Provider: provider, Name: name}, nil)
+}
+
+// VerifyStream verifies matching keys concurrently and calls onResult (if
+// non-nil) as each one completes; calls are serialized. Keys not yet started
+// when ctx is done are reported as errors. Results are returned in full.
+func VerifyStream(ctx context.Context, storage *KeyStorage, filter VerifyFilter, onResult func(*VerifyResult)) []*VerifyResult {
+	targets := storage.snapshotVerifyTargets(filter)
+	if len(targets) == 0 {
 		return nil
 	}
 
-	results := make([]*VerifyResult, len(keys))
+	results := make([]*VerifyResult, len(targets))
 	sem := make(chan struct{}, 5) // max 5 concurrent
 	var wg sync.WaitGroup
+	var emitMu sync.Mutex
 
-	for i, key := range keys {
+	for i, target := range targets {
 		wg.Add(1)
-		go func(idx int, keyName, keyProvider string) {
+		go func(idx int, t verifyTarget) {
 			defer wg.Done()
-			sem <- struct{}{}
-			defer func() { <-sem }()
-
-			// Decrypt the key value
-			value, err := storage.GetKeyValue(keyName, "verify")
-			if err != nil {
-				results[idx] = &VerifyResult{
-					Name:     keyName,
-					Provider: keyProvider,
-					Status:   "error",
-					Message:  fmt.Sprintf("解密失败: %v", err),
-				}
-				return
+			result := verifyOne(ctx, storage, t, sem)
+			results[idx] = result
+			if onResult != nil {
+				emitMu.Lock()
+				onResult(result)
+				emitMu.Unlock()
 			}
-
-			results[idx] = VerifyKey(keyName, keyProvider, value)
-		}(i, key.Name, key.Provider)
+		}(i, target)
 	}
 
 	wg.Wait()
+	notifyInvalidTransitions(storage, results)
 	return results
 }
+
+// verifyOne verifies one snapshotted key, holding a sem slot while it runs.
+func verifyOne(ctx context.Context, storage *KeyStorage, t verifyTarget, sem chan struct{}) *VerifyResult {
+	select {
+	case sem <- struct{}{}:
+		defer func() { <-sem }()
+	case <-ctx.Done():
+		return &VerifyResult{
+			Name:     t.name,
+			Provider: t.provider,
+			Status:   "error",
+			Message:  fmt.Sprintf("已取消: %v", ctx.Err()),
+		}
+	}
+
+	if t.protected {
+		return &VerifyResult{
+			Name:     t.name,
+			Provider: t.provider,
+			Status:   "unsupported",
+			Message:  "需要口令，跳过验证",
+		}
+	}
+
+	// Decrypt the snapshotted value; the key may already be gone from storage
+	value, err := storage.crypto.Decrypt(t.encrypted)
+	if err != nil {
+		return &VerifyResult{
+			Name:     t.name,
+			Provider: t.provider,
+			Status:   "error",
+			Message:  fmt.Sprintf("解密失败: %v", err),
+		}
+	}
+	storage.logUsage(t.name, "read", "verify")
+
+	return VerifyKeyContext(ctx, t.name, t.provider, value)
+}
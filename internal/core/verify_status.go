@@ -0,0 +1,156 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// VerifyStatus is the last persisted verification outcome for a key.
+type VerifyStatus struct {
+	Status    string `json:"status"`
+	Message   string `json:"message"`
+	CheckedAt string `json:"checked_at"`
+}
+
+// InvalidKeyNotifier is called once for each key that verification finds has
+// newly become invalid.
+type InvalidKeyNotifier func(result *VerifyResult) error
+
+// VerifyNotifier is the hook used by VerifyAll. It defaults to the webhook or
+// command configured via AKM_VERIFY_WEBHOOK / AKM_VERIFY_HOOK_CMD; nil disables it.
+var VerifyNotifier InvalidKeyNotifier = notifierFromEnv()
+
+var verifyStatusMu sync.Mutex
+
+func notifierFromEnv() InvalidKeyNotifier {
+	webhook := os.Getenv("AKM_VERIFY_WEBHOOK")
+	command := os.Getenv("AKM_VERIFY_HOOK_CMD")
+	if webhook == "" && command == "" {
+		return nil
+	}
+	return func(result *VerifyResult) error {
+		payload, err := json.Marshal(map[string]string{
+			"name":     result.Name,
+			"provider": result.Provider,
+			"status":   result.Status,
+			"message":  result.Message,
+		})
+		if err != nil {
+			return err
+		}
+		if webhook != "" {
+			client := &http.Client{Timeout: 10 * time.Second}
+			resp, err := client.Post(webhook, "application/json", bytes.NewReader(payload))
+			if err != nil {
+				return fmt.Errorf("webhook failed: %w", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode >= 300 {
+				return fmt.Errorf("webhook returned HTTP %d", resp.StatusCode)
+			}
+		}
+		if command != "" {
+			cmd := exec.Command("sh", "-c", command)
+			cmd.Stdin = bytes.NewReader(payload)
+			if out, err := cmd.CombinedOutput(); err != nil {
+				return fmt.Errorf("hook command failed: %w: %s", err, out)
+			}
+		}
+		return nil
+	}
+}
+
+func (s *KeyStorage) verifyStatusFile() string {
+	return filepath.Join(s.dataDir, "verify_status.json")
+}
+
+// LoadVerifyStatus returns the persisted verification status per key name.
+func (s *KeyStorage) LoadVerifyStatus() (map[string]*VerifyStatus, error) {
+	verifyStatusMu.Lock()
+	defer verifyStatusMu.Unlock()
+	return s.loadVerifyStatusLocked()
+}
+
+func (s *KeyStorage) loadVerifyStatusLocked() (map[string]*VerifyStatus, error) {
+	statuses := make(map[string]*VerifyStatus)
+	data, err := os.ReadFile(s.verifyStatusFile())
+	if os.IsNotExist(err) {
+		return statuses, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &statuses); err != nil {
+		return nil, fmt.Errorf("invalid verify status file: %w", err)
+	}
+	return statuses, nil
+}
+
+// recordVerifyResults persists results and returns those that transitioned to
+// "invalid" from any other (or no) previous status.
+func (s *KeyStorage) recordVerifyResults(results []*VerifyResult) ([]*VerifyResult, error) {
+	verifyStatusMu.Lock()
+	defer verifyStatusMu.Unlock()
+
+	statuses, err := s.loadVerifyStatusLocked()
+	if err != nil {
+		// Corrupt status file: start over rather than block verification
+		statuses = make(map[string]*VerifyStatus)
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	var transitions, changed []*VerifyResult
+	for _, r := range results {
+		if r == nil {
+			continue
+		}
+		prev := statuses[r.Name]
+		if r.Status == "invalid" && (prev == nil || prev.Status != "invalid") {
+			transitions = append(transitions, r)
+		}
+		if prev == nil || prev.Status != r.Status {
+			changed = append(changed, r)
+		}
+		statuses[r.Name] = &VerifyStatus{Status: r.Status, Message: r.Message, CheckedAt: now}
+	}
+
+	data, err := json.MarshalIndent(statuses, "", "  ")
+	if err != nil {
+		return transitions, err
+	}
+	tempFile := s.verifyStatusFile() + ".tmp"
+	if err := os.WriteFile(tempFile, data, s.filePerm); err != nil {
+		return transitions, err
+	}
+	if err := os.Rename(tempFile, s.verifyStatusFile()); err != nil {
+		return transitions, err
+	}
+	for _, r := range changed {
+		s.publish(KeyEvent{Type: EventVerifyChanged, Name: r.Name, Status: r.Status})
+	}
+	return transitions, nil
+}
+
+// notifyInvalidTransitions persists results and fires VerifyNotifier for keys
+// that newly became invalid. Failures are reported but never fail verification.
+func notifyInvalidTransitions(storage *KeyStorage, results []*VerifyResult) {
+	transitions, err := storage.recordVerifyResults(results)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  验证状态保存失败: %v\n", err)
+	}
+	if VerifyNotifier == nil {
+		return
+	}
+	for _, r := range transitions {
+		if err := VerifyNotifier(r); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  失效通知发送失败 (%s): %v\n", r.Name, err)
+		}
+	}
+}
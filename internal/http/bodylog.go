@@ -0,0 +1,127 @@
+package http
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// maxLoggedBody caps how much of a response body is kept for logging.
+const maxLoggedBody = 64 << 10
+
+// proxyLogBodies reports whether AKM_PROXY_LOG_BODIES asks for upstream
+// response bodies to be logged, decoded and redacted, for troubleshooting.
+func proxyLogBodies() bool {
+	return parseBoolEnv("AKM_PROXY_LOG_BODIES", false)
+}
+
+// normalizeEncodingHeaders keeps the framing headers consistent with the body
+// ReverseProxy is about to copy. A body the transport already decompressed
+// must not be labelled with its original encoding or compressed length; an
+// untouched body keeps both exactly as the upstream sent them.
+func normalizeEncodingHeaders(resp *http.Response) {
+	if !resp.Uncompressed {
+		return
+	}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+}
+
+// bodyLogger copies what the client receives into a bounded buffer and logs
+// it once the body is closed. Reads pass straight through, so streamed
+// responses reach the client without being held back.
+type bodyLogger struct {
+	io.ReadCloser
+	label     string
+	encoding  string
+	secret    string // key value injected for this request, redacted verbatim
+	buf       bytes.Buffer
+	truncated bool
+	once      sync.Once
+}
+
+// logResponseBody wraps resp.Body so its content is logged on close.
+func logResponseBody(resp *http.Response, label, secret string) {
+	resp.Body = &bodyLogger{
+		ReadCloser: resp.Body,
+		label:      fmt.Sprintf("%s %d", label, resp.StatusCode),
+		encoding:   strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))),
+		secret:     secret,
+	}
+}
+
+func (b *bodyLogger) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if room := maxLoggedBody - b.buf.Len(); n > 0 {
+		if n > room {
+			b.buf.Write(p[:room])
+			b.truncated = true
+		} else {
+			b.buf.Write(p[:n])
+		}
+	}
+	return n, err
+}
+
+func (b *bodyLogger) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.log)
+	return err
+}
+
+func (b *bodyLogger) log() {
+	body, err := decodeBody(b.encoding, b.buf.Bytes())
+	text := string(body)
+	if b.secret != "" {
+		text = strings.ReplaceAll(text, b.secret, "[REDACTED]")
+	}
+	note := ""
+	if b.encoding != "" {
+		note = " (" + b.encoding + ")"
+	}
+	if b.truncated {
+		note += fmt.Sprintf(" [truncated to %d bytes]", maxLoggedBody)
+	}
+	if err != nil {
+		note += fmt.Sprintf(" [decode: %v]", err)
+	}
+	logInfo("proxy response %s%s: %s", b.label, note, text)
+}
+
+// decodeBody undoes a gzip or deflate Content-Encoding. A truncated capture
+// decodes as far as it goes; unknown encodings are described, not returned.
+func decodeBody(encoding string, data []byte) ([]byte, error) {
+	var r io.Reader
+	switch encoding {
+	case "", "identity":
+		return data, nil
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		r = zr
+	case "deflate":
+		// Servers disagree on zlib-wrapped vs raw deflate; accept both
+		if zr, err := zlib.NewReader(bytes.NewReader(data)); err == nil {
+			r = zr
+		} else {
+			r = flate.NewReader(bytes.NewReader(data))
+		}
+	default:
+		return []byte(fmt.Sprintf("<%d bytes, not decoded>", len(data))), nil
+	}
+	out, err := io.ReadAll(r)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = nil // the capture was cut at maxLoggedBody
+	}
+	return out, err
+}
@@ -0,0 +1,39 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/baobao/akm-go/internal/core"
+	"github.com/gin-gonic/gin"
+)
+
+// catalogHandler returns the values-free key inventory as JSON, or as a
+// Markdown table with ?format=markdown.
+func catalogHandler(c *gin.Context) {
+	storage, err := core.GetStorage()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	entries, err := storage.Catalog(core.KeyFilter{
+		Provider: c.Query("provider"),
+		Tag:      c.Query("tag"),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch c.DefaultQuery("format", "json") {
+	case "json":
+		c.JSON(http.StatusOK, gin.H{
+			"keys":  entries,
+			"count": len(entries),
+		})
+	case "markdown", "md":
+		c.Data(http.StatusOK, "text/markdown; charset=utf-8", []byte(core.FormatCatalogMarkdown(entries)))
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or markdown"})
+	}
+}
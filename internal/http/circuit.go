@@ -0,0 +1,167 @@
+package http
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Circuit breaker states.
+const (
+	circuitClosed   = "closed"    // requests flow normally
+	circuitOpen     = "open"      // requests fast-fail until the cooldown ends
+	circuitHalfOpen = "half_open" // one probe request decides open vs closed
+)
+
+// Defaults are conservative: a provider must fail repeatedly in a short
+// window before it is skipped, and only briefly.
+const (
+	defaultCircuitThreshold = 5
+	defaultCircuitWindow    = time.Minute
+	defaultCircuitCooldown  = 30 * time.Second
+)
+
+// circuitConfig holds breaker thresholds. A zero threshold disables breaking.
+type circuitConfig struct {
+	threshold int
+	window    time.Duration
+	cooldown  time.Duration
+}
+
+// loadCircuitConfig reads AKM_CIRCUIT_THRESHOLD (consecutive failures, 0
+// disables), AKM_CIRCUIT_WINDOW and AKM_CIRCUIT_COOLDOWN (durations).
+func loadCircuitConfig() circuitConfig {
+	cfg := circuitConfig{
+		threshold: defaultCircuitThreshold,
+		window:    defaultCircuitWindow,
+		cooldown:  defaultCircuitCooldown,
+	}
+	if n, err := strconv.Atoi(strings.TrimSpace(os.Getenv("AKM_CIRCUIT_THRESHOLD"))); err == nil && n >= 0 {
+		cfg.threshold = n
+	}
+	if d, err := time.ParseDuration(strings.TrimSpace(os.Getenv("AKM_CIRCUIT_WINDOW"))); err == nil && d > 0 {
+		cfg.window = d
+	}
+	if d, err := time.ParseDuration(strings.TrimSpace(os.Getenv("AKM_CIRCUIT_COOLDOWN"))); err == nil && d > 0 {
+		cfg.cooldown = d
+	}
+	return cfg
+}
+
+// circuitBreaker tracks consecutive upstream failures for one provider.
+type circuitBreaker struct {
+	mu           sync.Mutex
+	cfg          circuitConfig
+	state        string
+	failures     int
+	firstFailure time.Time
+	openedAt     time.Time
+	probing      bool // a half-open probe is in flight
+}
+
+// circuitStatus is the breaker state reported by /api/providers.
+type circuitStatus struct {
+	State             string `json:"state"`
+	Failures          int    `json:"failures"`
+	RetryAfterSeconds int    `json:"retry_after_seconds,omitempty"`
+}
+
+var (
+	circuitsMu sync.Mutex
+	circuits   = make(map[string]*circuitBreaker)
+)
+
+// circuitFor returns the provider's breaker, creating it on first use.
+func circuitFor(provider string) *circuitBreaker {
+	circuitsMu.Lock()
+	defer circuitsMu.Unlock()
+	b, ok := circuits[provider]
+	if !ok {
+		b = &circuitBreaker{cfg: loadCircuitConfig(), state: circuitClosed}
+		circuits[provider] = b
+	}
+	return b
+}
+
+// allow reports whether a request may go upstream. When it may not, it also
+// returns how long until the next probe is allowed.
+func (b *circuitBreaker) allow() (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.cfg.threshold == 0 {
+		return true, 0
+	}
+	switch b.state {
+	case circuitOpen:
+		if wait := b.cfg.cooldown - time.Since(b.openedAt); wait > 0 {
+			return false, wait
+		}
+		b.state = circuitHalfOpen
+		b.probing = true
+		return true, 0
+	case circuitHalfOpen:
+		if b.probing {
+			return false, time.Second
+		}
+		b.probing = true
+		return true, 0
+	}
+	return true, 0
+}
+
+// success closes the circuit and clears the failure count.
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = circuitClosed
+	b.failures = 0
+	b.firstFailure = time.Time{}
+	b.probing = false
+}
+
+// failure counts an upstream failure, opening the circuit at the threshold
+// or immediately when a half-open probe fails.
+func (b *circuitBreaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.cfg.threshold == 0 {
+		return
+	}
+
+	now := time.Now()
+	if b.state == circuitHalfOpen {
+		b.state, b.openedAt, b.probing = circuitOpen, now, false
+		return
+	}
+	if b.failures == 0 || now.Sub(b.firstFailure) > b.cfg.window {
+		b.failures, b.firstFailure = 0, now
+	}
+	b.failures++
+	if b.failures >= b.cfg.threshold {
+		b.state, b.openedAt = circuitOpen, now
+	}
+}
+
+// release gives up a half-open probe that ended without an answer (e.g. the
+// client disconnected), so the next request can probe instead.
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// status snapshots the breaker for reporting.
+func (b *circuitBreaker) status() circuitStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := circuitStatus{State: b.state, Failures: b.failures}
+	if b.state == circuitOpen {
+		if wait := b.cfg.cooldown - time.Since(b.openedAt); wait > 0 {
+			s.RetryAfterSeconds = int(wait.Seconds()) + 1
+		}
+	}
+	return s
+}
@@ -0,0 +1,69 @@
+package http
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/baobao/akm-go/internal/core"
+	"github.com/gin-gonic/gin"
+)
+
+// eventsBuffer is how many events a slow client may fall behind before it is
+// told to resync instead.
+const eventsBuffer = 64
+
+// eventsHeartbeat keeps idle connections open through proxies.
+const eventsHeartbeat = 30 * time.Second
+
+// eventsHandler streams key changes as Server-Sent Events. Each event's name
+// is its type (key.added, key.updated, key.deleted, verify.changed) and its
+// data the core.KeyEvent JSON, which never includes key values. A "resync"
+// event means events were dropped and the client should refetch /api/keys.
+// Only changes made through this server process are seen.
+func eventsHandler(c *gin.Context) {
+	storage, err := core.GetStorage()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	events := make(chan core.KeyEvent, eventsBuffer)
+	var dropped atomic.Bool
+	unsubscribe := storage.OnChange(func(ev core.KeyEvent) {
+		select {
+		case events <- ev:
+		default:
+			dropped.Store(true) // never block the storage mutation
+		}
+	})
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.SSEvent("ready", gin.H{"timestamp": time.Now()})
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+	ctx := c.Request.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-events:
+			if dropped.Swap(false) {
+				c.SSEvent("resync", gin.H{"timestamp": time.Now()})
+			}
+			c.SSEvent(ev.Type, ev)
+		case <-heartbeat.C:
+			if _, err := c.Writer.WriteString(": ping\n\n"); err != nil {
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
@@ -1,6 +1,7 @@
 package http
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/baobao/akm-go/internal/core"
@@ -8,16 +9,17 @@ import (
 )
 
 type keyResponse struct {
-	Name           string   `json:"name"`
-	Provider       string   `json:"provider"`
-	Description    *string  `json:"description,omitempty"`
-	SourceProject  *string  `json:"source_project,omitempty"`
-	Tags           []string `json:"tags,omitempty"`
-	IsActive       bool     `json:"is_active"`
-	CreatedAt      string   `json:"created_at"`
-	UpdatedAt      string   `json:"updated_at"`
-	ModelVersion   *string  `json:"model_version,omitempty"`
-	ModelName      *string  `json:"model_name,omitempty"`
+	Name                string   `json:"name"`
+	Provider            string   `json:"provider"`
+	Description         *string  `json:"description,omitempty"`
+	SourceProject       *string  `json:"source_project,omitempty"`
+	Tags                []string `json:"tags,omitempty"`
+	IsActive            bool     `json:"is_active"`
+	CreatedAt           string   `json:"created_at"`
+	UpdatedAt           string   `json:"updated_at"`
+	ModelVersion        *string  `json:"model_version,omitempty"`
+	ModelName           *string  `json:"model_name,omitempty"`
+	PassphraseProtected bool     `json:"passphrase_protected,omitempty"`
 }
 
 type addKeyRequest struct {
@@ -42,16 +44,17 @@ func listKeysHandler(c *gin.Context) {
 	response := make([]keyResponse, 0, len(keys))
 	for _, key := range keys {
 		response = append(response, keyResponse{
-			Name:          key.Name,
-			Provider:      key.Provider,
-			Description:   key.Description,
-			SourceProject: key.SourceProject,
-			Tags:          key.Tags,
-			IsActive:      key.IsActive,
-			CreatedAt:     key.CreatedAt.Format("2006-01-02T15:04:05Z"),
-			UpdatedAt:     key.UpdatedAt.Format("2006-01-02T15:04:05Z"),
-			ModelVersion:  key.ModelVersion,
-			ModelName:     key.ModelName,
+			Name:                key.Name,
+			Provider:            key.Provider,
+			Description:         key.Description,
+			SourceProject:       key.SourceProject,
+			Tags:                key.Tags,
+			IsActive:            key.IsActive,
+			CreatedAt:           key.CreatedAt.Format("2006-01-02T15:04:05Z"),
+			UpdatedAt:           key.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+			ModelVersion:        key.ModelVersion,
+			ModelName:           key.ModelName,
+			PassphraseProtected: key.PassphraseProtected,
 		})
 	}
 
@@ -78,22 +81,35 @@ func getKeyHandler(c *gin.Context) {
 	}
 
 	response := gin.H{
-		"name":           key.Name,
-		"provider":       key.Provider,
-		"description":    key.Description,
-		"source_project": key.SourceProject,
-		"tags":           key.Tags,
-		"is_active":      key.IsActive,
-		"created_at":     key.CreatedAt.Format("2006-01-02T15:04:05Z"),
-		"updated_at":     key.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+		"name":                 key.Name,
+		"provider":             key.Provider,
+		"description":          key.Description,
+		"source_project":       key.SourceProject,
+		"tags":                 key.Tags,
+		"is_active":            key.IsActive,
+		"created_at":           key.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		"updated_at":           key.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+		"passphrase_protected": key.PassphraseProtected,
 	}
 
 	if showValue {
-		value, err := storage.GetKeyValue(name, "api")
+		if err := core.CheckReveal(); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		value, err := storage.GetKeyValueWithPassphrase(name, "api", c.GetHeader("X-AKM-Key-Passphrase"))
+		if errors.Is(err, core.ErrPassphraseRequired) || errors.Is(err, core.ErrBadPassphrase) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to decrypt key"})
 			return
 		}
+		if value, err = core.RevealValue(value, false); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		response["value"] = value
 	}
 
@@ -169,12 +185,16 @@ func deleteKeyHandler(c *gin.Context) {
 func exportEnvHandler(c *gin.Context) {
 	var req struct {
 		Provider string   `json:"provider"`
+		Tag      string   `json:"tag"`
 		Keys     []string `json:"keys"`
+		NoQuote  bool     `json:"no_quote"`
+		Sort     bool     `json:"sort"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		// Allow empty body
 		req.Provider = ""
+		req.Tag = ""
 		req.Keys = nil
 	}
 
@@ -184,23 +204,21 @@ func exportEnvHandler(c *gin.Context) {
 		return
 	}
 
-	keys, err := storage.GetKeysForExport("api-export", req.Provider, req.Keys)
+	keys, err := storage.GetKeysForExport("api-export", req.Provider, req.Tag, req.Keys)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Generate .env format
-	var lines []string
-	lines = append(lines, "# Generated by akm API")
-	for name, value := range keys {
-		escaped := core.EscapeDotenvValue(value)
-		lines = append(lines, name+`="`+escaped+`"`)
-	}
+	content := core.FormatDotenv(keys, core.DotenvOptions{
+		Header:  []string{"Generated by akm API"},
+		NoQuote: req.NoQuote,
+		Sort:    req.Sort,
+	})
 
-	c.Header("Content-Type", "text/plain")
 	c.Header("Content-Disposition", "attachment; filename=.env")
-	c.String(http.StatusOK, "%s\n", lines)
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(content))
 }
 
 func healthHandler(c *gin.Context) {
@@ -0,0 +1,73 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/baobao/akm-go/internal/core"
+	"github.com/gin-gonic/gin"
+	"github.com/zalando/go-keyring"
+)
+
+// TestMain points the storage, crypto and budget singletons at a throwaway
+// home directory and an in-memory keychain before any test touches them.
+func TestMain(m *testing.M) {
+	home, err := os.MkdirTemp("", "akm-http-test")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("HOME", home)
+	keyring.MockInit()
+	gin.SetMode(gin.TestMode)
+
+	code := m.Run()
+	os.RemoveAll(home)
+	os.Exit(code)
+}
+
+// testStorage returns the storage singleton the proxy reads keys from.
+func testStorage(t *testing.T) *core.KeyStorage {
+	t.Helper()
+	storage, err := core.GetStorage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return storage
+}
+
+// addTestKey stores an active key for provider and removes it after the test.
+func addTestKey(t *testing.T, name, value, provider string) {
+	t.Helper()
+	storage := testStorage(t)
+	if _, err := storage.AddKey(name, value, provider); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { storage.DeleteKey(name) })
+}
+
+// fakeUpstream points provider's route at handler for the duration of the test.
+func fakeUpstream(t *testing.T, provider string, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	route, ok := providerRoutes[provider]
+	if !ok {
+		t.Fatalf("no route for provider %s", provider)
+	}
+	orig := route
+	route.BaseURL = srv.URL
+	providerRoutes[provider] = route
+	t.Cleanup(func() { providerRoutes[provider] = orig })
+	return srv
+}
+
+// newProxyRouter mounts the proxy routes the way StartServer does, without
+// the API-key middleware.
+func newProxyRouter() *gin.Engine {
+	r := gin.New()
+	r.Use(recoveryMiddleware())
+	registerProxyRoutes(r.Group("/v1"))
+	return r
+}
@@ -0,0 +1,156 @@
+package http
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// latencyBuckets are the upper bounds (seconds) of the proxy latency
+// histogram; LLM calls run long, so they reach further than the usual
+// Prometheus defaults.
+var latencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+// proxyMetrics holds the proxy's counters and latency histogram, served in
+// the Prometheus text exposition format by metricsHandler. Values are keyed
+// by their label values joined with "\x00".
+type proxyMetrics struct {
+	mu               sync.Mutex
+	requests         map[string]float64 // provider
+	upstreamStatus   map[string]float64 // provider, code
+	budgetRejections map[string]float64 // provider
+	decryptFailures  map[string]float64 // provider
+	latency          map[string]*histogram
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative
+	sum    float64
+	count  uint64
+}
+
+var metrics = &proxyMetrics{
+	requests:         make(map[string]float64),
+	upstreamStatus:   make(map[string]float64),
+	budgetRejections: make(map[string]float64),
+	decryptFailures:  make(map[string]float64),
+	latency:          make(map[string]*histogram),
+}
+
+func labelKey(values ...string) string {
+	return strings.Join(values, "\x00")
+}
+
+// proxied counts a request routed to provider.
+func (m *proxyMetrics) proxied(provider string) {
+	m.mu.Lock()
+	m.requests[provider]++
+	m.mu.Unlock()
+}
+
+// upstream records an upstream answer (code "error" when none arrived) and
+// how long it took to get one.
+func (m *proxyMetrics) upstream(provider, code string, elapsed time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.upstreamStatus[labelKey(provider, code)]++
+	h, ok := m.latency[provider]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(latencyBuckets))}
+		m.latency[provider] = h
+	}
+	seconds := elapsed.Seconds()
+	for i, bound := range latencyBuckets {
+		if seconds <= bound {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+// budgetRejected counts a request refused because provider is over budget.
+func (m *proxyMetrics) budgetRejected(provider string) {
+	m.mu.Lock()
+	m.budgetRejections[provider]++
+	m.mu.Unlock()
+}
+
+// decryptFailed counts a key of provider that could not be decrypted.
+func (m *proxyMetrics) decryptFailed(provider string) {
+	m.mu.Lock()
+	m.decryptFailures[provider]++
+	m.mu.Unlock()
+}
+
+// write renders every metric in the Prometheus text format, series sorted so
+// scrapes are stable.
+func (m *proxyMetrics) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	writeCounter(w, "akm_proxy_requests_total", "Requests routed to an upstream provider.",
+		[]string{"provider"}, m.requests)
+	writeCounter(w, "akm_proxy_upstream_responses_total", "Upstream responses by status code (\"error\" when the request failed).",
+		[]string{"provider", "code"}, m.upstreamStatus)
+	writeCounter(w, "akm_proxy_budget_rejections_total", "Requests rejected because the provider budget was exhausted.",
+		[]string{"provider"}, m.budgetRejections)
+	writeCounter(w, "akm_proxy_decrypt_failures_total", "Keys that failed to decrypt while selecting a proxy key.",
+		[]string{"provider"}, m.decryptFailures)
+
+	const name = "akm_proxy_request_duration_seconds"
+	fmt.Fprintf(w, "# HELP %s Time until the upstream answered with headers or failed.\n", name)
+	fmt.Fprintf(w, "# TYPE %s histogram\n", name)
+	for _, provider := range sortedKeys(m.latency) {
+		h := m.latency[provider]
+		var cumulative uint64
+		for i, bound := range latencyBuckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(w, "%s_bucket{provider=%q,le=%q} %d\n", name, provider, formatFloat(bound), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket{provider=%q,le=\"+Inf\"} %d\n", name, provider, h.count)
+		fmt.Fprintf(w, "%s_sum{provider=%q} %s\n", name, provider, formatFloat(h.sum))
+		fmt.Fprintf(w, "%s_count{provider=%q} %d\n", name, provider, h.count)
+	}
+}
+
+func writeCounter(w io.Writer, name, help string, labels []string, values map[string]float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s counter\n", name)
+	for _, key := range sortedKeys(values) {
+		parts := strings.Split(key, "\x00")
+		pairs := make([]string, len(labels))
+		for i, label := range labels {
+			pairs[i] = fmt.Sprintf("%s=%q", label, parts[i])
+		}
+		fmt.Fprintf(w, "%s{%s} %s\n", name, strings.Join(pairs, ","), formatFloat(values[key]))
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// metricsHandler serves /metrics for Prometheus scrapers.
+func metricsHandler(c *gin.Context) {
+	c.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.Status(http.StatusOK)
+	metrics.write(c.Writer)
+}
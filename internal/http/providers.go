@@ -0,0 +1,71 @@
+package http
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/baobao/akm-go/internal/core"
+	"github.com/gin-gonic/gin"
+)
+
+type providerResponse struct {
+	ID            string        `json:"id"`
+	Name          string        `json:"name,omitempty"`
+	BaseURL       string        `json:"base_url"`
+	AuthHeader    string        `json:"auth_header"`
+	RequiresVPN   bool          `json:"requires_vpn"`
+	ModelPrefixes []string      `json:"model_prefixes,omitempty"`
+	HasActiveKey  bool          `json:"has_active_key"`
+	Circuit       circuitStatus `json:"circuit"`
+}
+
+// providersHandler lists the providers the proxy can route to.
+func providersHandler(c *gin.Context) {
+	storage, err := core.GetStorage()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	platforms := make(map[string]int)
+	registry := core.Platforms()
+	for i, p := range registry {
+		platforms[p.ID] = i
+	}
+
+	// Expired keys are refused by the proxy, so they do not count
+	now := time.Now()
+	active := make(map[string]bool)
+	for _, key := range storage.ListKeys("") {
+		if key.IsActive && !key.IsExpired(now) {
+			active[key.Provider] = true
+		}
+	}
+
+	response := make([]providerResponse, 0, len(providerRoutes))
+	for id, route := range providerRoutes {
+		p := providerResponse{
+			ID:           id,
+			BaseURL:      route.BaseURL,
+			AuthHeader:   route.AuthHeader,
+			HasActiveKey: active[id],
+			Circuit:      circuitFor(id).status(),
+		}
+		if i, ok := platforms[id]; ok {
+			p.Name = registry[i].Name
+			p.RequiresVPN = registry[i].RequiresVPN
+			p.ModelPrefixes = registry[i].ModelPrefixes
+		}
+		if extra := routeModelPrefixes[id]; len(extra) > 0 {
+			p.ModelPrefixes = append(append([]string(nil), p.ModelPrefixes...), extra...)
+		}
+		response = append(response, p)
+	}
+	sort.Slice(response, func(i, j int) bool { return response[i].ID < response[j].ID })
+
+	c.JSON(http.StatusOK, gin.H{
+		"providers": response,
+		"count":     len(response),
+	})
+}
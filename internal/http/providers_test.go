@@ -0,0 +1,77 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getProviders returns GET /api/providers keyed by provider ID.
+func getProviders(t *testing.T) map[string]providerResponse {
+	t.Helper()
+	r := gin.New()
+	r.GET("/api/providers", providersHandler)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/providers", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	var body struct {
+		Providers []providerResponse `json:"providers"`
+		Count     int                `json:"count"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Count != len(body.Providers) {
+		t.Errorf("count = %d, but %d providers listed", body.Count, len(body.Providers))
+	}
+	providers := make(map[string]providerResponse, len(body.Providers))
+	for _, p := range body.Providers {
+		providers[p.ID] = p
+	}
+	return providers
+}
+
+func TestProvidersListsBuiltins(t *testing.T) {
+	providers := getProviders(t)
+	for id, authHeader := range map[string]string{
+		"openai":    "Authorization",
+		"anthropic": "x-api-key",
+		"deepseek":  "Authorization",
+		"gemini":    "x-goog-api-key",
+		"zhipu":     "Authorization",
+	} {
+		p, ok := providers[id]
+		if !ok {
+			t.Errorf("provider %s missing", id)
+			continue
+		}
+		if p.BaseURL == "" {
+			t.Errorf("%s: empty base_url", id)
+		}
+		if p.AuthHeader != authHeader {
+			t.Errorf("%s: auth_header = %q, want %q", id, p.AuthHeader, authHeader)
+		}
+	}
+}
+
+func TestProvidersExpiredKeyIsNotActive(t *testing.T) {
+	addTestKey(t, "DEEPSEEK_API_KEY", "sk-test-0123456789abcdef", "deepseek")
+	storage := testStorage(t)
+
+	if !getProviders(t)["deepseek"].HasActiveKey {
+		t.Fatal("deepseek has_active_key = false with a live key")
+	}
+	past := time.Now().Add(-time.Hour)
+	if _, err := storage.UpdateKey("DEEPSEEK_API_KEY", map[string]interface{}{"expires_at": past}); err != nil {
+		t.Fatal(err)
+	}
+	if getProviders(t)["deepseek"].HasActiveKey {
+		t.Error("deepseek has_active_key = true with only an expired key")
+	}
+}
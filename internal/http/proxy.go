@@ -1,13 +1,23 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
+	"mime"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"os"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/baobao/akm-go/internal/core"
 	"github.com/gin-gonic/gin"
@@ -15,118 +25,480 @@ import (
 
 // ProviderRoute defines how to reach a provider's API.
 type ProviderRoute struct {
-	BaseURL      string
-	AuthHeader   string            // e.g. "Authorization", "x-api-key"
-	AuthPrefix   string            // e.g. "Bearer "
-	ExtraHeaders map[string]string // e.g. anthropic-version
+	BaseURL      string            `yaml:"base_url"`
+	AuthHeader   string            `yaml:"auth_header"`   // e.g. "Authorization", "x-api-key"
+	AuthPrefix   string            `yaml:"auth_prefix"`   // e.g. "Bearer "
+	ExtraHeaders map[string]string `yaml:"extra_headers"` // e.g. anthropic-version
 }
 
-var providerRoutes = map[string]ProviderRoute{
-	"openai": {
-		BaseURL:    "https://api.openai.com",
-		AuthHeader: "Authorization",
-		AuthPrefix: "Bearer ",
-	},
-	"anthropic": {
-		BaseURL:    "https://api.anthropic.com",
+var providerRoutes = registryRoutes()
+
+// formatAuth is how the proxy authenticates to each platform api_format.
+var formatAuth = map[string]ProviderRoute{
+	"openai": {AuthHeader: "Authorization", AuthPrefix: "Bearer "},
+	"claude": {
 		AuthHeader: "x-api-key",
 		ExtraHeaders: map[string]string{
 			"anthropic-version": "2023-06-01",
 		},
 	},
-	"deepseek": {
-		BaseURL:    "https://api.deepseek.com",
-		AuthHeader: "Authorization",
-		AuthPrefix: "Bearer ",
-	},
-	"gemini": {
-		BaseURL:    "https://generativelanguage.googleapis.com",
-		AuthHeader: "x-goog-api-key",
-	},
-	"zhipu": {
-		BaseURL:    "https://open.bigmodel.cn/api/paas",
-		AuthHeader: "Authorization",
-		AuthPrefix: "Bearer ",
-	},
+	"google": {AuthHeader: "x-goog-api-key"},
+}
+
+// registryRoutes builds a route for every active platform in the registry
+// whose api_format the proxy knows how to authenticate.
+func registryRoutes() map[string]ProviderRoute {
+	routes := make(map[string]ProviderRoute)
+	for _, p := range core.Platforms() {
+		auth, ok := formatAuth[p.APIFormat]
+		if !ok || !p.IsActive {
+			continue
+		}
+		route := auth
+		route.BaseURL = p.APIBase
+		route.ExtraHeaders = maps.Clone(auth.ExtraHeaders)
+		routes[p.ID] = route
+	}
+	return routes
+}
+
+// pathProviders maps provider-specific API paths to the provider that owns them.
+var pathProviders = map[string]string{
+	"/v1/messages": "anthropic", // Messages API, incl. /v1/messages/count_tokens and batches
 }
 
-// model prefix → provider mapping for auto-detection
-var modelPrefixMap = map[string]string{
-	"gpt-":      "openai",
-	"o1-":       "openai",
-	"o3-":       "openai",
-	"o4-":       "openai",
-	"claude-":   "anthropic",
-	"deepseek-": "deepseek",
-	"gemini-":   "gemini",
-	"glm-":      "zhipu",
+// splitProviderPath recognizes a provider-qualified path such as
+// /v1/anthropic/messages, returning the provider and the upstream path
+// (/v1/messages) with the provider segment removed.
+func splitProviderPath(path string) (provider, upstreamPath string, ok bool) {
+	rest, found := strings.CutPrefix(path, "/v1/")
+	if !found {
+		return "", "", false
+	}
+	id, tail, _ := strings.Cut(rest, "/")
+	if _, known := providerRoutes[strings.ToLower(id)]; !known || tail == "" {
+		return "", "", false
+	}
+	return strings.ToLower(id), "/v1/" + tail, true
+}
+
+// defaultProvider returns AKM_DEFAULT_PROVIDER, the provider used when nothing
+// in the request identifies one; unknown values are ignored.
+func defaultProvider() string {
+	provider := strings.ToLower(strings.TrimSpace(os.Getenv("AKM_DEFAULT_PROVIDER")))
+	if _, ok := providerRoutes[provider]; !ok {
+		return ""
+	}
+	return provider
 }
 
-// resolveProvider determines the provider from header or model name.
-func resolveProvider(header string, body []byte) (string, error) {
+// resolveProvider determines the provider from header, request path, or model
+// name, falling back to AKM_DEFAULT_PROVIDER; usedDefault reports the fallback.
+func resolveProvider(header, path string, body []byte) (provider string, usedDefault bool, err error) {
+	qualified, _, isQualified := splitProviderPath(path)
+
 	// 1. Explicit header takes priority
 	if header != "" {
 		header = strings.ToLower(strings.TrimSpace(header))
-		if _, ok := providerRoutes[header]; ok {
-			return header, nil
+		if _, ok := providerRoutes[header]; !ok {
+			return "", false, fmt.Errorf("unknown provider: %s", header)
 		}
-		return "", fmt.Errorf("unknown provider: %s", header)
+		if isQualified && qualified != header {
+			return "", false, fmt.Errorf("X-AKM-Provider %s conflicts with path provider %s", header, qualified)
+		}
+		return header, false, nil
+	}
+
+	// 2. Provider-qualified path, e.g. /v1/anthropic/messages
+	if isQualified {
+		return qualified, false, nil
 	}
 
-	// 2. Infer from model name in request body
+	// 3. Provider-specific endpoints
+	for prefix, provider := range pathProviders {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return provider, false, nil
+		}
+	}
+
+	// 4. Infer from model name in request body
 	var req struct {
 		Model string `json:"model"`
 	}
 	if err := json.Unmarshal(body, &req); err == nil && req.Model != "" {
-		model := strings.ToLower(req.Model)
-		for prefix, provider := range modelPrefixMap {
-			if strings.HasPrefix(model, prefix) {
-				return provider, nil
+		if provider, ok := core.ProviderForModel(req.Model); ok {
+			return provider, false, nil
+		}
+
+		// 5. Heuristic: model family name appearing anywhere in the model
+		candidates := core.ModelCandidates(req.Model)
+		switch len(candidates) {
+		case 1:
+			return candidates[0], false, nil
+		case 0:
+			if fallback := defaultProvider(); fallback != "" {
+				return fallback, true, nil
 			}
+			return "", false, fmt.Errorf("cannot determine provider for model '%s': set X-AKM-Provider to one of: %s", req.Model, strings.Join(knownProviders(), ", "))
+		default:
+			return "", false, fmt.Errorf("model '%s' is ambiguous, candidates: %s; set X-AKM-Provider to choose", req.Model, strings.Join(candidates, ", "))
 		}
 	}
 
-	return "", fmt.Errorf("cannot determine provider: set X-AKM-Provider header or use a recognizable model name")
+	// 6. Last resort: the configured default
+	if fallback := defaultProvider(); fallback != "" {
+		return fallback, true, nil
+	}
+	return "", false, fmt.Errorf("cannot determine provider: set X-AKM-Provider header, use a provider-qualified path (/v1/{provider}/...), a recognizable model name, or set AKM_DEFAULT_PROVIDER")
+}
+
+// strictProvider rejects models whose provider is recognized but which are not
+// in that platform's SupportedModels list. Set by StartServer; default lenient.
+var strictProvider bool
+
+// checkStrictModel returns an error listing valid models when strict mode is
+// on and the body names a model its platform does not list.
+func checkStrictModel(body []byte) error {
+	if !strictProvider {
+		return nil
+	}
+	var req struct {
+		Model string `json:"model"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil || req.Model == "" {
+		return nil
+	}
+	if provider, valid, ok := core.CheckSupportedModel(req.Model); !ok {
+		return fmt.Errorf("model '%s' is not a known %s model (strict provider mode); valid models: %s",
+			req.Model, provider, strings.Join(valid, ", "))
+	}
+	return nil
+}
+
+// knownProviders returns the sorted provider IDs the proxy can route to.
+func knownProviders() []string {
+	ids := make([]string, 0, len(providerRoutes))
+	for id := range providerRoutes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// shouldValidateJSON reports whether the body should be checked as JSON:
+// enabled by AKM_VALIDATE_JSON (default on), non-empty, and sent as JSON.
+func shouldValidateJSON(req *http.Request, body []byte) bool {
+	if len(body) == 0 || !parseBoolEnv("AKM_VALIDATE_JSON", true) {
+		return false
+	}
+	return isJSONContent(req)
+}
+
+// isJSONContent reports whether the request declares a JSON body.
+func isJSONContent(req *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// loadDefaultModels parses AKM_PROXY_DEFAULT_MODELS ("openai=gpt-4o-mini,
+// anthropic=claude-sonnet-4-5"). Providers not listed get no default.
+func loadDefaultModels() map[string]string {
+	defaults := make(map[string]string)
+	for _, item := range strings.Split(os.Getenv("AKM_PROXY_DEFAULT_MODELS"), ",") {
+		provider, model, ok := strings.Cut(item, "=")
+		provider, model = strings.ToLower(strings.TrimSpace(provider)), strings.TrimSpace(model)
+		if ok && provider != "" && model != "" {
+			defaults[provider] = model
+		}
+	}
+	return defaults
+}
+
+// applyDefaultModel injects the provider's configured default model into a
+// JSON object body whose "model" is absent, null or empty. It returns the
+// body unchanged (and false) in every other case.
+func applyDefaultModel(req *http.Request, provider string, body []byte) ([]byte, bool) {
+	model, ok := loadDefaultModels()[provider]
+	if !ok || len(body) == 0 || !isJSONContent(req) {
+		return body, false
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return body, false
+	}
+	if current, ok := fields["model"]; ok {
+		var name *string
+		if err := json.Unmarshal(current, &name); err != nil || (name != nil && *name != "") {
+			return body, false
+		}
+	}
+	encoded, err := json.Marshal(model)
+	if err != nil {
+		return body, false
+	}
+	fields["model"] = encoded
+	updated, err := json.Marshal(fields)
+	if err != nil {
+		return body, false
+	}
+	return updated, true
+}
+
+// defaultParams holds per-provider JSON fields merged into proxied request
+// bodies when absent, from AKM_PROXY_DEFAULT_PARAMS. Set by StartServer.
+var defaultParams map[string]map[string]json.RawMessage
+
+// loadDefaultParams parses AKM_PROXY_DEFAULT_PARAMS, a JSON object keyed by
+// provider, e.g. {"anthropic":{"max_tokens":1024},"openai":{"temperature":0.2}}.
+// Providers not listed are left untouched.
+func loadDefaultParams() (map[string]map[string]json.RawMessage, error) {
+	raw := strings.TrimSpace(os.Getenv("AKM_PROXY_DEFAULT_PARAMS"))
+	if raw == "" {
+		return nil, nil
+	}
+	var parsed map[string]map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(raw), &parsed); err != nil {
+		return nil, fmt.Errorf("invalid AKM_PROXY_DEFAULT_PARAMS: %w", err)
+	}
+	params := make(map[string]map[string]json.RawMessage, len(parsed))
+	for provider, fields := range parsed {
+		provider = strings.ToLower(strings.TrimSpace(provider))
+		if _, ok := providerRoutes[provider]; !ok {
+			return nil, fmt.Errorf("invalid AKM_PROXY_DEFAULT_PARAMS: unknown provider %q", provider)
+		}
+		if len(fields) > 0 {
+			params[provider] = fields
+		}
+	}
+	return params, nil
+}
+
+// applyDefaultParams adds the provider's configured default fields to a JSON
+// object body, skipping any field the client already sent (even as null). It
+// returns the body unchanged (and false) when nothing was added.
+func applyDefaultParams(req *http.Request, provider string, body []byte) ([]byte, bool) {
+	defaults := defaultParams[provider]
+	if len(defaults) == 0 || len(body) == 0 || !isJSONContent(req) {
+		return body, false
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil || fields == nil {
+		return body, false
+	}
+	added := false
+	for name, value := range defaults {
+		if _, ok := fields[name]; !ok {
+			fields[name] = value
+			added = true
+		}
+	}
+	if !added {
+		return body, false
+	}
+	updated, err := json.Marshal(fields)
+	if err != nil {
+		return body, false
+	}
+	return updated, true
+}
+
+// DefaultProxyTimeout bounds non-streaming proxied requests when
+// AKM_PROXY_TIMEOUT is unset.
+const DefaultProxyTimeout = 120 * time.Second
+
+// proxyTimeout returns the total deadline for non-streaming requests from
+// AKM_PROXY_TIMEOUT (a Go duration, e.g. "90s"); 0 disables it.
+func proxyTimeout() time.Duration {
+	raw := strings.TrimSpace(os.Getenv("AKM_PROXY_TIMEOUT"))
+	if raw == "" {
+		return DefaultProxyTimeout
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d < 0 {
+		return DefaultProxyTimeout
+	}
+	return d
+}
+
+// DefaultProxyMaxBodyBytes caps proxied request bodies when
+// AKM_PROXY_MAX_BODY_BYTES is unset.
+const DefaultProxyMaxBodyBytes = 10 << 20
+
+// proxyMaxBodyBytes returns the largest request body the proxy will buffer,
+// from AKM_PROXY_MAX_BODY_BYTES; 0 disables the limit.
+func proxyMaxBodyBytes() int64 {
+	raw := strings.TrimSpace(os.Getenv("AKM_PROXY_MAX_BODY_BYTES"))
+	if raw == "" {
+		return DefaultProxyMaxBodyBytes
+	}
+	n, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || n < 0 {
+		return DefaultProxyMaxBodyBytes
+	}
+	return n
+}
+
+// DefaultProxyHeaderTimeout bounds the wait for upstream response headers
+// when AKM_PROXY_HEADER_TIMEOUT is unset. It matches DefaultProxyTimeout
+// because a non-streaming completion only sends headers once it is done.
+const DefaultProxyHeaderTimeout = 120 * time.Second
+
+// proxyHeaderTimeout reads AKM_PROXY_HEADER_TIMEOUT (a Go duration); 0
+// disables it.
+func proxyHeaderTimeout() time.Duration {
+	raw := strings.TrimSpace(os.Getenv("AKM_PROXY_HEADER_TIMEOUT"))
+	if raw == "" {
+		return DefaultProxyHeaderTimeout
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d < 0 {
+		return DefaultProxyHeaderTimeout
+	}
+	return d
+}
+
+var (
+	upstreamTransportOnce sync.Once
+	upstreamTransport     http.RoundTripper
+	upstreamHeaderTimeout time.Duration
+)
+
+// getUpstreamTransport returns the shared transport for proxied requests. It
+// only limits the wait for response headers, so a hung provider is cut off
+// while a stream that has started may run as long as it needs.
+func getUpstreamTransport() http.RoundTripper {
+	upstreamTransportOnce.Do(func() {
+		upstreamHeaderTimeout = proxyHeaderTimeout()
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		t.ResponseHeaderTimeout = upstreamHeaderTimeout
+		upstreamTransport = t
+	})
+	return upstreamTransport
+}
+
+// isStreamingRequest reports whether the client asked for a streamed (SSE)
+// response, either via Accept or a "stream": true body field.
+func isStreamingRequest(req *http.Request, body []byte) bool {
+	if strings.Contains(req.Header.Get("Accept"), "text/event-stream") {
+		return true
+	}
+	var payload struct {
+		Stream bool `json:"stream"`
+	}
+	return json.Unmarshal(body, &payload) == nil && payload.Stream
 }
 
-// selectKey picks the API key to use for the given provider.
-func selectKey(storage *core.KeyStorage, provider, keyName string) (string, error) {
+// selectKey picks the API key to use for the given provider. Passphrase-protected
+// keys are only usable with a passphrase; without one they are skipped when
+// auto-selecting, as are expired keys. It returns the chosen key's name and value.
+func selectKey(storage *core.KeyStorage, provider, keyName, passphrase string) (string, string, error) {
 	// Explicit key name requested
 	if keyName != "" {
-		value, err := storage.GetKeyValue(keyName, "proxy")
+		value, err := storage.GetKeyValueWithPassphrase(keyName, "proxy", passphrase)
 		if err != nil {
-			return "", fmt.Errorf("key '%s' not found or decrypt failed: %w", keyName, err)
+			metrics.decryptFailed(provider)
+			return "", "", fmt.Errorf("key '%s' not found or decrypt failed: %w", keyName, err)
 		}
-		return value, nil
+		return keyName, value, nil
 	}
 
 	// Find first active key for provider
 	keys := storage.ListKeys(provider)
+	now := time.Now()
+	skippedProtected := false
 	for _, k := range keys {
-		if k.IsActive {
-			value, err := storage.GetKeyValue(k.Name, "proxy")
+		if k.IsActive && !k.IsExpired(now) {
+			if k.PassphraseProtected && passphrase == "" {
+				skippedProtected = true
+				continue
+			}
+			value, err := storage.GetKeyValueWithPassphrase(k.Name, "proxy", passphrase)
 			if err != nil {
+				metrics.decryptFailed(provider)
 				continue
 			}
-			return value, nil
+			return k.Name, value, nil
 		}
 	}
-	return "", fmt.Errorf("no active key found for provider '%s'", provider)
+	if skippedProtected {
+		return "", "", fmt.Errorf("active keys for provider '%s' require X-AKM-Key-Passphrase", provider)
+	}
+	return "", "", fmt.Errorf("no active key found for provider '%s'", provider)
+}
+
+// selectAlternateKey picks an active, unprotected key for provider that is not
+// in exclude, for failing over a rejected request.
+func selectAlternateKey(storage *core.KeyStorage, provider string, exclude map[string]bool) (string, string, bool) {
+	now := time.Now()
+	keys := storage.ListKeys(provider)
+	// Same order on every request, so failover walks the keys predictably
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Name < keys[j].Name })
+	for _, k := range keys {
+		if !k.IsActive || k.IsExpired(now) || k.PassphraseProtected || exclude[k.Name] {
+			continue
+		}
+		value, err := storage.GetKeyValue(k.Name, "proxy")
+		if err != nil {
+			metrics.decryptFailed(provider)
+			continue
+		}
+		return k.Name, value, true
+	}
+	return "", "", false
 }
 
 // proxyHandler handles /v1/* requests by proxying to the upstream provider.
 func proxyHandler(c *gin.Context) {
-	// Read request body (needed for provider detection)
+	// Read request body (needed for provider detection), refusing to buffer
+	// more than the configured limit
+	maxBody := proxyMaxBodyBytes()
+	if maxBody > 0 {
+		if c.Request.ContentLength > maxBody {
+			writeProxyError(c.Writer, http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("request body exceeds %d bytes", maxBody), "invalid_request_error")
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBody)
+	}
 	bodyBytes, err := io.ReadAll(c.Request.Body)
 	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeProxyError(c.Writer, http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("request body exceeds %d bytes", maxBody), "invalid_request_error")
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
 		return
 	}
 	c.Request.Body = io.NopCloser(strings.NewReader(string(bodyBytes)))
 
+	// Reject malformed JSON locally instead of paying for an upstream 400
+	if shouldValidateJSON(c.Request, bodyBytes) && !json.Valid(bodyBytes) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]string{
+				"message": "request body is not valid JSON",
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+
+	// Only known providers may qualify a path; anything else is not a route
+	if c.Param("provider") != "" {
+		if _, _, ok := splitProviderPath(c.Request.URL.Path); !ok {
+			writeProxyError(c.Writer, http.StatusNotFound,
+				fmt.Sprintf("unknown provider in path: %s (known: %s)", c.Param("provider"), strings.Join(knownProviders(), ", ")), "invalid_request_error")
+			return
+		}
+	}
+
 	// Resolve provider
 	providerHeader := c.GetHeader("X-AKM-Provider")
-	provider, err := resolveProvider(providerHeader, bodyBytes)
+	provider, usedDefault, err := resolveProvider(providerHeader, c.Request.URL.Path, bodyBytes)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": map[string]string{
@@ -136,6 +508,24 @@ func proxyHandler(c *gin.Context) {
 		})
 		return
 	}
+	if usedDefault {
+		c.Header("X-AKM-Resolved-Provider", provider)
+	}
+	// Upstream sees the plain path: /v1/anthropic/messages -> /v1/messages
+	if _, upstreamPath, ok := splitProviderPath(c.Request.URL.Path); ok {
+		c.Request.URL.Path, c.Request.URL.RawPath = upstreamPath, ""
+	}
+
+	if err := checkStrictModel(bodyBytes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]string{
+				"message": err.Error(),
+				"type":    "invalid_request_error",
+				"code":    "model_not_found",
+			},
+		})
+		return
+	}
 
 	route, ok := providerRoutes[provider]
 	if !ok {
@@ -148,10 +538,25 @@ func proxyHandler(c *gin.Context) {
 		return
 	}
 
+	// Fill in a configured default model for clients that omit one
+	if updated, ok := applyDefaultModel(c.Request, provider, bodyBytes); ok {
+		bodyBytes = updated
+		c.Request.Body = io.NopCloser(strings.NewReader(string(bodyBytes)))
+		c.Request.ContentLength = int64(len(bodyBytes))
+	}
+
+	// Cost governance: fill in per-provider defaults such as max_tokens
+	if updated, ok := applyDefaultParams(c.Request, provider, bodyBytes); ok {
+		bodyBytes = updated
+		c.Request.Body = io.NopCloser(strings.NewReader(string(bodyBytes)))
+		c.Request.ContentLength = int64(len(bodyBytes))
+	}
+
 	// Budget check
 	budget, err := core.GetBudgetTracker()
 	if err == nil {
 		if err := budget.Check(provider); err != nil {
+			metrics.budgetRejected(provider)
 			c.JSON(http.StatusTooManyRequests, gin.H{
 				"error": map[string]string{
 					"message": err.Error(),
@@ -175,7 +580,7 @@ func proxyHandler(c *gin.Context) {
 	}
 
 	keyName := c.GetHeader("X-AKM-Key")
-	apiKey, err := selectKey(storage, provider, keyName)
+	apiKeyName, apiKey, err := selectKey(storage, provider, keyName, c.GetHeader("X-AKM-Key-Passphrase"))
 	if err != nil {
 		c.JSON(http.StatusBadGateway, gin.H{
 			"error": map[string]string{
@@ -198,7 +603,32 @@ func proxyHandler(c *gin.Context) {
 		return
 	}
 
+	// Streams may legitimately run for a long time, so only non-streaming
+	// requests get a total deadline
+	streaming := isStreamingRequest(c.Request, bodyBytes)
+	var timeout time.Duration
+	if !streaming {
+		timeout = proxyTimeout()
+	}
+
+	// Fast-fail while the provider's circuit is open instead of waiting on
+	// an upstream that keeps failing
+	breaker := circuitFor(provider)
+	if ok, wait := breaker.allow(); !ok {
+		retryAfter := int(wait.Seconds()) + 1
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		writeProxyError(c.Writer, http.StatusServiceUnavailable,
+			fmt.Sprintf("provider %s is failing, circuit open; retry in %ds", provider, retryAfter), "provider_unavailable")
+		return
+	}
+
+	metrics.proxied(provider)
+	start := time.Now()
+
+	var retried string // set when a 429 was retried, reported in X-AKM-Retry
+	logBodies := proxyLogBodies()
 	proxy := &httputil.ReverseProxy{
+		Transport: getUpstreamTransport(),
 		Director: func(req *http.Request) {
 			req.URL.Scheme = target.Scheme
 			req.URL.Host = target.Host
@@ -207,14 +637,17 @@ func proxyHandler(c *gin.Context) {
 			// Inject provider auth
 			req.Header.Set(route.AuthHeader, route.AuthPrefix+apiKey)
 
-			// Set extra headers
+			// Set extra headers, keeping any the client already chose (e.g. a newer anthropic-version)
 			for k, v := range route.ExtraHeaders {
-				req.Header.Set(k, v)
+				if req.Header.Get(k) == "" {
+					req.Header.Set(k, v)
+				}
 			}
 
 			// Remove AKM-specific headers
 			req.Header.Del("X-AKM-Provider")
 			req.Header.Del("X-AKM-Key")
+			req.Header.Del("X-AKM-Key-Passphrase")
 
 			// Remove original Authorization (replaced by provider key)
 			if route.AuthHeader != "Authorization" {
@@ -222,13 +655,122 @@ func proxyHandler(c *gin.Context) {
 			}
 		},
 		ModifyResponse: func(resp *http.Response) error {
+			// Don't bill a request the client already abandoned
+			if err := resp.Request.Context().Err(); err != nil {
+				return err
+			}
+			metrics.upstream(provider, strconv.Itoa(resp.StatusCode), time.Since(start))
+			// The body is passed through as-is; only fix the framing headers
+			// if the transport decoded it
+			normalizeEncodingHeaders(resp)
+			if logBodies {
+				logResponseBody(resp, provider, apiKey)
+			}
+			if retried != "" {
+				resp.Header.Set("X-AKM-Retry", retried)
+			}
+			if resp.StatusCode >= http.StatusInternalServerError {
+				breaker.failure()
+			} else {
+				breaker.success()
+			}
 			// Record usage after successful proxy
 			if budget != nil {
-				budget.Record(provider)
+				guard("budget record", func() { budget.Record(provider) })
 			}
 			return nil
 		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			metrics.upstream(provider, "error", time.Since(start))
+			if errors.Is(err, context.DeadlineExceeded) && r.Context().Err() != nil {
+				breaker.failure()
+				writeProxyError(w, http.StatusGatewayTimeout, fmt.Sprintf("upstream did not respond within %s", timeout), "timeout_error")
+				return
+			}
+			if errors.Is(err, context.Canceled) {
+				breaker.release()
+				return // client went away, nobody to answer
+			}
+			// The request's own deadline is intact, so this is the
+			// transport's response-header timeout
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				breaker.failure()
+				writeProxyError(w, http.StatusGatewayTimeout, fmt.Sprintf("upstream sent no response headers within %s", upstreamHeaderTimeout), "timeout_error")
+				return
+			}
+			breaker.failure()
+			writeProxyError(w, http.StatusBadGateway, fmt.Sprintf("upstream request failed: %v", err), "upstream_error")
+		},
+	}
+
+	// Flush every write for streams so tokens reach the client as they are
+	// produced, whatever Content-Type the upstream labels them with.
+	// ModifyResponse runs once on the headers, so budget is still recorded
+	// once per request without waiting for the stream to finish.
+	if streaming {
+		proxy.FlushInterval = -1
 	}
 
+	// Opt-in retries: AKM_PROXY_MAX_KEY_RETRIES fails over to other keys on
+	// 401/429; AKM_PROXY_RETRY_429 retries a rate-limited non-streaming
+	// request once, on another key or after a short Retry-After
+	maxKeys := maxKeyRetries()
+	wait429 := timeout > 0 && retry429Enabled()
+	keyStatuses := []int{http.StatusUnauthorized, http.StatusTooManyRequests}
+	if maxKeys == 0 && wait429 {
+		maxKeys, keyStatuses = 1, []int{http.StatusTooManyRequests}
+	}
+	if keyName != "" {
+		maxKeys = 0 // the client pinned this key
+	}
+	if maxKeys > 0 || wait429 {
+		tried := map[string]bool{apiKeyName: true}
+		usedKey := apiKeyName
+		proxy.Transport = &retryTransport{
+			base:        getUpstreamTransport(),
+			body:        bodyBytes,
+			maxWait:     retryMaxWait(),
+			keyStatuses: keyStatuses,
+			maxKeys:     maxKeys,
+			wait429:     wait429,
+			authHeader:  route.AuthHeader,
+			failover: func() (string, string, bool) {
+				name, value, ok := selectAlternateKey(storage, provider, tried)
+				if ok {
+					tried[name] = true
+				}
+				return name, route.AuthPrefix + value, ok
+			},
+			onRetry: func(action string) {
+				kind, detail, _ := strings.Cut(action, ":")
+				retried = kind
+				logInfo("proxy %s: upstream rejected key %s, retrying (%s %s)", provider, usedKey, kind, detail)
+				if kind == "failover" {
+					usedKey = detail
+				}
+			},
+		}
+	}
+
+	// The outbound request inherits c.Request's context, so a client
+	// disconnect cancels the upstream call as well.
+	if timeout > 0 {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+	}
 	proxy.ServeHTTP(c.Writer, c.Request)
 }
+
+// writeProxyError writes an OpenAI-style error body outside of a gin handler.
+func writeProxyError(w http.ResponseWriter, status int, message, errType string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(gin.H{
+		"error": map[string]string{
+			"message": message,
+			"type":    errType,
+		},
+	})
+}
@@ -0,0 +1,220 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baobao/akm-go/internal/core"
+)
+
+// budgetCount returns today's recorded request count for provider.
+func budgetCount(t *testing.T, provider string) int64 {
+	t.Helper()
+	budget, err := core.GetBudgetTracker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range budget.GetAllStats() {
+		if s.Provider == provider {
+			return s.DailyCount
+		}
+	}
+	return 0
+}
+
+// A client that disconnects before the upstream answers must not be billed.
+func TestProxyClientCancelSkipsBudget(t *testing.T) {
+	addTestKey(t, "CANCEL_OPENAI_KEY", "sk-cancel-0123456789abcdef", "openai")
+	arrived := make(chan struct{})
+	upstreamCanceled := make(chan struct{})
+	fakeUpstream(t, "openai", func(w http.ResponseWriter, r *http.Request) {
+		// The server only notices the proxy hanging up once the body
+		// has been read
+		io.Copy(io.Discard, r.Body)
+		close(arrived)
+		<-r.Context().Done()
+		close(upstreamCanceled)
+	})
+	before := budgetCount(t, "openai")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+		strings.NewReader(`{"model":"gpt-4o-mini","messages":[]}`)).WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		newProxyRouter().ServeHTTP(rec, req)
+	}()
+	select {
+	case <-arrived:
+	case <-time.After(5 * time.Second):
+		t.Fatal("request never reached the upstream")
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("proxy did not return after the client cancelled")
+	}
+	select {
+	case <-upstreamCanceled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("upstream request was not canceled")
+	}
+	if rec.Code != http.StatusOK || rec.Body.Len() != 0 {
+		t.Errorf("wrote %d %q to a client that went away", rec.Code, rec.Body)
+	}
+
+	if got := budgetCount(t, "openai"); got != before {
+		t.Errorf("budget count = %d after cancelled request, want %d", got, before)
+	}
+}
+
+// The same request completed normally is billed once, so the test above is
+// not passing merely because nothing is ever recorded.
+func TestProxyRecordsBudget(t *testing.T) {
+	addTestKey(t, "RECORD_OPENAI_KEY", "sk-record-0123456789abcdef", "openai")
+	fakeUpstream(t, "openai", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"ok"}`))
+	})
+	before := budgetCount(t, "openai")
+
+	// Requests from a real server always carry a cancelable context
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+		strings.NewReader(`{"model":"gpt-4o-mini","messages":[]}`)).WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	newProxyRouter().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	if got := budgetCount(t, "openai"); got != before+1 {
+		t.Errorf("budget count = %d, want %d", got, before+1)
+	}
+}
+
+// A model no registry prefix claims is routed by family name only when that
+// is unambiguous; otherwise the client is told which providers to pick from.
+func TestProxyUnresolvedModel(t *testing.T) {
+	t.Setenv("AKM_DEFAULT_PROVIDER", "")
+	tests := []struct {
+		name  string
+		model string
+		want  string
+	}{
+		{"ambiguous", "my-claude-gpt-merge", "candidates: anthropic, openai"},
+		{"unknown", "mystery-model", strings.Join(knownProviders(), ", ")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+				strings.NewReader(`{"model":"`+tt.model+`","messages":[]}`))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			newProxyRouter().ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400; body %s", rec.Code, rec.Body)
+			}
+			var body struct {
+				Error struct {
+					Message string `json:"message"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(body.Error.Message, tt.want) {
+				t.Errorf("message %q does not list %q", body.Error.Message, tt.want)
+			}
+		})
+	}
+}
+
+// Messages API calls, count_tokens included, go to Anthropic with its own
+// auth headers instead of the client's Authorization.
+func TestProxyAnthropicMessages(t *testing.T) {
+	const apiKey = "sk-ant-REDACTED"
+	addTestKey(t, "MESSAGES_ANTHROPIC_KEY", apiKey, "anthropic")
+	type seen struct {
+		path, apiKey, version, auth string
+	}
+	got := make(chan seen, 1)
+	fakeUpstream(t, "anthropic", func(w http.ResponseWriter, r *http.Request) {
+		got <- seen{r.URL.Path, r.Header.Get("x-api-key"), r.Header.Get("anthropic-version"), r.Header.Get("Authorization")}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"ok"}`))
+	})
+
+	for _, path := range []string{"/v1/messages", "/v1/messages/count_tokens"} {
+		t.Run(path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, path,
+				strings.NewReader(`{"model":"claude-sonnet-4-5","messages":[]}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer akm-client-token")
+			rec := httptest.NewRecorder()
+			newProxyRouter().ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+			}
+			s := <-got
+			if s.path != path {
+				t.Errorf("upstream path = %q, want %q", s.path, path)
+			}
+			if s.apiKey != apiKey {
+				t.Errorf("x-api-key = %q, want the stored key", s.apiKey)
+			}
+			if s.version == "" {
+				t.Error("anthropic-version not set")
+			}
+			if s.auth != "" {
+				t.Errorf("Authorization = %q forwarded to anthropic", s.auth)
+			}
+		})
+	}
+}
+
+// A non-streaming request whose upstream outlives AKM_PROXY_TIMEOUT gets a
+// 504 instead of hanging.
+func TestProxyTimeout(t *testing.T) {
+	t.Setenv("AKM_PROXY_TIMEOUT", "50ms")
+	addTestKey(t, "TIMEOUT_DEEPSEEK_KEY", "sk-timeout-0123456789abcdef", "deepseek")
+	fakeUpstream(t, "deepseek", func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+		strings.NewReader(`{"model":"deepseek-chat","messages":[]}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	start := time.Now()
+	newProxyRouter().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Fatalf("status = %d, want 504; body %s", rec.Code, rec.Body)
+	}
+	if !strings.Contains(rec.Body.String(), "timeout_error") {
+		t.Errorf("body %s is not a timeout_error", rec.Body)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("request took %s, timeout not applied", elapsed)
+	}
+}
@@ -0,0 +1,214 @@
+package http
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// clientIDKey is the gin context key under which apiKeyMiddleware records
+// the authenticated caller for rateLimitMiddleware.
+const clientIDKey = "akm_client_id"
+
+// authFailedKey marks a request apiKeyMiddleware rejected for bad or missing
+// credentials. authFailureLimitMiddleware charges only those, not 401s that
+// come back from upstream providers or from a wrong key passphrase.
+const authFailedKey = "akm_auth_failed"
+
+// rateLimitSweepSize is the bucket count above which idle buckets are evicted.
+const rateLimitSweepSize = 1024
+
+// rateLimitConfig holds the per-client token bucket settings. A zero rate
+// disables limiting.
+type rateLimitConfig struct {
+	rps   float64
+	burst int
+}
+
+// loadRateLimitConfig reads AKM_RATE_LIMIT_RPS (requests per second, 0 or
+// unset disables) and AKM_RATE_LIMIT_BURST (defaults to the rate rounded up).
+func loadRateLimitConfig() (rateLimitConfig, error) {
+	var cfg rateLimitConfig
+	if raw := strings.TrimSpace(os.Getenv("AKM_RATE_LIMIT_RPS")); raw != "" {
+		rps, err := strconv.ParseFloat(raw, 64)
+		if err != nil || rps < 0 || math.IsInf(rps, 0) || math.IsNaN(rps) {
+			return cfg, fmt.Errorf("invalid AKM_RATE_LIMIT_RPS %q: must be a non-negative number", raw)
+		}
+		cfg.rps = rps
+	}
+	if cfg.rps == 0 {
+		return cfg, nil
+	}
+	cfg.burst = int(math.Ceil(cfg.rps))
+	if raw := strings.TrimSpace(os.Getenv("AKM_RATE_LIMIT_BURST")); raw != "" {
+		burst, err := strconv.Atoi(raw)
+		if err != nil || burst < 1 {
+			return cfg, fmt.Errorf("invalid AKM_RATE_LIMIT_BURST %q: must be a positive integer", raw)
+		}
+		cfg.burst = burst
+	}
+	return cfg, nil
+}
+
+// tokenBucket refills at cfg.rps up to cfg.burst tokens.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter keeps one token bucket per client.
+type rateLimiter struct {
+	mu      sync.Mutex
+	cfg     rateLimitConfig
+	buckets map[string]*tokenBucket
+}
+
+func newRateLimiter(cfg rateLimitConfig) *rateLimiter {
+	return &rateLimiter{cfg: cfg, buckets: make(map[string]*tokenBucket)}
+}
+
+// allow takes a token from the client's bucket. When the bucket is empty it
+// reports how long until the next token is available.
+func (l *rateLimiter) allow(client string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.bucket(client, now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, l.wait(b)
+}
+
+// peek is allow without taking a token.
+func (l *rateLimiter) peek(client string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.bucket(client, now)
+	if b.tokens >= 1 {
+		return true, 0
+	}
+	return false, l.wait(b)
+}
+
+// bucket returns the client's bucket refilled up to now. Callers hold l.mu.
+func (l *rateLimiter) bucket(client string, now time.Time) *tokenBucket {
+	b, ok := l.buckets[client]
+	if !ok {
+		if len(l.buckets) >= rateLimitSweepSize {
+			l.sweep(now)
+		}
+		b = &tokenBucket{tokens: float64(l.cfg.burst), last: now}
+		l.buckets[client] = b
+		return b
+	}
+	b.tokens = math.Min(float64(l.cfg.burst), b.tokens+now.Sub(b.last).Seconds()*l.cfg.rps)
+	b.last = now
+	return b
+}
+
+// wait is how long until b holds a whole token.
+func (l *rateLimiter) wait(b *tokenBucket) time.Duration {
+	return time.Duration((1 - b.tokens) / l.cfg.rps * float64(time.Second))
+}
+
+// sweep drops buckets that have refilled completely; they behave the same as
+// a fresh bucket. Callers hold l.mu.
+func (l *rateLimiter) sweep(now time.Time) {
+	full := float64(l.cfg.burst) / l.cfg.rps
+	for client, b := range l.buckets {
+		if now.Sub(b.last).Seconds() >= full {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// rateLimitMiddleware limits each client to cfg.rps requests per second,
+// keyed by the token apiKeyMiddleware accepted or, without auth, by client
+// IP. Exceeding the limit returns 429 with Retry-After. This protects the
+// server from its own clients; provider budgets track upstream usage
+// separately.
+func rateLimitMiddleware(cfg rateLimitConfig) gin.HandlerFunc {
+	if cfg.rps == 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	limiter := newRateLimiter(cfg)
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodOptions || c.Request.URL.Path == "/api/health" {
+			c.Next()
+			return
+		}
+		client := "ip:" + c.ClientIP()
+		if id := c.GetString(clientIDKey); id != "" {
+			client = id
+		}
+		ok, wait := limiter.allow(client, time.Now())
+		if ok {
+			c.Next()
+			return
+		}
+		rejectRateLimited(c, wait)
+	}
+}
+
+// authFailureLimitMiddleware runs before apiKeyMiddleware and charges every
+// request it rejects to the client IP, at the same rate as rateLimitMiddleware. Once an
+// IP's bucket is empty its requests get 429 before auth is attempted, so
+// tokens cannot be guessed faster than the configured rate. Successful
+// requests cost nothing here, so clients sharing an address (everything on
+// a loopback bind) are still limited by their own tokens only.
+func authFailureLimitMiddleware(cfg rateLimitConfig) gin.HandlerFunc {
+	if cfg.rps == 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	limiter := newRateLimiter(cfg)
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodOptions || c.Request.URL.Path == "/api/health" {
+			c.Next()
+			return
+		}
+		client := "ip:" + c.ClientIP()
+		if ok, wait := limiter.peek(client, time.Now()); !ok {
+			rejectRateLimited(c, wait)
+			return
+		}
+		c.Next()
+		if c.GetBool(authFailedKey) {
+			limiter.allow(client, time.Now())
+		}
+	}
+}
+
+// rejectRateLimited aborts with 429 and a Retry-After of wait, at least one
+// second.
+func rejectRateLimited(c *gin.Context, wait time.Duration) {
+	retryAfter := int(math.Ceil(wait.Seconds()))
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	message := fmt.Sprintf("rate limit exceeded; retry in %ds", retryAfter)
+	if strings.HasPrefix(c.Request.URL.Path, "/v1/") {
+		writeProxyError(c.Writer, http.StatusTooManyRequests, message, "rate_limit_error")
+		c.Abort()
+		return
+	}
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": message})
+}
+
+// clientID identifies an authenticated caller without keeping its token.
+func clientID(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "token:" + hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,67 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Failed authentications are limited per client IP even though they never
+// reach the token-keyed limiter; successful ones do not use that budget.
+func TestAuthFailuresAreRateLimited(t *testing.T) {
+	t.Setenv("AKM_API_KEY", "akm-test-secret")
+	cfg := rateLimitConfig{rps: 0.001, burst: 2}
+
+	r := gin.New()
+	api := r.Group("/api")
+	api.Use(authFailureLimitMiddleware(cfg), apiKeyMiddleware(), rateLimitMiddleware(cfg))
+	api.GET("/keys", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	do := func(key string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/keys", nil)
+		req.Header.Set("Authorization", "Bearer "+key)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for _, step := range []struct {
+		key  string
+		want int
+	}{
+		{"akm-test-secret", http.StatusOK},
+		{"akm-test-secret", http.StatusOK},
+		{"guess-1", http.StatusUnauthorized},
+		{"guess-2", http.StatusUnauthorized},
+		{"guess-3", http.StatusTooManyRequests},
+	} {
+		if got := do(step.key); got != step.want {
+			t.Fatalf("request with %s: status %d, want %d", step.key, got, step.want)
+		}
+	}
+}
+
+// A 401 relayed from a provider is the provider rejecting its key, not the
+// caller failing auth, so it must not use up the caller's IP budget.
+func TestUpstreamUnauthorizedIsNotCharged(t *testing.T) {
+	t.Setenv("AKM_API_KEY", "akm-test-secret")
+	cfg := rateLimitConfig{rps: 0.001, burst: 1}
+
+	r := gin.New()
+	v1 := r.Group("/v1")
+	v1.Use(authFailureLimitMiddleware(cfg), apiKeyMiddleware())
+	// Stands in for the proxy passing an upstream 401 through
+	v1.POST("/chat/completions", func(c *gin.Context) { c.Status(http.StatusUnauthorized) })
+
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+		req.Header.Set("Authorization", "Bearer akm-test-secret")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("request %d: status %d, want the upstream 401", i+1, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+)
+
+// secretPatterns match credentials that may end up in a panic value or stack:
+// provider keys, akm tokens and Authorization header values.
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`sk-[A-Za-z0-9_\-]{8,}`),
+	regexp.MustCompile(`akm_[0-9a-f]{8}_[A-Za-z0-9_\-]+`),
+	regexp.MustCompile(`AIza[0-9A-Za-z_\-]{20,}`),
+	regexp.MustCompile(`(?i)(bearer|basic)\s+[A-Za-z0-9._~+/=\-]+`),
+}
+
+// scrubSecrets redacts anything that looks like a credential.
+func scrubSecrets(s string) string {
+	for _, re := range secretPatterns {
+		s = re.ReplaceAllString(s, "[REDACTED]")
+	}
+	return s
+}
+
+// logError writes an error-level line to gin's error writer (stderr by
+// default), scrubbed of secrets.
+func logError(format string, args ...interface{}) {
+	fmt.Fprintf(gin.DefaultErrorWriter, "[ERROR] %s\n", scrubSecrets(fmt.Sprintf(format, args...)))
+}
+
+// logInfo writes an info-level line to gin's default writer, scrubbed of
+// secrets.
+func logInfo(format string, args ...interface{}) {
+	fmt.Fprintf(gin.DefaultWriter, "[INFO] %s\n", scrubSecrets(fmt.Sprintf(format, args...)))
+}
+
+// recoveryMiddleware replaces gin's default recovery: the panic and stack are
+// logged server-side and the client only gets a generic OpenAI-style 500.
+func recoveryMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			// ReverseProxy aborts a half-written response this way; let
+			// net/http close the connection as intended
+			if err, ok := r.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(r)
+			}
+
+			logError("panic in %s %s: %v\n%s", c.Request.Method, c.Request.URL.Path, r, debug.Stack())
+			if c.Writer.Written() {
+				c.Abort() // headers already sent, nothing clean left to say
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error": map[string]string{
+					"message": "internal server error",
+					"type":    "server_error",
+				},
+			})
+		}()
+		c.Next()
+	}
+}
+
+// guard runs a best-effort side effect (budget, audit) so a panic in it is
+// logged instead of failing the request it belongs to.
+func guard(name string, fn func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			logError("panic in %s: %v\n%s", name, r, debug.Stack())
+		}
+	}()
+	fn()
+}
@@ -0,0 +1,50 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// A panicking handler yields a generic 500; the stack and any secret in the
+// panic value only reach the (scrubbed) server log.
+func TestRecoveryMiddleware(t *testing.T) {
+	const secret = "sk-panic-0123456789abcdef"
+	var logs bytes.Buffer
+	orig := gin.DefaultErrorWriter
+	gin.DefaultErrorWriter = &logs
+	t.Cleanup(func() { gin.DefaultErrorWriter = orig })
+
+	r := gin.New()
+	r.Use(recoveryMiddleware())
+	r.GET("/panic", func(c *gin.Context) {
+		panic("upstream rejected key " + secret)
+	})
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, leak := range []string{secret, "goroutine", ".go:", "upstream rejected"} {
+		if strings.Contains(body, leak) {
+			t.Errorf("response body %s contains %q", body, leak)
+		}
+	}
+	if !strings.Contains(body, "server_error") {
+		t.Errorf("response body %s is not a server_error", body)
+	}
+
+	logged := logs.String()
+	if strings.Contains(logged, secret) {
+		t.Errorf("server log contains the secret: %s", logged)
+	}
+	if !strings.Contains(logged, "[REDACTED]") || !strings.Contains(logged, "goroutine") {
+		t.Errorf("server log lacks the scrubbed panic and stack: %s", logged)
+	}
+}
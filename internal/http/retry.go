@@ -0,0 +1,137 @@
+package http
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultRetryMaxWait caps how long a rate-limited request may be held.
+const defaultRetryMaxWait = 10 * time.Second
+
+// retry429Enabled reports whether AKM_PROXY_RETRY_429 opts in to retrying
+// rate-limited (429) non-streaming requests.
+func retry429Enabled() bool {
+	return parseBoolEnv("AKM_PROXY_RETRY_429", false)
+}
+
+// retryMaxWait reads AKM_PROXY_RETRY_MAX_WAIT: the longest Retry-After the
+// proxy will sit out before retrying. Longer waits are passed to the client.
+func retryMaxWait() time.Duration {
+	d, err := time.ParseDuration(strings.TrimSpace(os.Getenv("AKM_PROXY_RETRY_MAX_WAIT")))
+	if err != nil || d <= 0 {
+		return defaultRetryMaxWait
+	}
+	return d
+}
+
+// maxKeyRetries reads AKM_PROXY_MAX_KEY_RETRIES: how many other keys of the
+// same provider a request may fail over to after an upstream 401 or 429.
+// 0 (the default) disables key failover.
+func maxKeyRetries() int {
+	n, err := strconv.Atoi(strings.TrimSpace(os.Getenv("AKM_PROXY_MAX_KEY_RETRIES")))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// parseRetryAfter reads a Retry-After header in delay-seconds or HTTP-date form.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// retryTransport replays a request that the upstream rejected. On a status in
+// keyStatuses it moves to the next key from failover, up to maxKeys times;
+// after that a 429 may be retried once more with the same key when wait429 is
+// set and the upstream's Retry-After is within maxWait. Anything else is
+// returned to the client unchanged.
+type retryTransport struct {
+	base    http.RoundTripper
+	body    []byte // the request body, replayed on retry
+	maxWait time.Duration
+	// keyStatuses are the upstream statuses that trigger key failover
+	keyStatuses []int
+	// maxKeys bounds how many other keys are tried
+	maxKeys int
+	// wait429 allows one Retry-After wait once failover is exhausted
+	wait429 bool
+	// failover returns the next untried key's auth header value, if any
+	failover func() (name, authValue string, ok bool)
+	// authHeader is the header failover's value replaces
+	authHeader string
+	// onRetry reports what was done ("failover:<key>" or "wait:<d>")
+	onRetry func(action string)
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	current := req
+	keysTried, waited := 0, false
+	for err == nil {
+		action := ""
+		retry := current.Clone(current.Context())
+		if keysTried < t.maxKeys && t.failsOver(resp.StatusCode) {
+			if name, authValue, ok := t.failover(); ok {
+				retry.Header.Set(t.authHeader, authValue)
+				action = "failover:" + name
+				keysTried++
+			}
+		}
+		if action == "" {
+			if !t.wait429 || waited || resp.StatusCode != http.StatusTooManyRequests {
+				return resp, nil
+			}
+			wait, ok := parseRetryAfter(resp.Header.Get("Retry-After"))
+			if !ok || wait > t.maxWait {
+				return resp, nil
+			}
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-req.Context().Done():
+				timer.Stop()
+				return resp, nil
+			}
+			action = "wait:" + wait.String()
+			waited = true
+		}
+
+		// Done with the rejected response; drain so the connection is reused
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+
+		retry.Body = io.NopCloser(strings.NewReader(string(t.body)))
+		retry.ContentLength = int64(len(t.body))
+		t.onRetry(action)
+		current = retry
+		resp, err = t.base.RoundTrip(retry)
+	}
+	return resp, err
+}
+
+// failsOver reports whether status should move the request to another key.
+func (t *retryTransport) failsOver(status int) bool {
+	for _, s := range t.keyStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/baobao/akm-go/internal/core"
+	"gopkg.in/yaml.v3"
+)
+
+// customRoute is one providers.yaml entry: a ProviderRoute plus the model
+// prefixes that should resolve to it.
+type customRoute struct {
+	ProviderRoute `yaml:",inline"`
+	ModelPrefixes []string `yaml:"model_prefixes"`
+}
+
+// routeModelPrefixes holds the model prefixes providers.yaml added, reported
+// by /api/providers alongside the registry's.
+var routeModelPrefixes map[string][]string
+
+// providerRoutesFile returns the providers.yaml path in the active profile's
+// data directory.
+func providerRoutesFile() (string, error) {
+	dataDir, err := core.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, "providers.yaml"), nil
+}
+
+// loadProviderRoutes reads extra or overriding proxy routes keyed by provider,
+// e.g.
+//
+//	vllm:
+//	  base_url: http://gpu-box:8000
+//	  model_prefixes: [qwen-, llama-]
+//
+// A missing file yields no routes. auth_header defaults to Authorization with
+// a "Bearer " prefix; base_url must be an absolute http(s) URL.
+func loadProviderRoutes(path string) (map[string]customRoute, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var parsed map[string]customRoute
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&parsed); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("invalid %s: %w", path, err)
+	}
+
+	routes := make(map[string]customRoute, len(parsed))
+	for provider, route := range parsed {
+		provider = strings.ToLower(strings.TrimSpace(provider))
+		if provider == "" {
+			return nil, fmt.Errorf("invalid %s: empty provider name", path)
+		}
+		u, err := url.Parse(route.BaseURL)
+		if err != nil || !u.IsAbs() || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, fmt.Errorf("invalid %s: provider %q: base_url %q must be an absolute http(s) URL", path, provider, route.BaseURL)
+		}
+		route.BaseURL = strings.TrimRight(route.BaseURL, "/")
+		if route.AuthHeader == "" {
+			route.AuthHeader = "Authorization"
+			if route.AuthPrefix == "" {
+				route.AuthPrefix = "Bearer "
+			}
+		}
+		routes[provider] = route
+	}
+	return routes, nil
+}
+
+// applyProviderRoutes merges routes over the built-in providerRoutes, entries
+// replacing built-ins of the same name, and registers their model prefixes.
+// It returns how many routes were applied.
+func applyProviderRoutes(routes map[string]customRoute) int {
+	if len(routes) == 0 {
+		return 0
+	}
+	prefixes := make(map[string][]string)
+	for provider, route := range routes {
+		providerRoutes[provider] = route.ProviderRoute
+		if len(route.ModelPrefixes) > 0 {
+			prefixes[provider] = route.ModelPrefixes
+		}
+	}
+	routeModelPrefixes = prefixes
+	core.SetCustomModelPrefixes(prefixes)
+	return len(routes)
+}
@@ -2,14 +2,18 @@
 package http
 
 import (
+	"crypto/tls"
 	"embed"
 	"fmt"
 	"io/fs"
+	"net"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/baobao/akm-go/internal/core"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
@@ -25,10 +29,70 @@ func mustSub(fsys fs.FS, dir string) fs.FS {
 	return sub
 }
 
+// DefaultBindAddress keeps the server on loopback unless exposure is asked for.
+const DefaultBindAddress = "127.0.0.1"
+
+// ServerOptions configures StartServer.
+type ServerOptions struct {
+	Bind      string // listen address, DefaultBindAddress when empty
+	Port      int
+	EnableWeb bool
+	TLSCert   string // serve HTTPS when both TLSCert and TLSKey are set
+	TLSKey    string
+	Version   string // binary version, compared against the embedded web UI stamp
+	// StrictProvider makes the proxy reject models missing from the platform
+	// registry instead of forwarding them (also AKM_STRICT_PROVIDER)
+	StrictProvider bool
+}
+
 // StartServer starts the HTTP API server.
-func StartServer(port int, enableWeb bool) error {
+func StartServer(opts ServerOptions) error {
+	useTLS := opts.TLSCert != "" || opts.TLSKey != ""
+	if useTLS {
+		if opts.TLSCert == "" || opts.TLSKey == "" {
+			return fmt.Errorf("both TLS certificate and key are required")
+		}
+		// Fail before binding rather than after the listener is up
+		if _, err := tls.LoadX509KeyPair(opts.TLSCert, opts.TLSKey); err != nil {
+			return fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+	}
+
+	strictProvider = opts.StrictProvider || parseBoolEnv("AKM_STRICT_PROVIDER", false)
+	routesFile, err := providerRoutesFile()
+	if err != nil {
+		return err
+	}
+	custom, err := loadProviderRoutes(routesFile)
+	if err != nil {
+		return err
+	}
+	if n := applyProviderRoutes(custom); n > 0 {
+		fmt.Printf("🔧 Loaded %d provider route(s) from %s\n", n, routesFile)
+	}
+	params, err := loadDefaultParams()
+	if err != nil {
+		return err
+	}
+	defaultParams = params
+	rateLimitCfg, err := loadRateLimitConfig()
+	if err != nil {
+		return err
+	}
+	// One limiter for both groups so /api and /v1 share a client's budget;
+	// failed logins are charged to the client IP ahead of auth
+	rateLimit := rateLimitMiddleware(rateLimitCfg)
+	authLimit := authFailureLimitMiddleware(rateLimitCfg)
+
+	webVersion := ""
+	if subFS, err := fs.Sub(WebAssets, "web/dist"); err == nil {
+		webVersion = readWebVersion(subFS)
+	}
+	versionMismatch := webVersionMismatch(opts.Version, webVersion)
+
 	gin.SetMode(gin.ReleaseMode)
-	r := gin.Default()
+	r := gin.New()
+	r.Use(gin.Logger(), recoveryMiddleware())
 
 	// CORS configuration
 	allowOrigins := loadCorsOrigins()
@@ -50,7 +114,7 @@ func StartServer(port int, enableWeb bool) error {
 
 	// API routes
 	api := r.Group("/api")
-	api.Use(apiKeyMiddleware())
+	api.Use(authLimit, apiKeyMiddleware(), rateLimit)
 	{
 		// Keys
 		api.GET("/keys", listKeysHandler)
@@ -58,8 +122,25 @@ func StartServer(port int, enableWeb bool) error {
 		api.GET("/keys/:name", getKeyHandler)
 		api.DELETE("/keys/:name", deleteKeyHandler)
 
+		// Live key change events (SSE)
+		api.GET("/events", eventsHandler)
+
+		// One-time share links (redeeming needs only the link's token)
+		api.POST("/share", createShareHandler)
+		api.GET("/reveal/:token", revealShareHandler)
+
 		// Export
 		api.POST("/export/env", exportEnvHandler)
+		api.GET("/catalog", catalogHandler)
+
+		// Providers
+		api.GET("/providers", providersHandler)
+
+		// Verification
+		api.POST("/verify", verifyHandler)
+
+		// Version
+		api.GET("/version", versionHandler(opts.Version, webVersion))
 
 		// Health
 		api.GET("/health", healthHandler)
@@ -67,17 +148,17 @@ func StartServer(port int, enableWeb bool) error {
 
 	// Proxy routes (OpenAI-compatible)
 	v1 := r.Group("/v1")
-	v1.Use(apiKeyMiddleware())
-	{
-		v1.Any("/chat/completions", proxyHandler)
-		v1.Any("/completions", proxyHandler)
-		v1.Any("/embeddings", proxyHandler)
-		v1.Any("/models", proxyHandler)
-		v1.Any("/models/*path", proxyHandler)
+	v1.Use(authLimit, apiKeyMiddleware(), rateLimit)
+	registerProxyRoutes(v1)
+
+	// Prometheus metrics (opt-in), behind the same auth as the API
+	metricsEnabled := parseBoolEnv("AKM_ENABLE_METRICS", false)
+	if metricsEnabled {
+		r.GET("/metrics", authLimit, apiKeyMiddleware(), metricsHandler)
 	}
 
 	// Web UI (if enabled)
-	if enableWeb {
+	if opts.EnableWeb {
 		// Try to serve embedded web assets
 		subFS, err := fs.Sub(WebAssets, "web/dist")
 		if err == nil {
@@ -89,6 +170,14 @@ func StartServer(port int, enableWeb bool) error {
 				fmt.Printf("Warning: Failed to read index.html: %v\n", err)
 			}
 
+			// Warn about UI/API drift; AKM_WEB_VERSION_BANNER also shows it in the page
+			if versionMismatch != "" {
+				fmt.Printf("Warning: %s\n", versionMismatch)
+				if indexHTML != nil && parseBoolEnv("AKM_WEB_VERSION_BANNER", false) {
+					indexHTML = injectVersionBanner(indexHTML, versionMismatch)
+				}
+			}
+
 			// Serve assets directory
 			assetsFS, _ := fs.Sub(subFS, "assets")
 			r.StaticFS("/assets", http.FS(assetsFS))
@@ -126,15 +215,64 @@ func StartServer(port int, enableWeb bool) error {
 		}
 	}
 
-	addr := fmt.Sprintf(":%d", port)
-	fmt.Printf("🌐 HTTP API: http://localhost%s/api\n", addr)
-	fmt.Printf("🔀 Proxy:    http://localhost%s/v1/chat/completions\n", addr)
-	if enableWeb {
-		fmt.Printf("🖥️  Web UI:   http://localhost%s/\n", addr)
+	bind := opts.Bind
+	if bind == "" {
+		bind = DefaultBindAddress
+	}
+	// Listen first so the banner shows the address actually bound (port 0
+	// resolves here) and a busy port fails before anything is printed
+	ln, err := net.Listen("tcp", net.JoinHostPort(bind, strconv.Itoa(opts.Port)))
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", net.JoinHostPort(bind, strconv.Itoa(opts.Port)), err)
+	}
+	defer ln.Close()
+
+	addr := ln.Addr().String()
+	scheme := "http"
+	if useTLS {
+		scheme = "https"
+	}
+	fmt.Printf("🔌 Listening: %s\n", addr)
+	fmt.Printf("🌐 HTTP API: %s://%s/api\n", scheme, addr)
+	fmt.Printf("🔀 Proxy:    %s://%s/v1/chat/completions\n", scheme, addr)
+	if opts.EnableWeb {
+		fmt.Printf("🖥️  Web UI:   %s://%s/\n", scheme, addr)
+	}
+	if metricsEnabled {
+		fmt.Printf("📈 Metrics:  %s://%s/metrics\n", scheme, addr)
 	}
 	fmt.Println()
 
-	return r.Run(addr)
+	srv := &http.Server{Handler: r}
+	if useTLS {
+		return srv.ServeTLS(ln, opts.TLSCert, opts.TLSKey)
+	}
+	return srv.Serve(ln)
+}
+
+// IsLoopbackBind reports whether bind only accepts local connections.
+func IsLoopbackBind(bind string) bool {
+	if bind == "" || bind == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(bind)
+	return ip != nil && ip.IsLoopback()
+}
+
+// registerProxyRoutes mounts the provider proxy endpoints on the /v1 group.
+func registerProxyRoutes(v1 *gin.RouterGroup) {
+	v1.Any("/chat/completions", proxyHandler)
+	v1.Any("/completions", proxyHandler)
+	v1.Any("/embeddings", proxyHandler)
+	v1.Any("/models", proxyHandler)
+	v1.Any("/models/*path", proxyHandler)
+
+	// Anthropic Messages API (messages, count_tokens, batches)
+	v1.Any("/messages", proxyHandler)
+	v1.Any("/messages/*path", proxyHandler)
+
+	// Provider-qualified paths, e.g. /v1/anthropic/messages
+	v1.Any("/:provider/*path", proxyHandler)
 }
 
 func loadCorsOrigins() []string {
@@ -160,19 +298,27 @@ func loadCorsOrigins() []string {
 	return items
 }
 
+// apiKeyMiddleware authenticates requests with either the static AKM_API_KEY
+// or a scoped token from `akm server token create`. Auth is required once
+// either is configured; tokens are checked against the store on every request
+// so revocation applies immediately. Health checks and share-link redemption
+// (whose path token is its own credential) are exempt.
 func apiKeyMiddleware() gin.HandlerFunc {
-	require := parseBoolEnv("AKM_REQUIRE_API_KEY", false) || os.Getenv("AKM_API_KEY") != ""
+	envRequire := parseBoolEnv("AKM_REQUIRE_API_KEY", false) || os.Getenv("AKM_API_KEY") != ""
 	return func(c *gin.Context) {
-		if !require || c.Request.Method == http.MethodOptions {
+		if c.Request.Method == http.MethodOptions || c.Request.URL.Path == "/api/health" ||
+			strings.HasPrefix(c.Request.URL.Path, "/api/reveal/") {
 			c.Next()
 			return
 		}
-		if c.Request.URL.Path == "/api/health" {
+		storage, _ := core.GetStorage()
+		hasTokens := storage != nil && storage.TokenAuthEnabled()
+		if !envRequire && !hasTokens {
 			c.Next()
 			return
 		}
 		apiKey := os.Getenv("AKM_API_KEY")
-		if apiKey == "" {
+		if apiKey == "" && !hasTokens {
 			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "AKM_API_KEY not configured"})
 			return
 		}
@@ -183,14 +329,55 @@ func apiKeyMiddleware() gin.HandlerFunc {
 		if token == "" {
 			token = c.GetHeader("Api-Key")
 		}
-		if token != apiKey {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		if token == "" {
+			rejectUnauthorized(c)
+			return
+		}
+		if apiKey != "" && token == apiKey {
+			c.Set(clientIDKey, clientID(token))
+			c.Next()
+			return
+		}
+		if !hasTokens {
+			rejectUnauthorized(c)
+			return
+		}
+		t, err := storage.AuthenticateToken(token)
+		if err != nil {
+			rejectUnauthorized(c)
+			return
+		}
+		if scope := requiredScope(c.Request); !t.HasScope(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("token lacks '%s' scope", scope)})
 			return
 		}
+		c.Set(clientIDKey, clientID(token))
 		c.Next()
 	}
 }
 
+// rejectUnauthorized aborts with 401 and marks the request as a failed
+// authentication for authFailureLimitMiddleware.
+func rejectUnauthorized(c *gin.Context) {
+	c.Set(authFailedKey, true)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+}
+
+// requiredScope maps a request to the token scope it needs.
+func requiredScope(req *http.Request) string {
+	if strings.HasPrefix(req.URL.Path, "/v1/") {
+		return core.ScopeProxy
+	}
+	// A plaintext value is as sensitive as POST /api/export/env
+	if req.URL.Query().Get("show_value") == "true" {
+		return core.ScopeWrite
+	}
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		return core.ScopeRead
+	}
+	return core.ScopeWrite
+}
+
 func extractBearerToken(header string) string {
 	if header == "" {
 		return ""
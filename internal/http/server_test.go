@@ -0,0 +1,67 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/baobao/akm-go/internal/core"
+	"github.com/gin-gonic/gin"
+)
+
+// createTestToken issues a server token with scopes, removing the token store
+// after the test so later tests run without token auth.
+func createTestToken(t *testing.T, scopes ...string) string {
+	t.Helper()
+	storage := testStorage(t)
+	raw, token, err := storage.CreateToken("test-"+t.Name(), scopes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataDir, err := core.DataDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		storage.RevokeToken(token.ID)
+		os.Remove(filepath.Join(dataDir, "tokens.json"))
+	})
+	return raw
+}
+
+// A read token may list key metadata but not reveal values.
+func TestReadTokenCannotRevealValue(t *testing.T) {
+	t.Setenv("AKM_API_KEY", "")
+	addTestKey(t, "SCOPE_OPENAI_KEY", "sk-scope-0123456789abcdef", "openai")
+	readToken := createTestToken(t, "read")
+	writeToken := createTestToken(t, "write")
+
+	r := gin.New()
+	api := r.Group("/api")
+	api.Use(apiKeyMiddleware())
+	api.GET("/keys/:name", getKeyHandler)
+
+	tests := []struct {
+		name  string
+		token string
+		path  string
+		want  int
+	}{
+		{"read metadata", readToken, "/api/keys/SCOPE_OPENAI_KEY", http.StatusOK},
+		{"read show_value", readToken, "/api/keys/SCOPE_OPENAI_KEY?show_value=true", http.StatusForbidden},
+		{"write show_value", writeToken, "/api/keys/SCOPE_OPENAI_KEY?show_value=true", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d; body %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/baobao/akm-go/internal/core"
+	"github.com/gin-gonic/gin"
+)
+
+type shareRequest struct {
+	Name string `json:"name" binding:"required"`
+	TTL  string `json:"ttl"` // duration such as "10m"; default core.DefaultShareTTL
+}
+
+// createShareHandler mints a one-time reveal link for a key.
+func createShareHandler(c *gin.Context) {
+	var req shareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var ttl time.Duration
+	if req.TTL != "" {
+		d, err := time.ParseDuration(req.TTL)
+		if err != nil || d <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ttl must be a positive duration such as 10m"})
+			return
+		}
+		ttl = d
+	}
+
+	storage, err := core.GetStorage()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, link, err := storage.CreateShare(req.Name, ttl)
+	if errors.Is(err, core.ErrRevealForbidden) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"name":       link.KeyName,
+		"token":      token,
+		"url":        scheme + "://" + c.Request.Host + "/api/reveal/" + token,
+		"expires_at": link.ExpiresAt,
+	})
+}
+
+// revealShareHandler returns the shared value exactly once. The token in the
+// path is the credential, so this route is exempt from API authentication.
+func revealShareHandler(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+
+	storage, err := core.GetStorage()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	name, value, err := storage.RedeemShare(c.Param("token"))
+	switch {
+	case errors.Is(err, core.ErrInvalidShare):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, core.ErrRevealForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to decrypt key"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"name":  name,
+		"value": value,
+	})
+}
@@ -0,0 +1,76 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/baobao/akm-go/internal/core"
+	"github.com/gin-gonic/gin"
+)
+
+type verifyRequest struct {
+	Provider string `json:"provider"`
+	Name     string `json:"name"`
+	Tag      string `json:"tag"`
+	Stream   bool   `json:"stream"`
+}
+
+// verifyHandler runs key verification. With "stream": true (or an
+// Accept: application/x-ndjson header) results are written as NDJSON as each
+// key completes; otherwise a single JSON document is returned at the end.
+func verifyHandler(c *gin.Context) {
+	var req verifyRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if strings.Contains(c.GetHeader("Accept"), "application/x-ndjson") {
+		req.Stream = true
+	}
+
+	storage, err := core.GetStorage()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter := core.VerifyFilter{Provider: req.Provider, Tag: req.Tag}
+	if req.Name != "" {
+		key := storage.GetKey(req.Name)
+		if key == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "key not found"})
+			return
+		}
+		filter.Name = key.Name
+	}
+
+	ctx := c.Request.Context()
+
+	if !req.Stream {
+		results := core.VerifyStream(ctx, storage, filter, nil)
+		if results == nil {
+			results = []*core.VerifyResult{}
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"results": results,
+			"count":   len(results),
+		})
+		return
+	}
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Cache-Control", "no-cache")
+	c.Status(http.StatusOK)
+	enc := json.NewEncoder(c.Writer)
+	core.VerifyStream(ctx, storage, filter, func(r *core.VerifyResult) {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := enc.Encode(r); err == nil {
+			c.Writer.Flush()
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// webVersionFile is the build stamp written into web/dist by `make web`.
+const webVersionFile = "version.txt"
+
+// readWebVersion returns the version stamp of the embedded web UI, or "" if
+// the assets carry no stamp (built before stamping or copied in by hand).
+func readWebVersion(webFS fs.FS) string {
+	data, err := fs.ReadFile(webFS, webVersionFile)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// webVersionMismatch describes why the embedded UI may not match the binary,
+// or returns "" when the versions agree.
+func webVersionMismatch(binaryVersion, webVersion string) string {
+	if webVersion == "" {
+		return fmt.Sprintf("embedded web UI has no version stamp (binary %s); it may be stale", binaryVersion)
+	}
+	if webVersion != binaryVersion {
+		return fmt.Sprintf("embedded web UI version %s does not match binary version %s", webVersion, binaryVersion)
+	}
+	return ""
+}
+
+// versionHandler reports the binary and embedded web UI versions.
+func versionHandler(binaryVersion, webVersion string) gin.HandlerFunc {
+	mismatch := webVersionMismatch(binaryVersion, webVersion)
+	return func(c *gin.Context) {
+		resp := gin.H{
+			"version":     binaryVersion,
+			"web_version": webVersion,
+			"web_stale":   mismatch != "",
+		}
+		if mismatch != "" {
+			resp["warning"] = mismatch
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// injectVersionBanner adds a fixed warning banner to index.html just before
+// </body>. The page is returned unchanged if it has no </body>.
+func injectVersionBanner(indexHTML []byte, message string) []byte {
+	idx := bytes.LastIndex(indexHTML, []byte("</body>"))
+	if idx < 0 {
+		return indexHTML
+	}
+	banner := fmt.Sprintf(`<div style="position:fixed;bottom:0;left:0;right:0;z-index:9999;padding:6px 12px;background:#fff3cd;color:#664d03;font:13px sans-serif;text-align:center">⚠️ %s</div>`, html.EscapeString(message))
+	out := make([]byte, 0, len(indexHTML)+len(banner))
+	out = append(out, indexHTML[:idx]...)
+	out = append(out, banner...)
+	return append(out, indexHTML[idx:]...)
+}
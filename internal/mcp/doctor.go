@@ -0,0 +1,128 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/baobao/akm-go/internal/core"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// DoctorCheck is the outcome of one MCP self-test step.
+type DoctorCheck struct {
+	Name   string
+	OK     bool
+	Detail string
+}
+
+// toolNames returns the sorted names of the tools registered on s.
+func toolNames(s *server.MCPServer) []string {
+	var names []string
+	for name := range s.ListTools() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// storageStatus describes whether key storage is reachable.
+func storageStatus() (string, bool) {
+	storage, err := core.GetStorage()
+	if err != nil {
+		return err.Error(), false
+	}
+	if storage == nil {
+		return "storage not initialized", false
+	}
+	return fmt.Sprintf("%d keys", len(storage.ListKeys(""))), true
+}
+
+// statusLine summarizes registered tools and storage for the startup log.
+func statusLine(s *server.MCPServer) string {
+	storage, ok := storageStatus()
+	state := "ok"
+	if !ok {
+		state = "unavailable"
+	}
+	return fmt.Sprintf("akm-mcp: %d tools (%s), storage %s: %s",
+		len(s.ListTools()), strings.Join(toolNames(s), ", "), state, storage)
+}
+
+// Doctor validates the MCP setup end-to-end without a client: it builds the
+// server, checks storage, and drives initialize, tools/list and an akm_health
+// call through the JSON-RPC handler in-process.
+func Doctor(ctx context.Context) []DoctorCheck {
+	s := newServer()
+	var checks []DoctorCheck
+
+	names := toolNames(s)
+	checks = append(checks, DoctorCheck{
+		Name:   "tools registered",
+		OK:     len(names) > 0,
+		Detail: fmt.Sprintf("%d: %s", len(names), strings.Join(names, ", ")),
+	})
+
+	detail, ok := storageStatus()
+	checks = append(checks, DoctorCheck{Name: "storage", OK: ok, Detail: detail})
+
+	call := func(id int, method string, params any) (map[string]any, error) {
+		raw, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": id, "method": method, "params": params})
+		respBytes, err := json.Marshal(s.HandleMessage(ctx, raw))
+		if err != nil {
+			return nil, err
+		}
+		var resp struct {
+			Result map[string]any `json:"result"`
+			Error  *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(respBytes, &resp); err != nil {
+			return nil, err
+		}
+		if resp.Error != nil {
+			return nil, fmt.Errorf("%s", resp.Error.Message)
+		}
+		return resp.Result, nil
+	}
+
+	_, err := call(1, "initialize", map[string]any{
+		"protocolVersion": "2024-11-05",
+		"clientInfo":      map[string]any{"name": "akm-doctor", "version": "1.0.0"},
+		"capabilities":    map[string]any{},
+	})
+	checks = append(checks, doctorResult("initialize", err, "ok"))
+	if err != nil {
+		return checks
+	}
+
+	result, err := call(2, "tools/list", map[string]any{})
+	listed := 0
+	if tools, ok := result["tools"].([]any); ok {
+		listed = len(tools)
+	}
+	if err == nil && listed != len(names) {
+		err = fmt.Errorf("listed %d tools, %d registered", listed, len(names))
+	}
+	checks = append(checks, doctorResult("tools/list", err, fmt.Sprintf("%d tools visible to clients", listed)))
+
+	result, err = call(3, "tools/call", map[string]any{"name": "akm_health", "arguments": map[string]any{}})
+	if err == nil {
+		if isError, _ := result["isError"].(bool); isError {
+			err = fmt.Errorf("akm_health returned an error result")
+		}
+	}
+	checks = append(checks, doctorResult("tools/call akm_health", err, "ok"))
+
+	return checks
+}
+
+func doctorResult(name string, err error, okDetail string) DoctorCheck {
+	if err != nil {
+		return DoctorCheck{Name: name, Detail: err.Error()}
+	}
+	return DoctorCheck{Name: name, OK: true, Detail: okDetail}
+}
@@ -4,13 +4,24 @@ package mcp
 import (
 	"context"
 	"fmt"
+	"os"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 )
 
-// StartMCPServer starts the MCP server in stdio mode.
+// StartMCPServer starts the MCP server in stdio mode. A one-line status
+// summary goes to stderr; stdout carries only protocol messages.
 func StartMCPServer() error {
+	s := newServer()
+	fmt.Fprintln(os.Stderr, statusLine(s))
+
+	// Start stdio server
+	return server.ServeStdio(s)
+}
+
+// newServer creates the MCP server with all tools registered.
+func newServer() *server.MCPServer {
 	s := server.NewMCPServer(
 		"akm-mcp",
 		"1.0.0",
@@ -19,9 +30,7 @@ func StartMCPServer() error {
 
 	// Register tools
 	registerTools(s)
-
-	// Start stdio server
-	return server.ServeStdio(s)
+	return s
 }
 
 func registerTools(s *server.MCPServer) {
@@ -38,7 +47,7 @@ func registerTools(s *server.MCPServer) {
 		mcp.WithDescription("搜索 API 密钥"),
 		mcp.WithString("query",
 			mcp.Required(),
-			mcp.Description("搜索关键词"),
+			mcp.Description("搜索关键词（匹配名称、提供商、描述、来源项目和标签）"),
 		),
 	), handleSearch)
 
@@ -53,7 +62,7 @@ func registerTools(s *server.MCPServer) {
 
 	// akm_verify - Verify keys
 	s.AddTool(mcp.NewTool("akm_verify",
-		mcp.WithDescription("验证密钥有效性（调用各提供商 API）"),
+		mcp.WithDescription("验证密钥有效性（调用各提供商 API）。status: valid, valid_limited（认证通过但权限受限）, invalid, error, unsupported"),
 		mcp.WithString("name",
 			mcp.Description("指定密钥名称（可选，不指定则验证所有）"),
 		),
@@ -63,11 +72,14 @@ func registerTools(s *server.MCPServer) {
 	s.AddTool(mcp.NewTool("akm_export",
 		mcp.WithDescription("导出密钥为指定格式"),
 		mcp.WithString("format",
-			mcp.Description("输出格式: shell, env, json（默认 env）"),
+			mcp.Description("输出格式: shell, posix (可安全 source), env (dotenv 加载器), json（默认 env）"),
 		),
 		mcp.WithString("provider",
 			mcp.Description("按提供商过滤（可选）"),
 		),
+		mcp.WithString("tag",
+			mcp.Description("按标签过滤（可选，与 provider 同时指定时需都满足）"),
+		),
 	), handleExport)
 
 	// akm_inject - Inject keys to project
@@ -80,12 +92,29 @@ func registerTools(s *server.MCPServer) {
 		mcp.WithString("provider",
 			mcp.Description("按提供商过滤（可选）"),
 		),
+		mcp.WithBoolean("add_gitignore",
+			mcp.Description("目标目录在 git 仓库中且 .env 未被忽略时，将 .env 加入 .gitignore"),
+		),
+		mcp.WithBoolean("force",
+			mcp.Description("即使 .env 未被 git 忽略也写入（不推荐）"),
+		),
 	), handleInject)
 
 	// akm_health - System health check
 	s.AddTool(mcp.NewTool("akm_health",
 		mcp.WithDescription("系统健康检查"),
+		mcp.WithBoolean("deep",
+			mcp.Description("额外探测有启用密钥的 provider API 是否可达及延迟（需要网络，较慢）"),
+		),
 	), handleHealth)
+
+	// akm_budget - Proxy budget usage
+	s.AddTool(mcp.NewTool("akm_budget",
+		mcp.WithDescription("查看代理预算用量：各提供商今日/本月请求数、限额与剩余（限额 0 表示不限）"),
+		mcp.WithString("provider",
+			mcp.Description("按提供商过滤（支持通配符）"),
+		),
+	), handleBudget)
 }
 
 func handleList(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -138,10 +167,11 @@ func handleExport(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallTo
 	args := getArgs(request)
 	format := getStringArg(args, "format")
 	provider := getStringArg(args, "provider")
+	tag := getStringArg(args, "tag")
 	if format == "" {
 		format = "env"
 	}
-	result, err := exportKeys(format, provider)
+	result, err := exportKeys(format, provider, tag)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
@@ -155,7 +185,7 @@ func handleInject(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallTo
 	if path == "" {
 		return mcp.NewToolResultError("path is required"), nil
 	}
-	result, err := injectKeys(path, provider)
+	result, err := injectKeys(path, provider, getBoolArg(args, "add_gitignore"), getBoolArg(args, "force"))
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
@@ -163,7 +193,16 @@ func handleInject(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallTo
 }
 
 func handleHealth(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	result, err := healthCheck()
+	result, err := healthCheck(getBoolArg(getArgs(request), "deep"))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return mcp.NewToolResultText(result), nil
+}
+
+func handleBudget(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := getArgs(request)
+	result, err := budgetStats(getStringArg(args, "provider"))
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
@@ -186,6 +225,15 @@ func getStringArg(args map[string]interface{}, key string) string {
 	return ""
 }
 
+func getBoolArg(args map[string]interface{}, key string) bool {
+	if v, ok := args[key]; ok {
+		if b, ok := v.(bool); ok {
+			return b
+		}
+	}
+	return false
+}
+
 func errResult(format string, args ...interface{}) *mcp.CallToolResult {
 	return mcp.NewToolResultError(fmt.Sprintf(format, args...))
 }
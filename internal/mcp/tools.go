@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/baobao/akm-go/internal/core"
 )
@@ -133,9 +135,11 @@ func verifyKeys(name string) (string, error) {
 	}
 
 	if name != "" {
-		if storage.GetKey(name) == nil {
+		key := storage.GetKey(name)
+		if key == nil {
 			return "", fmt.Errorf("key '%s' not found", name)
 		}
+		name = key.Name
 	}
 
 	results := core.VerifyAll(storage, "", name)
@@ -143,9 +147,15 @@ func verifyKeys(name string) (string, error) {
 		return `{"results":[],"count":0}`, nil
 	}
 
+	summary := make(map[string]int)
+	for _, r := range results {
+		summary[r.Status]++
+	}
+
 	jsonBytes, err := json.MarshalIndent(map[string]interface{}{
 		"results": results,
 		"count":   len(results),
+		"summary": summary,
 	}, "", "  ")
 	if err != nil {
 		return "", err
@@ -155,13 +165,13 @@ func verifyKeys(name string) (string, error) {
 }
 
 // exportKeys exports keys in the specified format.
-func exportKeys(format, provider string) (string, error) {
+func exportKeys(format, provider, tag string) (string, error) {
 	storage, err := core.GetStorage()
 	if err != nil {
 		return "", fmt.Errorf("failed to initialize storage: %w", err)
 	}
 
-	keys, err := storage.GetKeysForExport("mcp-export", provider, nil)
+	keys, err := storage.GetKeysForExport("mcp-export", provider, tag, nil)
 	if err != nil {
 		return "", err
 	}
@@ -174,26 +184,39 @@ func exportKeys(format, provider string) (string, error) {
 		}
 		return string(jsonBytes), nil
 
+	case "posix", "dotenv-export":
+		return strings.TrimSuffix(core.FormatPOSIX(keys), "\n"), nil
+
 	case "shell":
 		var lines []string
-		for name, value := range keys {
-			escaped := strings.ReplaceAll(value, "'", "'\"'\"'")
+		for _, name := range sortedNames(keys) {
+			escaped := strings.ReplaceAll(keys[name], "'", "'\"'\"'")
 			lines = append(lines, fmt.Sprintf("export %s='%s'", name, escaped))
 		}
 		return strings.Join(lines, "\n"), nil
 
 	default: // env
 		var lines []string
-		for name, value := range keys {
-			escaped := core.EscapeDotenvValue(value)
+		for _, name := range sortedNames(keys) {
+			escaped := core.EscapeDotenvValue(keys[name])
 			lines = append(lines, fmt.Sprintf("%s=\"%s\"", name, escaped))
 		}
 		return strings.Join(lines, "\n"), nil
 	}
 }
 
+// sortedNames returns the names in keys in sorted order, for stable output.
+func sortedNames(keys map[string]string) []string {
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // injectKeys writes a .env file to the specified path.
-func injectKeys(path, provider string) (string, error) {
+func injectKeys(path, provider string, addGitignore, force bool) (string, error) {
 	storage, err := core.GetStorage()
 	if err != nil {
 		return "", fmt.Errorf("failed to initialize storage: %w", err)
@@ -214,8 +237,15 @@ func injectKeys(path, provider string) (string, error) {
 		return "", fmt.Errorf("path '%s' is not a directory", path)
 	}
 
+	// Refuse to drop secrets where git would pick them up
+	envPath := filepath.Join(path, ".env")
+	root, exposed := core.UnignoredInRepo(envPath)
+	if exposed && !addGitignore && !force {
+		return "", fmt.Errorf("%s is inside git repository %s and not gitignored; pass add_gitignore=true to ignore it, or force=true to write anyway", envPath, root)
+	}
+
 	project := filepath.Base(path)
-	keys, err := storage.GetKeysForInjection(project, provider, nil)
+	keys, err := storage.GetKeysForInjection(project, provider, "", nil)
 	if err != nil {
 		return "", err
 	}
@@ -225,29 +255,33 @@ func injectKeys(path, provider string) (string, error) {
 	}
 
 	// Generate .env content
-	var lines []string
-	lines = append(lines, "# Generated by akm MCP")
-	lines = append(lines, fmt.Sprintf("# Project: %s", project))
-	lines = append(lines, "")
-
-	for name, value := range keys {
-		escaped := core.EscapeDotenvValue(value)
-		lines = append(lines, fmt.Sprintf("%s=\"%s\"", name, escaped))
+	content := core.FormatDotenv(keys, core.DotenvOptions{
+		Header: []string{"Generated by akm MCP", "Project: " + project},
+	})
+
+	note := ""
+	if exposed {
+		if addGitignore {
+			if err := core.AppendGitignore(root, ".env"); err != nil {
+				return "", fmt.Errorf("failed to update .gitignore: %w", err)
+			}
+			note = fmt.Sprintf(" (added .env to %s)", filepath.Join(root, ".gitignore"))
+		} else {
+			note = " (WARNING: .env is not gitignored; do not commit it)"
+		}
 	}
 
-	content := strings.Join(lines, "\n") + "\n"
-
 	// Write file
-	envPath := filepath.Join(path, ".env")
 	if err := os.WriteFile(envPath, []byte(content), 0600); err != nil {
 		return "", fmt.Errorf("failed to write .env: %w", err)
 	}
 
-	return fmt.Sprintf("Wrote %d keys to %s", len(keys), envPath), nil
+	return fmt.Sprintf("Wrote %d keys to %s%s", len(keys), envPath, note), nil
 }
 
-// healthCheck returns system health status.
-func healthCheck() (string, error) {
+// healthCheck returns system health status. deep adds a reachability probe of
+// each provider with an active key.
+func healthCheck(deep bool) (string, error) {
 	result := map[string]interface{}{
 		"status": "healthy",
 	}
@@ -299,7 +333,7 @@ func healthCheck() (string, error) {
 
 	// Check audit logs
 	if storage != nil {
-		total, verified, unsigned, tampered, err := storage.VerifyAuditLogs()
+		total, verified, unsigned, tampered, brokenChain, err := storage.VerifyAuditLogs()
 		if err != nil {
 			result["audit"] = map[string]interface{}{
 				"status": "error",
@@ -307,17 +341,45 @@ func healthCheck() (string, error) {
 			}
 		} else {
 			status := "ok"
-			if tampered > 0 {
+			if tampered > 0 || brokenChain > 0 {
 				status = "warning"
 			}
 			result["audit"] = map[string]interface{}{
-				"status":   status,
-				"total":    total,
-				"verified": verified,
-				"unsigned": unsigned,
-				"tampered": tampered,
+				"status":       status,
+				"total":        total,
+				"verified":     verified,
+				"unsigned":     unsigned,
+				"tampered":     tampered,
+				"broken_chain": brokenChain,
+			}
+		}
+	}
+
+	if deep && storage != nil {
+		providers := make([]map[string]interface{}, 0)
+		for _, r := range core.ProbeProviders(storage, 5*time.Second) {
+			entry := map[string]interface{}{
+				"provider": r.Platform,
+				"url":      r.URL,
+			}
+			switch {
+			case r.Skipped:
+				entry["status"] = "skipped"
+			case r.Reachable:
+				entry["status"] = "reachable"
+				entry["http_status"] = r.StatusCode
+				entry["latency_ms"] = r.Latency.Milliseconds()
+			default:
+				entry["status"] = "unreachable"
+				entry["latency_ms"] = r.Latency.Milliseconds()
+				entry["requires_vpn"] = r.RequiresVPN
+			}
+			if r.Error != "" {
+				entry["error"] = r.Error
 			}
+			providers = append(providers, entry)
 		}
+		result["providers"] = providers
 	}
 
 	jsonBytes, err := json.MarshalIndent(result, "", "  ")
@@ -327,3 +389,55 @@ func healthCheck() (string, error) {
 
 	return string(jsonBytes), nil
 }
+
+// budgetStats returns per-provider proxy budget usage as JSON. Limits of 0 mean
+// unlimited; remaining is only reported for configured limits.
+func budgetStats(provider string) (string, error) {
+	budget, err := core.GetBudgetTracker()
+	if err != nil {
+		return "", fmt.Errorf("failed to load budget: %w", err)
+	}
+
+	type window struct {
+		Count     int64  `json:"count"`
+		Limit     int64  `json:"limit"`
+		Remaining *int64 `json:"remaining,omitempty"`
+		Exceeded  bool   `json:"exceeded"`
+	}
+	newWindow := func(count, limit int64) window {
+		w := window{Count: count, Limit: limit}
+		if limit > 0 {
+			remaining := max(limit-count, 0)
+			w.Remaining = &remaining
+			w.Exceeded = count >= limit
+		}
+		return w
+	}
+	type providerBudget struct {
+		Provider string `json:"provider"`
+		Daily    window `json:"daily"`
+		Monthly  window `json:"monthly"`
+	}
+
+	result := []providerBudget{}
+	for _, s := range budget.GetAllStats() {
+		if !core.MatchProvider(provider, s.Provider) {
+			continue
+		}
+		result = append(result, providerBudget{
+			Provider: s.Provider,
+			Daily:    newWindow(s.DailyCount, s.DailyLimit),
+			Monthly:  newWindow(s.MonthlyCount, s.MonthlyLimit),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Provider < result[j].Provider })
+
+	jsonBytes, err := json.MarshalIndent(map[string]interface{}{
+		"providers": result,
+		"count":     len(result),
+	}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(jsonBytes), nil
+}
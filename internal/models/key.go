@@ -98,10 +98,39 @@ type APIKey struct {
 	ExpiresAt      FlexTimePtr `json:"expires_at,omitempty"`
 	IsActive       bool        `json:"is_active"`
 
+	// Who added / last changed the key (AKM_USER or $USER); unset in
+	// single-user or legacy data
+	CreatedBy *string `json:"created_by,omitempty"`
+	UpdatedBy *string `json:"updated_by,omitempty"`
+
 	// Model information
 	ModelVersion      *string  `json:"model_version,omitempty"`
 	ModelName         *string  `json:"model_name,omitempty"`
 	ModelCapabilities []string `json:"model_capabilities,omitempty"`
+
+	// Previous encrypted values, newest first (bounded, see core.MaxValueHistory)
+	ValueHistory []KeyValueVersion `json:"value_history,omitempty"`
+
+	// PassphraseProtected marks values additionally wrapped with a per-key passphrase
+	PassphraseProtected bool `json:"passphrase_protected,omitempty"`
+
+	// Passphrase is transient input for core.WithPassphrase; never persisted
+	Passphrase string `json:"-"`
+
+	// DeletedAt is set while the key sits in the trash (KeysFile.Deleted)
+	DeletedAt *FlexTime `json:"deleted_at,omitempty"`
+}
+
+// IsExpired reports whether the key has an expiry that is before now. Keys
+// without ExpiresAt never expire.
+func (k *APIKey) IsExpired(now time.Time) bool {
+	return k.ExpiresAt.Time != nil && k.ExpiresAt.Time.Before(now)
+}
+
+// KeyValueVersion is a superseded encrypted value kept for rollback.
+type KeyValueVersion struct {
+	ValueEncrypted string   `json:"value_encrypted"`
+	ReplacedAt     FlexTime `json:"replaced_at"`
 }
 
 // NewAPIKey creates a new APIKey with default values.
@@ -119,12 +148,15 @@ func NewAPIKey(name, valueEncrypted, provider string) *APIKey {
 	}
 }
 
-// KeyUsageLog represents an audit log entry with HMAC signature.
+// KeyUsageLog represents an audit log entry with HMAC signature. PrevHash is
+// the signature of the entry before it, chaining entries so that deleted or
+// reordered lines are detectable; it is unset on legacy entries.
 type KeyUsageLog struct {
 	KeyName   string   `json:"key_name"`
 	Project   string   `json:"project"`
 	Action    string   `json:"action"` // read, inject, export, add, delete, update
 	Timestamp FlexTime `json:"timestamp"`
+	PrevHash  *string  `json:"prev_hash,omitempty"`
 	Signature *string  `json:"signature,omitempty"`
 }
 
@@ -143,4 +175,5 @@ type KeysFile struct {
 	Version   string    `json:"version"`
 	UpdatedAt string    `json:"updated_at"`
 	Keys      []*APIKey `json:"keys"`
+	Deleted   []*APIKey `json:"deleted,omitempty"` // trash, restorable until purged
 }
@@ -11,6 +11,7 @@ type Platform struct {
 	APIFormat             string     `json:"api_format"` // openai, claude, google, etc.
 	SupportedModels       []string   `json:"supported_models,omitempty"`
 	DeprecatedModels      []string   `json:"deprecated_models,omitempty"`
+	ModelPrefixes         []string   `json:"model_prefixes,omitempty"` // e.g. "gpt-", used for auto-detection
 	IsActive              bool       `json:"is_active"`
 	RequiresVPN           bool       `json:"requires_vpn"`
 	DocsURL               *string    `json:"docs_url,omitempty"`